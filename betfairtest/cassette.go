@@ -0,0 +1,344 @@
+// betfairtest/cassette.go
+
+package betfairtest
+
+// Record/replay HTTP transport.
+// Records real Betfair exchanges into a JSON cassette file with secrets redacted, then replays
+// them without network access or credentials. Requests are matched by method, path and
+// normalized body; repeated requests replay their recordings in order, the last one repeating.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Mode int
+
+const (
+	MODE_REPLAY Mode = iota // serve from the cassette, never touch the network
+	MODE_RECORD             // send requests and record them, overwriting the cassette on Save
+	MODE_AUTO               // replay if the cassette exists, otherwise record
+)
+
+// Placeholder for redacted values, 44 chars long so replayed session tokens pass the login check
+const REDACTED = "REDACTED____________________________________"
+
+// Headers and form fields always redacted
+var (
+	redactedHeaders = []string{"X-Authentication", "X-Application", "Authorization", "Cookie", "Set-Cookie"}
+	redactedFields  = []string{"username", "password"}
+	redactedJSON    = []string{"sessionToken", "token"}
+)
+
+// Generated per request by the client (see PlaceOrders), dropped from recorded bodies so replays match
+var volatileJSON = []string{"customerRef"}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"` // normalized
+}
+
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type Cassette struct {
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	played       map[string]int // replay position per request key
+	secrets      []string
+}
+
+// Opens a cassette file, in MODE_AUTO the mode is resolved by whether the file exists
+func OpenCassette(path string, mode Mode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, played: make(map[string]int)}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil && mode != MODE_RECORD:
+		if err := json.Unmarshal(data, &c.interactions); err != nil {
+			return nil, fmt.Errorf("unable to parse cassette %s: %w", path, err)
+		}
+		c.mode = MODE_REPLAY
+	case errors.Is(err, os.ErrNotExist) && mode == MODE_AUTO:
+		c.mode = MODE_RECORD
+	case err != nil && mode == MODE_REPLAY:
+		return nil, fmt.Errorf("unable to read cassette %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Mode in use, MODE_AUTO resolved
+func (c *Cassette) Mode() Mode {
+	return c.mode
+}
+
+// Adds values to scrub from everything recorded, e.g. an account id
+func (c *Cassette) Redact(values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, v := range values {
+		if v != "" {
+			c.secrets = append(c.secrets, v)
+		}
+	}
+}
+
+// Use with client.WithRoundTripper, next is only used when recording
+func (c *Cassette) Wrap(next http.RoundTripper) http.RoundTripper {
+	c.next = next
+	return c
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("unable to read request body: %w", err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if c.mode == MODE_REPLAY {
+		return c.replay(req, body)
+	}
+	return c.record(req, body)
+}
+
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := requestKey(req.Method, req.URL.Path, c.normalize(req.Header.Get("Content-Type"), body))
+
+	var matches []int
+	for i, in := range c.interactions {
+		if requestKey(in.Request.Method, in.Request.Path, in.Request.Body) == key {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, req.URL.Path)
+	}
+
+	n := min(c.played[key], len(matches)-1)
+	c.played[key]++
+	recorded := c.interactions[matches[n]].Response
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (c *Cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	next := c.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	c.collectSecrets(req, body)
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := redactHeader(resp.Header)
+	header.Del("Content-Length") // redaction can change the body length
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interactions = append(c.interactions, Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Header: redactHeader(req.Header),
+			Body:   c.normalize(req.Header.Get("Content-Type"), body),
+		},
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: header,
+			Body:   c.scrub(redactJSON(respBody)),
+		},
+	})
+
+	return resp, nil
+}
+
+// Writes the recorded interactions, a no-op when replaying
+func (c *Cassette) Save() error {
+	if c.mode != MODE_RECORD {
+		return nil
+	}
+
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("unable to marshal cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("unable to create cassette dir: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write cassette %s: %w", c.path, err)
+	}
+
+	return nil
+}
+
+// Secrets sent with a request, scrubbed from response bodies too
+func (c *Cassette) collectSecrets(req *http.Request, body []byte) {
+	var values []string
+	for _, h := range redactedHeaders {
+		values = append(values, req.Header.Get(h))
+	}
+	if form, err := url.ParseQuery(string(body)); err == nil {
+		for _, f := range redactedFields {
+			values = append(values, form.Get(f))
+		}
+	}
+	c.Redact(values...)
+}
+
+// Canonical form of a request body, used for matching and storage
+// JSON is re-marshalled with sorted keys, forms are sorted with credentials redacted
+func (c *Cassette) normalize(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err == nil {
+			for _, f := range redactedFields {
+				if form.Has(f) {
+					form.Set(f, REDACTED)
+				}
+			}
+			return c.scrub([]byte(form.Encode()))
+		}
+	}
+
+	var v any
+	if err := json.Unmarshal(body, &v); err == nil {
+		dropFields(v, volatileJSON)
+		if canonical, err := json.Marshal(v); err == nil {
+			return c.scrub(canonical)
+		}
+	}
+
+	return c.scrub(body)
+}
+
+// Must hold c.mu or be called before recording starts
+func (c *Cassette) scrub(data []byte) string {
+	s := string(data)
+
+	secrets := append([]string(nil), c.secrets...)
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, REDACTED)
+	}
+	return s
+}
+
+func redactHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range redactedHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, REDACTED)
+		}
+	}
+	return out
+}
+
+// Replaces top level session token fields in a JSON object, other bodies are returned as is
+func redactJSON(body []byte) []byte {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return body
+	}
+
+	changed := false
+	for _, field := range redactedJSON {
+		if _, ok := obj[field]; ok {
+			obj[field] = json.RawMessage(`"` + REDACTED + `"`)
+			changed = true
+		}
+	}
+	if !changed {
+		return body
+	}
+
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// Removes fields from every object in a decoded JSON value
+func dropFields(v any, fields []string) {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, f := range fields {
+			delete(v, f)
+		}
+		for _, child := range v {
+			dropFields(child, fields)
+		}
+	case []any:
+		for _, child := range v {
+			dropFields(child, fields)
+		}
+	}
+}
+
+func requestKey(method, path, body string) string {
+	return method + " " + path + " " + body
+}
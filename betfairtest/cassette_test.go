@@ -0,0 +1,59 @@
+// betfairtest/cassette_test.go
+
+package betfairtest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/betfairtest"
+	"github.com/Bazcampbell/betfair-api-go-sdk/client"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassette_RecordThenReplayOffline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "discovery.json")
+
+	srv, err := betfairtest.NewServer()
+	require.NoError(t, err)
+
+	rec, err := betfairtest.OpenCassette(path, betfairtest.MODE_AUTO)
+	require.NoError(t, err)
+	require.Equal(t, betfairtest.MODE_RECORD, rec.Mode())
+
+	bf, err := client.NewSession(srv.Credentials(), func(error) {},
+		client.WithBaseURL(srv.URL()), client.WithRoundTripper(rec.Wrap))
+	require.NoError(t, err)
+
+	filter := types.MarketFilter{EventTypeIds: []string{"7"}}
+	recorded, err := bf.ListEventTypes(filter)
+	require.NoError(t, err)
+	require.NoError(t, rec.Save())
+	srv.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), betfairtest.PASSWORD)
+	assert.NotContains(t, string(data), betfairtest.APP_KEY)
+
+	// Replays with throwaway credentials and nothing listening
+	play, err := betfairtest.OpenCassette(path, betfairtest.MODE_AUTO)
+	require.NoError(t, err)
+	require.Equal(t, betfairtest.MODE_REPLAY, play.Mode())
+
+	creds, err := betfairtest.Credentials()
+	require.NoError(t, err)
+	creds.Password = "something else"
+
+	bf, err = client.NewSession(creds, func(error) {},
+		client.WithBaseURL(srv.URL()), client.WithRoundTripper(play.Wrap))
+	require.NoError(t, err)
+
+	replayed, err := bf.ListEventTypes(filter)
+	require.NoError(t, err)
+	assert.Equal(t, recorded, replayed)
+}
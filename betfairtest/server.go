@@ -0,0 +1,577 @@
+// betfairtest/server.go
+
+package betfairtest
+
+// Local stand-in for the Betfair identity SSO and REST/JSON-RPC APIs, for tests without network access.
+// Point a client at it with client.WithBaseURL(srv.URL()). Operations are answered from fixtures,
+// APING errors and latency can be scripted per operation and every request is recorded.
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+const (
+	USERNAME = "test-user"
+	PASSWORD = "test-password"
+	APP_KEY  = "test-app-key"
+)
+
+// Operation names used for the non-APING endpoints
+const (
+	OP_LOGIN      = "certlogin"
+	OP_KEEP_ALIVE = "keepAlive"
+	OP_LOGOUT     = "logout"
+	OP_NAVIGATION = "navigation"
+)
+
+const (
+	authPath       = "/api/"
+	bettingPath    = "/exchange/betting/rest/v1.0/"
+	accountPath    = "/exchange/account/rest/v1.0/"
+	scoresPath     = "/exchange/scores/rest/v1.0/"
+	bettingRPCPath = "/exchange/betting/json-rpc/v1"
+	accountRPCPath = "/exchange/account/json-rpc/v1"
+	scoresRPCPath  = "/exchange/scores/json-rpc/v1"
+	navigationPath = "/exchange/betting/rest/v1/en/navigation/menu.json"
+)
+
+//go:embed fixtures/*.json
+var defaultFixtures embed.FS
+
+// Betfair's fault strings for APING error codes
+var faultStrings = map[string]string{
+	"TOO_MUCH_DATA":               "ANGX-0001",
+	"INVALID_INPUT_DATA":          "ANGX-0002",
+	"INVALID_SESSION_INFORMATION": "ANGX-0003",
+	"NO_APP_KEY":                  "ANGX-0004",
+	"NO_SESSION":                  "ANGX-0005",
+	"UNEXPECTED_ERROR":            "ANGX-0006",
+	"INVALID_APP_KEY":             "ANGX-0007",
+	"TOO_MANY_REQUESTS":           "ANGX-0008",
+	"SERVICE_BUSY":                "ANGX-0009",
+	"TIMEOUT_ERROR":               "ANGX-0010",
+	"REQUEST_SIZE_EXCEEDS_LIMIT":  "ANGX-0011",
+	"ACCESS_DENIED":               "ANGX-0012",
+}
+
+// A request received by the server
+type Request struct {
+	Operation string // e.g. "listMarketBook" or OP_LOGIN
+	Path      string
+	Header    http.Header
+	Body      []byte // JSON params for API operations, the form for OP_LOGIN
+	RPC       bool   // sent over JSON-RPC
+	At        time.Time
+}
+
+// Unmarshals the request params
+func (r Request) Decode(v any) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+type Server struct {
+	Username string // expected username, empty accepts any
+	Password string // expected password, empty accepts any
+	AppKey   string // expected app key, empty accepts any
+
+	srv        *httptest.Server
+	certString string
+	keyString  string
+
+	mu       sync.Mutex
+	fixtures map[string]json.RawMessage
+	failures map[string][]string
+	latency  map[string]time.Duration
+	tokens   map[string]bool
+	requests []Request
+}
+
+// Starts a server on a random local port with the default fixtures loaded
+func NewServer() (*Server, error) {
+	creds, err := Credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Username:   creds.Username,
+		Password:   creds.Password,
+		AppKey:     creds.AppKey,
+		certString: creds.CertString,
+		keyString:  creds.KeyString,
+		fixtures:   make(map[string]json.RawMessage),
+		failures:   make(map[string][]string),
+		latency:    make(map[string]time.Duration),
+		tokens:     make(map[string]bool),
+	}
+
+	sub, err := fs.Sub(defaultFixtures, "fixtures")
+	if err != nil {
+		return nil, fmt.Errorf("unable to open default fixtures: %w", err)
+	}
+	if err := s.LoadFixtures(sub); err != nil {
+		return nil, err
+	}
+
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s, nil
+}
+
+// Base URL to pass to client.WithBaseURL
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Credentials accepted by the server, with a throwaway client certificate
+func (s *Server) Credentials() types.BetfairCredentials {
+	return types.BetfairCredentials{
+		Username:   s.Username,
+		Password:   s.Password,
+		AppKey:     s.AppKey,
+		CertString: s.certString,
+		KeyString:  s.keyString,
+	}
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Sets the response for an operation, e.g. "listMarketBook", replacing any fixture already loaded
+// The response is marshalled to JSON, []byte and json.RawMessage are sent as is
+func (s *Server) SetFixture(operation string, response any) error {
+	raw, ok := response.([]byte)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(response); err != nil {
+			return fmt.Errorf("unable to marshal fixture for %s: %w", operation, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fixtures[operation] = json.RawMessage(raw)
+	return nil
+}
+
+// Loads every <operation>.json file in fsys as a fixture
+func (s *Server) LoadFixtures(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return fmt.Errorf("unable to list fixtures: %w", err)
+	}
+
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("unable to read fixture %s: %w", file, err)
+		}
+		if !json.Valid(data) {
+			return fmt.Errorf("fixture %s is not valid JSON", file)
+		}
+		if err := s.SetFixture(strings.TrimSuffix(path.Base(file), ".json"), data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Fails the next call to an operation with an APING error code, e.g. "TOO_MUCH_DATA"
+// For OP_LOGIN the code is the login status, e.g. "BETTING_RESTRICTED_LOCATION"
+// Calls queue up, each failing one request
+func (s *Server) FailNext(operation, errorCode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[operation] = append(s.failures[operation], errorCode)
+}
+
+// Delays responses to an operation, an empty operation delays everything
+func (s *Server) SetLatency(operation string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency[operation] = d
+}
+
+// Invalidates every session token, later calls fail with INVALID_SESSION_INFORMATION until the client logs in again
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = make(map[string]bool)
+}
+
+// Every request received so far, in order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Requests for one operation, in order
+func (s *Server) RequestsTo(operation string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var reqs []Request
+	for _, r := range s.requests {
+		if r.Operation == operation {
+			reqs = append(reqs, r)
+		}
+	}
+	return reqs
+}
+
+// Fails the test unless the operation was called, returns the last call
+func (s *Server) AssertCalled(t testing.TB, operation string) Request {
+	t.Helper()
+
+	reqs := s.RequestsTo(operation)
+	if len(reqs) == 0 {
+		t.Errorf("betfairtest: expected a call to %s", operation)
+		return Request{}
+	}
+	return reqs[len(reqs)-1]
+}
+
+// Fails the test if the operation was called
+func (s *Server) AssertNotCalled(t testing.TB, operation string) {
+	t.Helper()
+
+	if n := len(s.RequestsTo(operation)); n > 0 {
+		t.Errorf("betfairtest: expected no calls to %s, got %d", operation, n)
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p := r.URL.Path
+	switch {
+	case strings.HasPrefix(p, authPath):
+		s.serveAuth(w, r, strings.TrimPrefix(p, authPath), body)
+	case p == navigationPath:
+		s.serveREST(w, r, OP_NAVIGATION, "APINGException", body)
+	case strings.HasPrefix(p, bettingPath):
+		s.serveREST(w, r, strings.Trim(strings.TrimPrefix(p, bettingPath), "/"), "APINGException", body)
+	case strings.HasPrefix(p, accountPath):
+		s.serveREST(w, r, strings.Trim(strings.TrimPrefix(p, accountPath), "/"), "AccountAPINGException", body)
+	case strings.HasPrefix(p, scoresPath):
+		s.serveREST(w, r, strings.Trim(strings.TrimPrefix(p, scoresPath), "/"), "APINGException", body)
+	case p == bettingRPCPath:
+		s.serveRPC(w, r, "APINGException", body)
+	case p == accountRPCPath:
+		s.serveRPC(w, r, "AccountAPINGException", body)
+	case p == scoresRPCPath:
+		s.serveRPC(w, r, "APINGException", body)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) record(operation string, r *http.Request, body []byte, rpc bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, Request{
+		Operation: operation,
+		Path:      r.URL.Path,
+		Header:    r.Header.Clone(),
+		Body:      body,
+		RPC:       rpc,
+		At:        time.Now(),
+	})
+}
+
+func (s *Server) wait(r *http.Request, operation string) {
+	s.mu.Lock()
+	d := s.latency[""] + s.latency[operation]
+	s.mu.Unlock()
+
+	if d <= 0 {
+		return
+	}
+
+	select {
+	case <-time.After(d):
+	case <-r.Context().Done():
+	}
+}
+
+func (s *Server) newToken() string {
+	b := make([]byte, 33)
+	rand.Read(b)
+	token := base64.StdEncoding.EncodeToString(b) // 44 chars, like Betfair's
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[token] = true
+	return token
+}
+
+func (s *Server) serveAuth(w http.ResponseWriter, r *http.Request, operation string, body []byte) {
+	s.record(operation, r, body, false)
+	s.wait(r, operation)
+
+	token := r.Header.Get("X-Authentication")
+
+	s.mu.Lock()
+	queue := s.failures[operation]
+	if len(queue) > 0 {
+		s.failures[operation] = queue[1:]
+	}
+	s.mu.Unlock()
+
+	if len(queue) > 0 {
+		if operation == OP_LOGIN {
+			writeJSON(w, http.StatusOK, types.LoginResponse{Status: queue[0]})
+		} else {
+			writeJSON(w, http.StatusOK, types.LogoutResponse{Status: "FAIL", Error: queue[0]})
+		}
+		return
+	}
+
+	switch operation {
+	case OP_LOGIN:
+		form, _ := url.ParseQuery(string(body))
+		if (s.Username != "" && form.Get("username") != s.Username) ||
+			(s.Password != "" && form.Get("password") != s.Password) {
+			writeJSON(w, http.StatusOK, types.LoginResponse{Status: "INVALID_USERNAME_OR_PASSWORD"})
+			return
+		}
+		if s.AppKey != "" && r.Header.Get("X-Application") != s.AppKey {
+			writeJSON(w, http.StatusOK, types.LoginResponse{Status: "INVALID_APP_KEY"})
+			return
+		}
+		writeJSON(w, http.StatusOK, types.LoginResponse{Status: "SUCCESS", SessionToken: s.newToken()})
+
+	case OP_KEEP_ALIVE:
+		if !s.validToken(token) {
+			writeJSON(w, http.StatusOK, types.KeepAliveResponse{Status: "FAIL", Error: "NO_SESSION"})
+			return
+		}
+		writeJSON(w, http.StatusOK, types.KeepAliveResponse{Status: "SUCCESS", SessionToken: token})
+
+	case OP_LOGOUT:
+		if !s.validToken(token) {
+			writeJSON(w, http.StatusOK, types.LogoutResponse{Status: "FAIL", Error: "NO_SESSION"})
+			return
+		}
+		s.mu.Lock()
+		delete(s.tokens, token)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, types.LogoutResponse{Status: "SUCCESS"})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) validToken(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tokens[token]
+}
+
+// Response for one API operation, either the fixture or an APING error code
+func (s *Server) respond(r *http.Request, operation string) (json.RawMessage, string) {
+	switch {
+	case r.Header.Get("X-Application") == "":
+		return nil, "NO_APP_KEY"
+	case s.AppKey != "" && r.Header.Get("X-Application") != s.AppKey:
+		return nil, "INVALID_APP_KEY"
+	case r.Header.Get("X-Authentication") == "":
+		return nil, "NO_SESSION"
+	case !s.validToken(r.Header.Get("X-Authentication")):
+		return nil, "INVALID_SESSION_INFORMATION"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if queue := s.failures[operation]; len(queue) > 0 {
+		s.failures[operation] = queue[1:]
+		return nil, queue[0]
+	}
+
+	fixture, ok := s.fixtures[operation]
+	if !ok {
+		return nil, "INVALID_INPUT_DATA"
+	}
+	return fixture, ""
+}
+
+func (s *Server) serveREST(w http.ResponseWriter, r *http.Request, operation, exceptionName string, body []byte) {
+	s.record(operation, r, body, false)
+	s.wait(r, operation)
+
+	result, code := s.respond(r, operation)
+	if code != "" {
+		writeJSON(w, http.StatusBadRequest, restFault{
+			FaultCode:   "Client",
+			FaultString: faultString(code),
+			Detail: map[string]any{
+				exceptionName:   apingException(code, operation),
+				"exceptionname": exceptionName,
+			},
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(result)
+}
+
+type restFault struct {
+	FaultCode   string         `json:"faultcode"`
+	FaultString string         `json:"faultstring"`
+	Detail      map[string]any `json:"detail"`
+}
+
+type rpcCall struct {
+	JsonRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	Id      int             `json:"id"`
+}
+
+func (s *Server) serveRPC(w http.ResponseWriter, r *http.Request, exceptionName string, body []byte) {
+	batch := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+
+	var calls []rpcCall
+	if batch {
+		if err := json.Unmarshal(body, &calls); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		var call rpcCall
+		if err := json.Unmarshal(body, &call); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		calls = []rpcCall{call}
+	}
+
+	responses := make([]types.RPCResponse, len(calls))
+	for i, call := range calls {
+		operation := call.Method[strings.LastIndex(call.Method, "/")+1:]
+		s.record(operation, r, call.Params, true)
+		s.wait(r, operation)
+
+		resp := types.RPCResponse{JsonRPC: "2.0", Id: call.Id}
+		result, code := s.respond(r, operation)
+		if code != "" {
+			data := &types.RPCErrorData{ExceptionName: exceptionName}
+			exception := apingException(code, operation)
+			if exceptionName == "AccountAPINGException" {
+				data.AccountAPINGException = exception
+			} else {
+				data.APINGException = exception
+			}
+			resp.Error = &types.RPCError{Code: -32099, Message: faultString(code), Data: data}
+		} else {
+			resp.Result = result
+		}
+		responses[i] = resp
+	}
+
+	if batch {
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses[0])
+}
+
+func apingException(code, operation string) *types.APINGException {
+	return &types.APINGException{
+		ErrorCode:    code,
+		ErrorDetails: "betfairtest: " + operation,
+		RequestUUID:  "betfairtest-" + operation,
+	}
+}
+
+func faultString(code string) string {
+	if fault, ok := faultStrings[code]; ok {
+		return fault
+	}
+	return faultStrings["UNEXPECTED_ERROR"]
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Default test credentials with a throwaway self-signed client certificate
+// Enough for NewSession against a Server or a replayed cassette
+func Credentials() (types.BetfairCredentials, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return types.BetfairCredentials{}, fmt.Errorf("unable to generate key: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "betfairtest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return types.BetfairCredentials{}, fmt.Errorf("unable to create certificate: %w", err)
+	}
+
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return types.BetfairCredentials{}, fmt.Errorf("unable to marshal key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer})
+
+	return types.BetfairCredentials{
+		Username:   USERNAME,
+		Password:   PASSWORD,
+		AppKey:     APP_KEY,
+		CertString: base64.StdEncoding.EncodeToString(certPEM),
+		KeyString:  base64.StdEncoding.EncodeToString(keyPEM),
+	}, nil
+}
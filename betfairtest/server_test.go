@@ -0,0 +1,78 @@
+// betfairtest/server_test.go
+
+package betfairtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/betfairtest"
+	"github.com/Bazcampbell/betfair-api-go-sdk/client"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSession(t *testing.T, opts ...client.Option) (*betfairtest.Server, *client.BetfairClient) {
+	srv, err := betfairtest.NewServer()
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+
+	opts = append(opts, client.WithBaseURL(srv.URL()))
+	bf, err := client.NewSession(srv.Credentials(), func(err error) {}, opts...)
+	require.NoError(t, err)
+
+	return srv, bf
+}
+
+func TestServer_FixturesAndRequestAssertions(t *testing.T) {
+	srv, bf := newSession(t)
+
+	req := srv.AssertCalled(t, betfairtest.OP_LOGIN)
+	assert.Equal(t, betfairtest.APP_KEY, req.Header.Get("X-Application"))
+
+	books, err := bf.ListMarketBook(types.ListMarketBookRequest{MarketIds: []string{"1.234567"}})
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	assert.Equal(t, types.OPEN, books[0].Status)
+
+	var sent types.ListMarketBookRequest
+	require.NoError(t, srv.AssertCalled(t, "listMarketBook").Decode(&sent))
+	assert.Equal(t, []string{"1.234567"}, sent.MarketIds)
+
+	require.NoError(t, srv.SetFixture("listEvents", []types.ListEventsResponse{{MarketCount: 3}}))
+	events, err := bf.ListEvents(types.MarketFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, events[0].MarketCount)
+
+	srv.AssertNotCalled(t, "placeOrders")
+}
+
+func TestServer_ScriptedErrorsAndLatency(t *testing.T) {
+	srv, bf := newSession(t, client.WithTransport(client.TRANSPORT_JSON_RPC))
+
+	srv.FailNext("listEventTypes", "TOO_MUCH_DATA")
+	_, err := bf.ListEventTypes(types.MarketFilter{})
+	var rpcErr *types.RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "TOO_MUCH_DATA", rpcErr.ErrorCode())
+
+	// Only the next call fails
+	eventTypes, err := bf.ListEventTypes(types.MarketFilter{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, eventTypes)
+	assert.True(t, srv.AssertCalled(t, "listEventTypes").RPC)
+
+	srv.SetLatency("getAccountFunds", 50*time.Millisecond)
+	start := time.Now()
+	funds, err := bf.GetAccountFunds(types.GetAccountFundsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 1000.0, funds.AvailableToBetBalance)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	srv.ExpireSessions()
+	_, err = bf.GetAccountFunds(types.GetAccountFundsRequest{})
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "INVALID_SESSION_INFORMATION", rpcErr.ErrorCode())
+}
@@ -0,0 +1,11 @@
+// client/account_endpoints.go
+
+package client
+
+import "github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+const ACCOUNT_URL = "https://api.betfair.com/exchange/account/rest/v1.0/"
+
+func (b *BetfairClient) GetAccountFunds(req types.GetAccountFundsRequest) (types.AccountFundsResponse, error) {
+	return post[types.AccountFundsResponse](b, accountService, "getAccountFunds", req)
+}
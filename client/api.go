@@ -0,0 +1,58 @@
+// client/api.go
+
+package client
+
+// Interfaces over BetfairClient so callers can swap in fakes or other backends.
+// Depend on the smallest one that covers what you call.
+
+import "github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+// Market navigation and catalogue lookups
+type Discovery interface {
+	ListEventTypes(filter types.MarketFilter) ([]types.ListEventTypesResponse, error)
+	ListCompetitions(filter types.MarketFilter) ([]types.ListCompetitionsResponse, error)
+	ListCountries(filter types.MarketFilter) ([]types.ListCountriesResponse, error)
+	ListEvents(filter types.MarketFilter) ([]types.ListEventsResponse, error)
+	ListMarketTypes(filter types.MarketFilter) ([]types.ListMarketTypesResponse, error)
+	ListMarketCatalogues(req types.ListRequest) ([]types.ListMarketCataloguesResponse, error)
+	GetNavigationMenu() (*types.NavigationNode, error)
+}
+
+// Prices, race status and scores
+type MarketData interface {
+	ListMarketBook(req types.ListMarketBookRequest) ([]types.ListMarketBookResponse, error)
+	ListRaceDetails(req types.ListRaceDetailsRequest) ([]types.RaceDetails, error)
+	ListRaceDetailsForMarkets(markets []types.ListMarketCataloguesResponse) ([]types.RaceDetails, error)
+	ListAvailableEvents(req types.ListAvailableEventsRequest) ([]types.AvailableEvent, error)
+	ListScores(req types.ListScoresRequest) ([]types.Score, error)
+	ListIncidents(req types.ListIncidentsRequest) ([]types.EventIncidents, error)
+}
+
+type Orders interface {
+	PlaceOrders(req types.PlaceOrdersRequest) (types.PlaceExecutionReport, error)
+	CancelOrders(req types.CancelOrdersRequest) (types.CancelExecutionReport, error)
+	ReplaceOrders(req types.ReplaceOrdersRequest) (types.ReplaceExecutionReport, error)
+	UpdateOrders(req types.UpdateOrdersRequest) (types.UpdateExecutionReport, error)
+	ListCurrentOrders(req types.ListCurrentOrdersRequest) (types.CurrentOrderSummaryReport, error)
+}
+
+type Account interface {
+	GetAccountFunds(req types.GetAccountFundsRequest) (types.AccountFundsResponse, error)
+}
+
+// Every public method of BetfairClient
+type BetfairAPI interface {
+	Discovery
+	MarketData
+	Orders
+	Account
+
+	AppKey() string
+	SessionToken() (string, error)
+	ExecuteBatch(batch *Batch) error
+}
+
+var (
+	_ BetfairAPI = (*BetfairClient)(nil)
+	_ BetfairAPI = (*Fake)(nil)
+)
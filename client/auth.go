@@ -17,7 +17,7 @@ const BASE_AUTH_URL = "https://identitysso-cert.betfair.com.au/api/"
 
 // Returns session token + error
 func (b *BetfairClient) login() (string, error) {
-	loginUrl := BASE_AUTH_URL + "certlogin"
+	loginUrl := b.urls.auth + "certlogin"
 
 	params := url.Values{}
 	params.Add("username", b.creds.Username)
@@ -63,7 +63,7 @@ func (b *BetfairClient) keepAlive() error {
 		return fmt.Errorf("session token not initialized")
 	}
 
-	keepAliveUrl := BASE_AUTH_URL + "keepAlive"
+	keepAliveUrl := b.urls.auth + "keepAlive"
 
 	req, err := http.NewRequest("POST", keepAliveUrl, nil)
 	if err != nil {
@@ -102,7 +102,7 @@ func (b *BetfairClient) keepAlive() error {
 }
 
 func (b *BetfairClient) logout() error {
-	logoutUrl := BASE_AUTH_URL + "logout"
+	logoutUrl := b.urls.auth + "logout"
 
 	req, err := http.NewRequest("POST", logoutUrl, nil)
 	if err != nil {
@@ -0,0 +1,110 @@
+// client/batch.go
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+	"github.com/Bazcampbell/betfair-api-go-sdk/util"
+)
+
+// Collects several operations to be sent in a single JSON-RPC round trip
+// Batches are always sent over JSON-RPC regardless of the session's transport
+type Batch struct {
+	calls []batchCall
+}
+
+type batchCall struct {
+	svc    service
+	method string
+	params any
+	decode func(types.RPCResponse)
+}
+
+// Typed result of a single call in a batch
+// Populated once the batch has been executed
+type BatchResult[T any] struct {
+	Value T
+	Err   error
+}
+
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Adds an operation to the batch, e.g. "listMarketBook" or "AccountAPING/v1.0/getAccountFunds"
+// Bare names are treated as SportsAPING operations
+func AddCall[T any](batch *Batch, operation string, params any) *BatchResult[T] {
+	svc, op := parseOperation(operation)
+	result := &BatchResult[T]{}
+
+	batch.calls = append(batch.calls, batchCall{
+		svc:    svc,
+		method: svc.rpcMethod(op),
+		params: params,
+		decode: func(resp types.RPCResponse) {
+			if resp.Error != nil {
+				result.Err = resp.Error
+				return
+			}
+
+			if err := json.Unmarshal(resp.Result, &result.Value); err != nil {
+				result.Err = fmt.Errorf("json unmarshal failed: %w", err)
+			}
+		},
+	})
+
+	return result
+}
+
+func (batch *Batch) Len() int {
+	return len(batch.calls)
+}
+
+// Sends every call in one HTTP request and fills in each BatchResult
+// The returned error covers the request as a whole, per call errors are on the results
+// All calls must target the same API (betting, account or scores)
+// Batches containing order operations are sent once and never retried
+func (b *BetfairClient) ExecuteBatch(batch *Batch) error {
+	if len(batch.calls) == 0 {
+		return fmt.Errorf("batch is empty")
+	}
+
+	svc := batch.calls[0].svc
+	once := false
+	reqs := make([]types.RPCRequest, len(batch.calls))
+	for i, c := range batch.calls {
+		if c.svc != svc {
+			return fmt.Errorf("batch mixes %s and %s operations (%s)", svc, c.svc, c.method)
+		}
+
+		if _, op := parseOperation(c.method); unsafeOperations[op] {
+			once = true
+		}
+
+		reqs[i] = types.RPCRequest{JsonRPC: "2.0", Method: c.method, Params: c.params, Id: i + 1}
+	}
+
+	token, err := b.getSessionToken()
+	if err != nil {
+		return err
+	}
+
+	send := util.JSONRPCBatch
+	if once {
+		send = util.JSONRPCBatchOnce
+	}
+
+	responses, err := send(b.client, b.urls.rpc(svc), b.creds.AppKey, token, reqs)
+	if err != nil {
+		return err
+	}
+
+	for i, resp := range responses {
+		batch.calls[i].decode(resp)
+	}
+
+	return nil
+}
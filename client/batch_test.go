@@ -0,0 +1,42 @@
+// client/batch_test.go
+
+package client_test
+
+import (
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/betfairtest"
+	"github.com/Bazcampbell/betfair-api-go-sdk/client"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteBatch_MixedServices(t *testing.T) {
+	srv, err := betfairtest.NewServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	bf, err := client.NewSession(srv.Credentials(), func(error) {}, client.WithBaseURL(srv.URL()))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		second string
+		want   string
+	}{
+		{"account", "AccountAPING/v1.0/getAccountFunds", "batch mixes betting and account operations (AccountAPING/v1.0/getAccountFunds)"},
+		{"scores", "ScoresAPING/v1.0/listScores", "batch mixes betting and scores operations (ScoresAPING/v1.0/listScores)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := client.NewBatch()
+			client.AddCall[[]types.ListMarketBookResponse](batch, "listMarketBook", types.ListMarketBookRequest{})
+			client.AddCall[any](batch, tt.second, nil)
+
+			assert.EqualError(t, bf.ExecuteBatch(batch), tt.want)
+		})
+	}
+}
@@ -16,7 +16,9 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/Bazcampbell/betfair-api-go-sdk/risk"
 	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+	"github.com/Bazcampbell/betfair-api-go-sdk/validation"
 )
 
 type BetfairClient struct {
@@ -30,7 +32,11 @@ type BetfairClient struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	onError func(error)
+	onError   func(error)
+	transport Transport
+	urls      endpoints
+	validator *validation.Validator
+	risk      *risk.Engine
 }
 
 const (
@@ -38,7 +44,7 @@ const (
 	keepAliveRetryDelay = 1 * time.Second
 )
 
-func NewSession(creds types.BetfairCredentials, onError func(error)) (*BetfairClient, error) {
+func NewSession(creds types.BetfairCredentials, onError func(error), opts ...Option) (*BetfairClient, error) {
 	if creds.AppKey == "" {
 		return nil, fmt.Errorf("app key cannot be empty")
 	}
@@ -97,10 +103,15 @@ func NewSession(creds types.BetfairCredentials, onError func(error)) (*BetfairCl
 		client:  &client,
 		onError: onError,
 		creds:   creds,
+		urls:    defaultEndpoints(),
 		ctx:     ctx,
 		cancel:  cancel,
 	}
 
+	for _, opt := range opts {
+		opt(b)
+	}
+
 	b.keepAliveTicker()
 
 	sessionToken, err := b.login()
@@ -181,6 +192,16 @@ func (b *BetfairClient) getSessionToken() (string, error) {
 	return token, nil
 }
 
+// Exposes the app key for services authenticated outside this client (e.g. the stream API)
+func (b *BetfairClient) AppKey() string {
+	return b.creds.AppKey
+}
+
+// Exposes the current session token, which changes on keep-alive and reconnect
+func (b *BetfairClient) SessionToken() (string, error) {
+	return b.getSessionToken()
+}
+
 func (b *BetfairClient) reconnect() error {
 	token, err := b.login()
 	if err != nil {
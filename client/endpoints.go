@@ -0,0 +1,75 @@
+// client/endpoints.go
+
+package client
+
+// Where each Betfair service lives.
+// Defaults to the production hosts, WithBaseURL points everything at one server.
+
+import (
+	"strings"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/util"
+)
+
+type endpoints struct {
+	auth       string
+	betting    string
+	account    string
+	bettingRPC string
+	accountRPC string
+	scores     string
+	scoresRPC  string
+	navigation string
+}
+
+func defaultEndpoints() endpoints {
+	return endpoints{
+		auth:       BASE_AUTH_URL,
+		betting:    BASE_URL,
+		account:    ACCOUNT_URL,
+		bettingRPC: util.BETTING_RPC_URL,
+		accountRPC: util.ACCOUNT_RPC_URL,
+		scores:     SCORES_URL,
+		scoresRPC:  util.SCORES_RPC_URL,
+		navigation: NAVIGATION_URL,
+	}
+}
+
+// Sends every request to base instead of Betfair, keeping Betfair's paths
+// e.g. the URL of a betfairtest.Server
+func WithBaseURL(base string) Option {
+	base = strings.TrimSuffix(base, "/")
+
+	return func(b *BetfairClient) {
+		b.urls = endpoints{
+			auth:       base + "/api/",
+			betting:    base + "/exchange/betting/rest/v1.0/",
+			account:    base + "/exchange/account/rest/v1.0/",
+			bettingRPC: base + "/exchange/betting/json-rpc/v1",
+			accountRPC: base + "/exchange/account/json-rpc/v1",
+			scores:     base + "/exchange/scores/rest/v1.0/",
+			scoresRPC:  base + "/exchange/scores/json-rpc/v1",
+			navigation: base + "/exchange/betting/rest/v1/en/navigation/menu.json",
+		}
+	}
+}
+
+func (e endpoints) rest(s service) string {
+	switch s {
+	case accountService:
+		return e.account
+	case scoresService:
+		return e.scores
+	}
+	return e.betting
+}
+
+func (e endpoints) rpc(s service) string {
+	switch s {
+	case accountService:
+		return e.accountRPC
+	case scoresService:
+		return e.scoresRPC
+	}
+	return e.bettingRPC
+}
@@ -0,0 +1,248 @@
+// client/fake.go
+
+package client
+
+// Recording fake of BetfairAPI for tests.
+// Every call is recorded with its request, responses are scripted per method and
+// returned in order, the last one repeating. Unscripted methods return zero values.
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+// A recorded call, Method is the Go method name e.g. "ListMarketBook"
+type Call struct {
+	Method  string
+	Request any // nil for methods without one
+}
+
+type fakeResponse struct {
+	value any
+	err   error
+	fn    func(req any) (any, error)
+}
+
+type Fake struct {
+	mu        sync.Mutex
+	calls     []Call
+	responses map[string][]fakeResponse
+	appKey    string
+	token     string
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		responses: make(map[string][]fakeResponse),
+		appKey:    "FAKE_APP_KEY",
+		token:     "FAKE_SESSION_TOKEN",
+	}
+}
+
+// Queues a response for a method, the value must have the method's return type
+// Batched calls are answered by responses queued under their JSON-RPC method, e.g. "SportsAPING/v1.0/listMarketBook"
+func (f *Fake) Respond(method string, value any, err error) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses[method] = append(f.responses[method], fakeResponse{value: value, err: err})
+	return f
+}
+
+// Queues a function computing the response from the request
+func (f *Fake) RespondWith(method string, fn func(req any) (any, error)) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses[method] = append(f.responses[method], fakeResponse{fn: fn})
+	return f
+}
+
+// Every call so far, in order
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// Calls made to one method, in order
+func (f *Fake) CallsTo(method string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []Call
+	for _, c := range f.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Clears recorded calls and scripted responses
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = nil
+	f.responses = make(map[string][]fakeResponse)
+}
+
+// Records the call and takes the next scripted response
+func (f *Fake) next(method string, req any) (any, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, Call{Method: method, Request: req})
+
+	queue := f.responses[method]
+	if len(queue) == 0 {
+		f.mu.Unlock()
+		return nil, nil
+	}
+	resp := queue[0]
+	if len(queue) > 1 {
+		f.responses[method] = queue[1:]
+	}
+	f.mu.Unlock()
+
+	if resp.fn != nil {
+		return resp.fn(req)
+	}
+	return resp.value, resp.err
+}
+
+func fakeCall[T any](f *Fake, method string, req any) (T, error) {
+	var zero T
+
+	value, err := f.next(method, req)
+	if value == nil {
+		return zero, err
+	}
+
+	typed, ok := value.(T)
+	if !ok {
+		return zero, fmt.Errorf("fake response for %s is %T, want %T", method, value, zero)
+	}
+	return typed, err
+}
+
+func (f *Fake) ListEventTypes(filter types.MarketFilter) ([]types.ListEventTypesResponse, error) {
+	return fakeCall[[]types.ListEventTypesResponse](f, "ListEventTypes", filter)
+}
+
+func (f *Fake) ListCompetitions(filter types.MarketFilter) ([]types.ListCompetitionsResponse, error) {
+	return fakeCall[[]types.ListCompetitionsResponse](f, "ListCompetitions", filter)
+}
+
+func (f *Fake) ListCountries(filter types.MarketFilter) ([]types.ListCountriesResponse, error) {
+	return fakeCall[[]types.ListCountriesResponse](f, "ListCountries", filter)
+}
+
+func (f *Fake) ListEvents(filter types.MarketFilter) ([]types.ListEventsResponse, error) {
+	return fakeCall[[]types.ListEventsResponse](f, "ListEvents", filter)
+}
+
+func (f *Fake) ListMarketTypes(filter types.MarketFilter) ([]types.ListMarketTypesResponse, error) {
+	return fakeCall[[]types.ListMarketTypesResponse](f, "ListMarketTypes", filter)
+}
+
+func (f *Fake) ListMarketCatalogues(req types.ListRequest) ([]types.ListMarketCataloguesResponse, error) {
+	return fakeCall[[]types.ListMarketCataloguesResponse](f, "ListMarketCatalogues", req)
+}
+
+func (f *Fake) GetNavigationMenu() (*types.NavigationNode, error) {
+	return fakeCall[*types.NavigationNode](f, "GetNavigationMenu", nil)
+}
+
+func (f *Fake) ListMarketBook(req types.ListMarketBookRequest) ([]types.ListMarketBookResponse, error) {
+	return fakeCall[[]types.ListMarketBookResponse](f, "ListMarketBook", req)
+}
+
+func (f *Fake) ListRaceDetails(req types.ListRaceDetailsRequest) ([]types.RaceDetails, error) {
+	return fakeCall[[]types.RaceDetails](f, "ListRaceDetails", req)
+}
+
+func (f *Fake) ListRaceDetailsForMarkets(markets []types.ListMarketCataloguesResponse) ([]types.RaceDetails, error) {
+	return fakeCall[[]types.RaceDetails](f, "ListRaceDetailsForMarkets", markets)
+}
+
+func (f *Fake) ListAvailableEvents(req types.ListAvailableEventsRequest) ([]types.AvailableEvent, error) {
+	return fakeCall[[]types.AvailableEvent](f, "ListAvailableEvents", req)
+}
+
+func (f *Fake) ListScores(req types.ListScoresRequest) ([]types.Score, error) {
+	return fakeCall[[]types.Score](f, "ListScores", req)
+}
+
+func (f *Fake) ListIncidents(req types.ListIncidentsRequest) ([]types.EventIncidents, error) {
+	return fakeCall[[]types.EventIncidents](f, "ListIncidents", req)
+}
+
+func (f *Fake) PlaceOrders(req types.PlaceOrdersRequest) (types.PlaceExecutionReport, error) {
+	return fakeCall[types.PlaceExecutionReport](f, "PlaceOrders", req)
+}
+
+func (f *Fake) CancelOrders(req types.CancelOrdersRequest) (types.CancelExecutionReport, error) {
+	return fakeCall[types.CancelExecutionReport](f, "CancelOrders", req)
+}
+
+func (f *Fake) ReplaceOrders(req types.ReplaceOrdersRequest) (types.ReplaceExecutionReport, error) {
+	return fakeCall[types.ReplaceExecutionReport](f, "ReplaceOrders", req)
+}
+
+func (f *Fake) UpdateOrders(req types.UpdateOrdersRequest) (types.UpdateExecutionReport, error) {
+	return fakeCall[types.UpdateExecutionReport](f, "UpdateOrders", req)
+}
+
+func (f *Fake) ListCurrentOrders(req types.ListCurrentOrdersRequest) (types.CurrentOrderSummaryReport, error) {
+	return fakeCall[types.CurrentOrderSummaryReport](f, "ListCurrentOrders", req)
+}
+
+func (f *Fake) GetAccountFunds(req types.GetAccountFundsRequest) (types.AccountFundsResponse, error) {
+	return fakeCall[types.AccountFundsResponse](f, "GetAccountFunds", req)
+}
+
+func (f *Fake) AppKey() string {
+	return f.appKey
+}
+
+func (f *Fake) SessionToken() (string, error) {
+	return f.token, nil
+}
+
+// Records one ExecuteBatch call, then answers each batched call from its scripted responses
+func (f *Fake) ExecuteBatch(batch *Batch) error {
+	if len(batch.calls) == 0 {
+		return fmt.Errorf("batch is empty")
+	}
+
+	if _, err := f.next("ExecuteBatch", batch); err != nil {
+		return err
+	}
+
+	for i, c := range batch.calls {
+		resp := types.RPCResponse{JsonRPC: "2.0", Id: i + 1, Result: json.RawMessage("null")}
+
+		value, err := f.next(c.method, c.params)
+		if err != nil {
+			rpcErr, ok := err.(*types.RPCError)
+			if !ok {
+				rpcErr = &types.RPCError{Code: -32099, Message: err.Error()}
+			}
+			resp.Error = rpcErr
+		} else {
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("unable to marshal fake response for %s: %w", c.method, err)
+			}
+			resp.Result = raw
+		}
+
+		c.decode(resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+// client/fake_test.go
+
+package client_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/client"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Code under test only sees the interface
+func marketStatus(api client.MarketData, marketId string) (types.MarketStatus, error) {
+	books, err := api.ListMarketBook(types.ListMarketBookRequest{MarketIds: []string{marketId}})
+	if err != nil || len(books) == 0 {
+		return "", err
+	}
+	return books[0].Status, nil
+}
+
+func TestFake_RecordsCallsAndScriptsResponses(t *testing.T) {
+	fake := client.NewFake().
+		Respond("ListMarketBook", []types.ListMarketBookResponse{{MarketId: "1.1", Status: types.OPEN}}, nil).
+		Respond("ListMarketBook", nil, errors.New("boom")).
+		Respond("ListMarketBook", []types.ListMarketBookResponse{{MarketId: "1.1", Status: types.CLOSED}}, nil)
+
+	status, err := marketStatus(fake, "1.1")
+	require.NoError(t, err)
+	assert.Equal(t, types.OPEN, status)
+
+	_, err = marketStatus(fake, "1.1")
+	assert.EqualError(t, err, "boom")
+
+	// The last response repeats
+	for range 2 {
+		status, err = marketStatus(fake, "1.1")
+		require.NoError(t, err)
+		assert.Equal(t, types.CLOSED, status)
+	}
+
+	calls := fake.CallsTo("ListMarketBook")
+	require.Len(t, calls, 4)
+	assert.Equal(t, []string{"1.1"}, calls[0].Request.(types.ListMarketBookRequest).MarketIds)
+
+	// Unscripted methods return zero values, wrongly typed responses are reported
+	funds, err := fake.GetAccountFunds(types.GetAccountFundsRequest{})
+	require.NoError(t, err)
+	assert.Zero(t, funds)
+
+	fake.Respond("PlaceOrders", "not a report", nil)
+	_, err = fake.PlaceOrders(types.PlaceOrdersRequest{})
+	assert.ErrorContains(t, err, "fake response for PlaceOrders")
+}
+
+func TestFake_ExecuteBatch(t *testing.T) {
+	fake := client.NewFake().
+		Respond("SportsAPING/v1.0/listMarketBook", []types.ListMarketBookResponse{{MarketId: "1.1"}}, nil).
+		Respond("SportsAPING/v1.0/listEvents", nil, errors.New("no events"))
+
+	batch := client.NewBatch()
+	books := client.AddCall[[]types.ListMarketBookResponse](batch, "listMarketBook", nil)
+	events := client.AddCall[[]types.ListEventsResponse](batch, "listEvents", nil)
+
+	require.NoError(t, fake.ExecuteBatch(batch))
+	require.NoError(t, books.Err)
+	assert.Equal(t, "1.1", books.Value[0].MarketId)
+	assert.ErrorContains(t, events.Err, "no events")
+	assert.Len(t, fake.CallsTo("ExecuteBatch"), 1)
+}
@@ -6,77 +6,41 @@ import (
 	"fmt"
 
 	"github.com/Bazcampbell/betfair-api-go-sdk/types"
-	"github.com/Bazcampbell/betfair-api-go-sdk/util"
 )
 
 const BASE_URL = "https://api.betfair.com/exchange/betting/rest/v1.0/"
 
 func (b *BetfairClient) ListEventTypes(filter types.MarketFilter) ([]types.ListEventTypesResponse, error) {
-	token, err := b.getSessionToken()
-	if err != nil {
-		return nil, err
-	}
-
 	body := types.ListRequest{Filter: filter}
-	return util.GenericPost[[]types.ListEventTypesResponse](b.client, "listEventTypes/", b.creds.AppKey, token, body)
+	return post[[]types.ListEventTypesResponse](b, sportsService, "listEventTypes", body)
 }
 
 func (b *BetfairClient) ListCompetitions(filter types.MarketFilter) ([]types.ListCompetitionsResponse, error) {
-	token, err := b.getSessionToken()
-	if err != nil {
-		return nil, err
-	}
-
 	body := types.ListRequest{Filter: filter}
-	return util.GenericPost[[]types.ListCompetitionsResponse](b.client, "listCompetitions/", b.creds.AppKey, token, body)
+	return post[[]types.ListCompetitionsResponse](b, sportsService, "listCompetitions", body)
 }
 
 func (b *BetfairClient) ListCountries(filter types.MarketFilter) ([]types.ListCountriesResponse, error) {
-	token, err := b.getSessionToken()
-	if err != nil {
-		return nil, err
-	}
-
 	body := types.ListRequest{Filter: filter}
-	return util.GenericPost[[]types.ListCountriesResponse](b.client, "listCountries/", b.creds.AppKey, token, body)
+	return post[[]types.ListCountriesResponse](b, sportsService, "listCountries", body)
 }
 
 func (b *BetfairClient) ListEvents(filter types.MarketFilter) ([]types.ListEventsResponse, error) {
-	token, err := b.getSessionToken()
-	if err != nil {
-		return nil, err
-	}
-
 	body := types.ListRequest{Filter: filter}
-	return util.GenericPost[[]types.ListEventsResponse](b.client, "listEvents/", b.creds.AppKey, token, body)
+	return post[[]types.ListEventsResponse](b, sportsService, "listEvents", body)
 }
 
 func (b *BetfairClient) ListMarketTypes(filter types.MarketFilter) ([]types.ListMarketTypesResponse, error) {
-	token, err := b.getSessionToken()
-	if err != nil {
-		return nil, err
-	}
-
 	body := types.ListRequest{Filter: filter}
-	return util.GenericPost[[]types.ListMarketTypesResponse](b.client, "listMarketTypes/", b.creds.AppKey, token, body)
+	return post[[]types.ListMarketTypesResponse](b, sportsService, "listMarketTypes", body)
 }
 
 func (b *BetfairClient) ListMarketCatalogues(req types.ListRequest) ([]types.ListMarketCataloguesResponse, error) {
-	token, err := b.getSessionToken()
-	if err != nil {
-		return nil, err
-	}
-
-	return util.GenericPost[[]types.ListMarketCataloguesResponse](b.client, "listMarketCatalogue/", b.creds.AppKey, token, req)
+	return post[[]types.ListMarketCataloguesResponse](b, sportsService, "listMarketCatalogue", req)
 }
 
 func (b *BetfairClient) ListMarketBook(req types.ListMarketBookRequest) ([]types.ListMarketBookResponse, error) {
-	token, err := b.getSessionToken()
-	if err != nil {
-		return nil, err
-	}
-
-	result, err := util.GenericPost[[]types.ListMarketBookResponse](b.client, "listMarketBook/", b.creds.AppKey, token, req)
+	result, err := post[[]types.ListMarketBookResponse](b, sportsService, "listMarketBook", req)
 	if err != nil {
 		return nil, err
 	}
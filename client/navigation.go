@@ -0,0 +1,21 @@
+// client/navigation.go
+
+package client
+
+import (
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+	"github.com/Bazcampbell/betfair-api-go-sdk/util"
+)
+
+const NAVIGATION_URL = "https://api.betfair.com/exchange/betting/rest/v1/en/navigation/menu.json"
+
+// Downloads the full exchange navigation menu
+// The menu is refreshed by Betfair every few minutes, so cache it rather than calling per request
+func (b *BetfairClient) GetNavigationMenu() (*types.NavigationNode, error) {
+	token, err := b.getSessionToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return util.GenericGetUrl[*types.NavigationNode](b.client, b.urls.navigation, b.creds.AppKey, token)
+}
@@ -0,0 +1,49 @@
+// client/navigation_test.go
+
+package client_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/betfairtest"
+	"github.com/Bazcampbell/betfair-api-go-sdk/client"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNavigationMenu(t *testing.T) {
+	srv, err := betfairtest.NewServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	require.NoError(t, srv.SetFixture(betfairtest.OP_NAVIGATION, json.RawMessage(`{
+		"type": "GROUP", "name": "ROOT", "id": 0,
+		"children": [
+			{"type": "EVENT_TYPE", "name": "Soccer", "id": "1", "children": [
+				{"type": "EVENT", "name": "Arsenal v Chelsea", "id": "33000001", "countryCode": "GB", "children": [
+					{"type": "MARKET", "name": "Match Odds", "id": "1.300000001", "marketType": "MATCH_ODDS", "numberOfWinners": 1}
+				]}
+			]}
+		]
+	}`)))
+
+	bf, err := client.NewSession(srv.Credentials(), func(error) {}, client.WithBaseURL(srv.URL()))
+	require.NoError(t, err)
+
+	root, err := bf.GetNavigationMenu()
+	require.NoError(t, err)
+
+	assert.Equal(t, types.NavigationId("0"), root.Id)
+	assert.Equal(t, []string{"1.300000001"}, root.MarketIds())
+
+	events := root.FilterByType(types.NAV_EVENT)
+	require.Len(t, events, 1)
+	assert.Equal(t, "GB", events[0].CountryCode)
+
+	req := srv.AssertCalled(t, betfairtest.OP_NAVIGATION)
+	assert.Equal(t, betfairtest.APP_KEY, req.Header.Get("X-Application"))
+	assert.NotEmpty(t, req.Header.Get("X-Authentication"))
+}
@@ -0,0 +1,127 @@
+// client/order_endpoints.go
+
+package client
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/risk"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+	"github.com/Bazcampbell/betfair-api-go-sdk/validation"
+)
+
+// Checks every order request before it is sent, see the validation package
+// Rejected requests return a *validation.ValidationError and never reach Betfair
+func WithValidator(v *validation.Validator) Option {
+	return func(b *BetfairClient) {
+		b.validator = v
+	}
+}
+
+// Checks place and replace requests against risk limits before they are sent, see the risk package
+// Breaches return a *risk.Rejection, accepted orders are added to the engine's positions.
+// A request that fails in transit stays in the engine as provisional positions until a Refresh or
+// the order stream resolves it, since it may have reached Betfair
+func WithRiskEngine(e *risk.Engine) Option {
+	return func(b *BetfairClient) {
+		b.risk = e
+	}
+}
+
+// Orders are sent once and never retried, a CustomerRef is generated when none is set so Betfair
+// de-duplicates the request. After a transport error the report still carries the CustomerRef,
+// resending the same request with it within 60 seconds is safe
+func (b *BetfairClient) PlaceOrders(req types.PlaceOrdersRequest) (types.PlaceExecutionReport, error) {
+	if req.CustomerRef == "" {
+		ref, err := newCustomerRef()
+		if err != nil {
+			return types.PlaceExecutionReport{}, err
+		}
+		req.CustomerRef = ref
+	}
+
+	if b.validator != nil {
+		if err := b.validator.ValidatePlace(req); err != nil {
+			return types.PlaceExecutionReport{}, err
+		}
+	}
+
+	if b.risk != nil {
+		if err := b.risk.CheckPlace(req); err != nil {
+			return types.PlaceExecutionReport{}, err
+		}
+		b.risk.Pending(req)
+	}
+
+	report, err := post[types.PlaceExecutionReport](b, sportsService, "placeOrders", req)
+	if err == nil && b.risk != nil {
+		b.risk.Placed(req, report)
+	}
+
+	if report.CustomerRef == "" {
+		report.CustomerRef = req.CustomerRef
+	}
+
+	return report, err
+}
+
+// Betfair accepts up to 32 characters
+func newCustomerRef() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("unable to generate customer ref: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (b *BetfairClient) CancelOrders(req types.CancelOrdersRequest) (types.CancelExecutionReport, error) {
+	if b.validator != nil {
+		if err := b.validator.ValidateCancel(req); err != nil {
+			return types.CancelExecutionReport{}, err
+		}
+	}
+
+	report, err := post[types.CancelExecutionReport](b, sportsService, "cancelOrders", req)
+	if err == nil && b.risk != nil {
+		b.risk.Cancelled(report)
+	}
+
+	return report, err
+}
+
+func (b *BetfairClient) ReplaceOrders(req types.ReplaceOrdersRequest) (types.ReplaceExecutionReport, error) {
+	if b.validator != nil {
+		if err := b.validator.ValidateReplace(req); err != nil {
+			return types.ReplaceExecutionReport{}, err
+		}
+	}
+
+	if b.risk != nil {
+		if err := b.risk.CheckReplace(req); err != nil {
+			return types.ReplaceExecutionReport{}, err
+		}
+	}
+
+	report, err := post[types.ReplaceExecutionReport](b, sportsService, "replaceOrders", req)
+	if err == nil && b.risk != nil {
+		b.risk.Replaced(req, report)
+	}
+
+	return report, err
+}
+
+func (b *BetfairClient) UpdateOrders(req types.UpdateOrdersRequest) (types.UpdateExecutionReport, error) {
+	if b.validator != nil {
+		if err := b.validator.ValidateUpdate(req); err != nil {
+			return types.UpdateExecutionReport{}, err
+		}
+	}
+
+	return post[types.UpdateExecutionReport](b, sportsService, "updateOrders", req)
+}
+
+func (b *BetfairClient) ListCurrentOrders(req types.ListCurrentOrdersRequest) (types.CurrentOrderSummaryReport, error) {
+	return post[types.CurrentOrderSummaryReport](b, sportsService, "listCurrentOrders", req)
+}
@@ -0,0 +1,72 @@
+// client/order_endpoints_test.go
+
+package client_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/betfairtest"
+	"github.com/Bazcampbell/betfair-api-go-sdk/client"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// Fails every request mentioning operation as if the connection dropped after it was sent
+func dropping(operation string, sent *atomic.Int32, refs *[]string) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			if !strings.Contains(r.URL.Path+string(body), operation) {
+				return next.RoundTrip(r)
+			}
+
+			sent.Add(1)
+			if i := bytes.Index(body, []byte(`"customerRef":"`)); i >= 0 {
+				ref := body[i+len(`"customerRef":"`):]
+				*refs = append(*refs, string(ref[:bytes.IndexByte(ref, '"')]))
+			}
+			return nil, errors.New("connection reset")
+		})
+	}
+}
+
+func TestPlaceOrders_SentOnceWithCustomerRef(t *testing.T) {
+	for name, transport := range map[string]client.Transport{"rest": client.TRANSPORT_REST, "json-rpc": client.TRANSPORT_JSON_RPC} {
+		t.Run(name, func(t *testing.T) {
+			srv, err := betfairtest.NewServer()
+			require.NoError(t, err)
+			defer srv.Close()
+
+			var sent atomic.Int32
+			var refs []string
+			bf, err := client.NewSession(srv.Credentials(), func(error) {}, client.WithBaseURL(srv.URL()),
+				client.WithTransport(transport), client.WithRoundTripper(dropping("placeOrders", &sent, &refs)))
+			require.NoError(t, err)
+
+			report, err := bf.PlaceOrders(types.PlaceOrdersRequest{MarketId: "1.1", Instructions: []types.PlaceInstruction{{
+				OrderType: types.LIMIT, SelectionId: 1, Side: types.BACK,
+				LimitOrder: &types.LimitOrder{Price: 2, Size: 5, PersistenceType: types.LAPSE},
+			}}})
+			require.Error(t, err)
+
+			assert.EqualValues(t, 1, sent.Load())
+			require.Len(t, refs, 1)
+			assert.Len(t, refs[0], 32)
+			assert.Equal(t, refs[0], report.CustomerRef)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// client/race_endpoints.go
+
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+const SCORES_URL = "https://api.betfair.com/exchange/scores/rest/v1.0/"
+
+// Returns race status for horse and greyhound races
+// Leave both slices empty to get every race currently covered by the service
+func (b *BetfairClient) ListRaceDetails(req types.ListRaceDetailsRequest) ([]types.RaceDetails, error) {
+	return post[[]types.RaceDetails](b, scoresService, "listRaceDetails", req)
+}
+
+// Meeting ids are the event ids of racing events
+func MeetingIdsFromEvents(events []types.ListEventsResponse) []string {
+	seen := make(map[string]bool)
+	var ids []string
+
+	for _, e := range events {
+		if e.Event.Id == "" || seen[e.Event.Id] {
+			continue
+		}
+		seen[e.Event.Id] = true
+		ids = append(ids, e.Event.Id)
+	}
+
+	return ids
+}
+
+// Builds race ids from market catalogues
+// Race ids are formatted as meetingId.HHMM with the start time in GMT, all year round
+// Markets must be requested with the EVENT and MARKET_START_TIME projections
+func RaceIdsFromMarkets(markets []types.ListMarketCataloguesResponse) ([]string, error) {
+	seen := make(map[string]bool)
+	var ids []string
+
+	for _, m := range markets {
+		if m.Event == nil || m.Event.Id == "" {
+			return nil, fmt.Errorf("market %s has no event, request the EVENT projection", m.MarketId)
+		}
+
+		if m.MarketStartTime == "" {
+			return nil, fmt.Errorf("market %s has no start time, request the MARKET_START_TIME projection", m.MarketId)
+		}
+
+		start, err := time.Parse(time.RFC3339, m.MarketStartTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start time for market %s: %w", m.MarketId, err)
+		}
+
+		id := m.Event.Id + "." + start.UTC().Format("1504")
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Convenience wrapper resolving race ids from market catalogues
+func (b *BetfairClient) ListRaceDetailsForMarkets(markets []types.ListMarketCataloguesResponse) ([]types.RaceDetails, error) {
+	raceIds, err := RaceIdsFromMarkets(markets)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raceIds) == 0 {
+		return nil, nil
+	}
+
+	return b.ListRaceDetails(types.ListRaceDetailsRequest{RaceIds: raceIds})
+}
@@ -0,0 +1,95 @@
+// client/race_endpoints_test.go
+
+package client_test
+
+import (
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/betfairtest"
+	"github.com/Bazcampbell/betfair-api-go-sdk/client"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func raceMarket(marketId, eventId, start string) types.ListMarketCataloguesResponse {
+	return types.ListMarketCataloguesResponse{MarketId: marketId, MarketStartTime: start, Event: &types.Event{Id: eventId}}
+}
+
+func TestRaceIdsFromMarkets(t *testing.T) {
+	tests := []struct {
+		name    string
+		markets []types.ListMarketCataloguesResponse
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "winter",
+			markets: []types.ListMarketCataloguesResponse{raceMarket("1.1", "28587288", "2024-01-15T14:30:00.000Z")},
+			want:    []string{"28587288.1430"},
+		},
+		{
+			name:    "summer time stays in GMT",
+			markets: []types.ListMarketCataloguesResponse{raceMarket("1.1", "28587288", "2024-07-15T14:30:00.000Z")},
+			want:    []string{"28587288.1430"},
+		},
+		{
+			name:    "offset start times are converted to GMT",
+			markets: []types.ListMarketCataloguesResponse{raceMarket("1.1", "28587288", "2024-07-15T15:30:00+01:00")},
+			want:    []string{"28587288.1430"},
+		},
+		{
+			name:    "clocks going forward",
+			markets: []types.ListMarketCataloguesResponse{raceMarket("1.1", "1", "2024-03-31T00:30:00Z"), raceMarket("1.2", "1", "2024-03-31T01:30:00Z")},
+			want:    []string{"1.0030", "1.0130"},
+		},
+		{
+			name:    "win and place markets share a race",
+			markets: []types.ListMarketCataloguesResponse{raceMarket("1.1", "1", "2024-10-27T01:15:00Z"), raceMarket("1.2", "1", "2024-10-27T01:15:00Z")},
+			want:    []string{"1.0115"},
+		},
+		{
+			name:    "missing event",
+			markets: []types.ListMarketCataloguesResponse{{MarketId: "1.1", MarketStartTime: "2024-01-15T14:30:00Z"}},
+			wantErr: "EVENT projection",
+		},
+		{
+			name:    "missing start time",
+			markets: []types.ListMarketCataloguesResponse{raceMarket("1.1", "1", "")},
+			wantErr: "MARKET_START_TIME projection",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := client.RaceIdsFromMarkets(tt.markets)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestListRaceDetails_UsesSessionTransport(t *testing.T) {
+	srv, err := betfairtest.NewServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	require.NoError(t, srv.SetFixture("listRaceDetails", []types.RaceDetails{{MeetingId: "1", RaceId: "1.1430", RaceStatus: types.DORMANT}}))
+
+	bf, err := client.NewSession(srv.Credentials(), func(error) {},
+		client.WithBaseURL(srv.URL()), client.WithTransport(client.TRANSPORT_JSON_RPC))
+	require.NoError(t, err)
+
+	races, err := bf.ListRaceDetails(types.ListRaceDetailsRequest{RaceIds: []string{"1.1430"}})
+	require.NoError(t, err)
+	require.Len(t, races, 1)
+	assert.Equal(t, "1.1430", races[0].RaceId)
+
+	req := srv.AssertCalled(t, "listRaceDetails")
+	assert.Equal(t, "/exchange/scores/json-rpc/v1", req.Path)
+}
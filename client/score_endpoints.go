@@ -0,0 +1,23 @@
+// client/score_endpoints.go
+
+package client
+
+import (
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+// Returns the events currently covered by the scores service
+func (b *BetfairClient) ListAvailableEvents(req types.ListAvailableEventsRequest) ([]types.AvailableEvent, error) {
+	return post[[]types.AvailableEvent](b, scoresService, "listAvailableEvents", req)
+}
+
+// Returns the current score per event
+// Use types.NextUpdateKeys on the result to poll incrementally
+func (b *BetfairClient) ListScores(req types.ListScoresRequest) ([]types.Score, error) {
+	return post[[]types.Score](b, scoresService, "listScores", req)
+}
+
+// Returns goals, cards, breaks of serve etc. per event
+func (b *BetfairClient) ListIncidents(req types.ListIncidentsRequest) ([]types.EventIncidents, error) {
+	return post[[]types.EventIncidents](b, scoresService, "listIncidents", req)
+}
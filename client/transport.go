@@ -0,0 +1,113 @@
+// client/transport.go
+
+package client
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/util"
+)
+
+type Transport int
+
+const (
+	TRANSPORT_REST Transport = iota
+	TRANSPORT_JSON_RPC
+)
+
+type Option func(*BetfairClient)
+
+// Selects how API operations are sent, REST is the default
+func WithTransport(t Transport) Option {
+	return func(b *BetfairClient) {
+		b.transport = t
+	}
+}
+
+// Wraps the HTTP transport built by NewSession (client certificate, proxy)
+// e.g. to record or replay traffic with a betfairtest.Cassette
+func WithRoundTripper(wrap func(http.RoundTripper) http.RoundTripper) Option {
+	return func(b *BetfairClient) {
+		b.client.Transport = wrap(b.client.Transport)
+	}
+}
+
+type service int
+
+const (
+	sportsService service = iota
+	accountService
+	scoresService
+)
+
+const (
+	SPORTS_RPC_PREFIX  = "SportsAPING/v1.0/"
+	ACCOUNT_RPC_PREFIX = "AccountAPING/v1.0/"
+	SCORES_RPC_PREFIX  = "ScoresAPING/v1.0/"
+)
+
+func (s service) String() string {
+	switch s {
+	case accountService:
+		return "account"
+	case scoresService:
+		return "scores"
+	}
+	return "betting"
+}
+
+func (s service) rpcMethod(operation string) string {
+	switch s {
+	case accountService:
+		return ACCOUNT_RPC_PREFIX + operation
+	case scoresService:
+		return SCORES_RPC_PREFIX + operation
+	}
+	return SPORTS_RPC_PREFIX + operation
+}
+
+// Resolves an operation name to its service
+// Fully qualified JSON-RPC method names are accepted as well as bare operation names
+func parseOperation(name string) (service, string) {
+	switch {
+	case strings.HasPrefix(name, ACCOUNT_RPC_PREFIX):
+		return accountService, strings.TrimPrefix(name, ACCOUNT_RPC_PREFIX)
+	case strings.HasPrefix(name, SCORES_RPC_PREFIX):
+		return scoresService, strings.TrimPrefix(name, SCORES_RPC_PREFIX)
+	case strings.HasPrefix(name, SPORTS_RPC_PREFIX):
+		return sportsService, strings.TrimPrefix(name, SPORTS_RPC_PREFIX)
+	}
+
+	return sportsService, name
+}
+
+// Operations that change orders are never retried, a request that timed out may still have been executed
+var unsafeOperations = map[string]bool{
+	"placeOrders":   true,
+	"cancelOrders":  true,
+	"replaceOrders": true,
+}
+
+// Sends an API operation using the transport selected on NewSession
+func post[T any](b *BetfairClient, svc service, operation string, body any) (T, error) {
+	token, err := b.getSessionToken()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	once := unsafeOperations[operation]
+
+	if b.transport == TRANSPORT_JSON_RPC {
+		if once {
+			return util.JSONRPCPostOnce[T](b.client, b.urls.rpc(svc), b.creds.AppKey, token, svc.rpcMethod(operation), body)
+		}
+		return util.JSONRPCPost[T](b.client, b.urls.rpc(svc), b.creds.AppKey, token, svc.rpcMethod(operation), body)
+	}
+
+	if once {
+		return util.GenericPostUrlOnce[T](b.client, b.urls.rest(svc)+operation+"/", b.creds.AppKey, token, body)
+	}
+	return util.GenericPostUrl[T](b.client, b.urls.rest(svc)+operation+"/", b.creds.AppKey, token, body)
+}
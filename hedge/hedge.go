@@ -0,0 +1,274 @@
+// hedge/hedge.go
+
+package hedge
+
+// Green-up / hedge calculator.
+// Hedging a runner sizes one opposing bet so its profit is the same whether it wins or loses.
+// Once every runner in a market is hedged that way the market pays the same whatever the result.
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/ladder"
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+var (
+	ErrFlat          = errors.New("position is already level")
+	ErrNoPrice       = errors.New("no price available to hedge at")
+	ErrBelowMinStake = errors.New("hedge stake is below the minimum")
+	ErrBadFraction   = errors.New("fraction must be above 0 and at most 1")
+)
+
+// A matched bet
+type Bet struct {
+	Side  types.Side
+	Price float64
+	Size  float64
+}
+
+// Matched bets on one runner
+type RunnerPosition struct {
+	SelectionId int64
+	Handicap    float64
+	Bets        []Bet
+}
+
+// Profit if the runner wins and if it loses
+func (p RunnerPosition) Outcomes() (ifWins, ifLoses float64) {
+	for _, b := range p.Bets {
+		if b.Side == types.LAY {
+			ifWins -= b.Size * (b.Price - 1)
+			ifLoses += b.Size
+			continue
+		}
+		ifWins += b.Size * (b.Price - 1)
+		ifLoses -= b.Size
+	}
+	return
+}
+
+// Position from the order stream's matched ladders, e.g. stream.RunnerOrders.Matched
+func FromMatched(selectionId int64, handicap float64, m stream.MatchedPositions) RunnerPosition {
+	p := RunnerPosition{SelectionId: selectionId, Handicap: handicap}
+	for _, b := range m.Backs {
+		p.Bets = append(p.Bets, Bet{Side: types.BACK, Price: float64(b.Price), Size: float64(b.Size)})
+	}
+	for _, l := range m.Lays {
+		p.Bets = append(p.Bets, Bet{Side: types.LAY, Price: float64(l.Price), Size: float64(l.Size)})
+	}
+	return p
+}
+
+// Positions per runner from the matched part of ListCurrentOrders results for one market
+func FromOrders(orders []types.CurrentOrderSummary) []RunnerPosition {
+	var positions []RunnerPosition
+	index := make(map[[2]float64]int)
+
+	for _, o := range orders {
+		if o.SizeMatched == 0 {
+			continue
+		}
+		key := [2]float64{float64(o.SelectionId), o.Handicap}
+		i, ok := index[key]
+		if !ok {
+			i = len(positions)
+			index[key] = i
+			positions = append(positions, RunnerPosition{SelectionId: o.SelectionId, Handicap: o.Handicap})
+		}
+		positions[i].Bets = append(positions[i].Bets, Bet{Side: o.Side, Price: o.AveragePriceMatched, Size: o.SizeMatched})
+	}
+
+	return positions
+}
+
+type Options struct {
+	Ladder   *ladder.Ladder // defaults to the classic ladder
+	Fraction float64        // share of the full hedge to place, defaults to 1
+	MinStake float64        // hedges below this return ErrBelowMinStake, 0 skips the check
+}
+
+func (o Options) withDefaults() (Options, error) {
+	if o.Ladder == nil {
+		o.Ladder = ladder.Classic()
+	}
+	if o.Fraction == 0 {
+		o.Fraction = 1
+	}
+	if o.Fraction < 0 || o.Fraction > 1 {
+		return o, fmt.Errorf("%g: %w", o.Fraction, ErrBadFraction)
+	}
+	return o, nil
+}
+
+// Bet that hedges a runner, with the runner's profit once it is matched
+type Hedge struct {
+	SelectionId   int64
+	Handicap      float64
+	Side          types.Side
+	Price         float64
+	Size          float64
+	ProfitIfWins  float64
+	ProfitIfLoses float64
+}
+
+// Hedge for one runner at its best available price
+// Backs at the best back price when winning pays less than losing, otherwise lays at the best lay price
+func Runner(pos RunnerPosition, book types.Runner, opts Options) (Hedge, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return Hedge{}, err
+	}
+
+	ifWins, ifLoses := pos.Outcomes()
+	h := Hedge{SelectionId: pos.SelectionId, Handicap: pos.Handicap, ProfitIfWins: ifWins, ProfitIfLoses: ifLoses}
+
+	diff := ifWins - ifLoses
+	if math.Abs(diff) < 0.01 {
+		return h, ErrFlat
+	}
+
+	var price float64
+	if diff > 0 {
+		if len(book.Ex.Lay) == 0 {
+			return h, fmt.Errorf("lay selection %d: %w", pos.SelectionId, ErrNoPrice)
+		}
+		h.Side = types.LAY
+		price, err = opts.Ladder.Snap(float64(book.Ex.Lay[0].Price), ladder.SNAP_UP)
+	} else {
+		if len(book.Ex.Back) == 0 {
+			return h, fmt.Errorf("back selection %d: %w", pos.SelectionId, ErrNoPrice)
+		}
+		h.Side = types.BACK
+		price, err = opts.Ladder.Snap(float64(book.Ex.Back[0].Price), ladder.SNAP_DOWN)
+	}
+	if err != nil {
+		return h, fmt.Errorf("unable to price hedge for selection %d: %w", pos.SelectionId, err)
+	}
+
+	return AtPrice(pos, h.Side, price, opts)
+}
+
+// Hedge for one runner at a chosen side and price, e.g. to rest a hedge a tick inside the spread
+func AtPrice(pos RunnerPosition, side types.Side, price float64, opts Options) (Hedge, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return Hedge{}, err
+	}
+
+	ifWins, ifLoses := pos.Outcomes()
+	h := Hedge{SelectionId: pos.SelectionId, Handicap: pos.Handicap, Side: side, ProfitIfWins: ifWins, ProfitIfLoses: ifLoses}
+
+	if err := opts.Ladder.Validate(price); err != nil {
+		return h, err
+	}
+
+	// Laying S at p: wins - S(p-1) = loses + S, so S = (wins - loses) / p, backing mirrors it
+	size := (ifWins - ifLoses) / price
+	if side == types.BACK {
+		size = -size
+	}
+	if size <= 0 {
+		return h, fmt.Errorf("%s hedge on selection %d: %w", side, pos.SelectionId, ErrFlat)
+	}
+
+	h.Price = price
+	h.Size = math.Round(size*opts.Fraction*100) / 100
+	if h.Size == 0 {
+		return h, ErrFlat
+	}
+	if opts.MinStake > 0 && h.Size < opts.MinStake {
+		return h, fmt.Errorf("%.2f: %w", h.Size, ErrBelowMinStake)
+	}
+
+	hedged := pos
+	hedged.Bets = append(append([]Bet(nil), pos.Bets...), Bet{Side: side, Price: price, Size: h.Size})
+	h.ProfitIfWins, h.ProfitIfLoses = hedged.Outcomes()
+
+	return h, nil
+}
+
+// Profit is the market's profit for each runner winning once the hedges match, keyed by selection id
+// Guaranteed is the least of them, or of the market's profit if a runner without a position wins
+type MarketHedge struct {
+	Hedges     []Hedge
+	Profit     map[int64]float64
+	Guaranteed float64
+}
+
+// Hedges every runner with a position, skipping runners that are already level
+func Market(positions []RunnerPosition, book types.ListMarketBookResponse, opts Options) (MarketHedge, error) {
+	runners := make(map[[2]float64]types.Runner, len(book.Runners))
+	for _, r := range book.Runners {
+		runners[[2]float64{float64(r.SelectionId), float64(r.Handicap)}] = r
+	}
+
+	result := MarketHedge{Profit: make(map[int64]float64)}
+
+	type outcome struct{ ifWins, ifLoses float64 }
+	outcomes := make([]outcome, 0, len(positions))
+
+	for _, pos := range positions {
+		r, ok := runners[[2]float64{float64(pos.SelectionId), pos.Handicap}]
+		if !ok {
+			return result, fmt.Errorf("selection %d is not in market %s: %w", pos.SelectionId, book.MarketId, ErrNoPrice)
+		}
+
+		h, err := Runner(pos, r, opts)
+		switch {
+		case errors.Is(err, ErrFlat):
+		case err != nil:
+			return result, err
+		default:
+			result.Hedges = append(result.Hedges, h)
+		}
+		outcomes = append(outcomes, outcome{h.ProfitIfWins, h.ProfitIfLoses})
+	}
+
+	var none float64
+	for _, o := range outcomes {
+		none += o.ifLoses
+	}
+
+	// A runner without a position can only win if the book has one
+	result.Guaranteed = math.Inf(1)
+	if len(book.Runners) > len(positions) {
+		result.Guaranteed = none
+	}
+
+	for i, pos := range positions {
+		profit := none - outcomes[i].ifLoses + outcomes[i].ifWins
+		result.Profit[pos.SelectionId] = math.Round(profit*100) / 100
+		result.Guaranteed = min(result.Guaranteed, profit)
+	}
+	result.Guaranteed = math.Round(result.Guaranteed*100) / 100
+
+	return result, nil
+}
+
+// Limit order placing the hedge
+func (h Hedge) Instruction(persistence types.PersistenceType) types.PlaceInstruction {
+	return types.PlaceInstruction{
+		OrderType:   types.LIMIT,
+		SelectionId: h.SelectionId,
+		Handicap:    h.Handicap,
+		Side:        h.Side,
+		LimitOrder: &types.LimitOrder{
+			Price:           h.Price,
+			Size:            h.Size,
+			PersistenceType: persistence,
+		},
+	}
+}
+
+// Limit orders placing every hedge, ready for a PlaceOrdersRequest
+func (m MarketHedge) Instructions(persistence types.PersistenceType) []types.PlaceInstruction {
+	instructions := make([]types.PlaceInstruction, len(m.Hedges))
+	for i, h := range m.Hedges {
+		instructions[i] = h.Instruction(persistence)
+	}
+	return instructions
+}
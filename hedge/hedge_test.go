@@ -0,0 +1,90 @@
+// hedge/hedge_test.go
+
+package hedge_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/hedge"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runner(selectionId int, back, lay float32) types.Runner {
+	return types.Runner{
+		SelectionId: selectionId,
+		Status:      types.ACTIVE,
+		Ex: types.Ex{
+			Back: []types.RunnerPrice{{Price: back, Size: 100}},
+			Lay:  []types.RunnerPrice{{Price: lay, Size: 100}},
+		},
+	}
+}
+
+func TestRunner_GreensUpBothWays(t *testing.T) {
+	// Backed at 3.0, price shortened: lay 15 at 2.0 for 5 either way
+	backed := hedge.RunnerPosition{SelectionId: 1, Bets: []hedge.Bet{{Side: types.BACK, Price: 3.0, Size: 10}}}
+	h, err := hedge.Runner(backed, runner(1, 1.98, 2.0), hedge.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, types.LAY, h.Side)
+	assert.Equal(t, 2.0, h.Price)
+	assert.Equal(t, 15.0, h.Size)
+	assert.InDelta(t, 5, h.ProfitIfWins, 1e-9)
+	assert.InDelta(t, 5, h.ProfitIfLoses, 1e-9)
+
+	// Laid at 2.0, price drifted: back at 3.0
+	laid := hedge.RunnerPosition{SelectionId: 1, Bets: []hedge.Bet{{Side: types.LAY, Price: 2.0, Size: 10}}}
+	h, err = hedge.Runner(laid, runner(1, 3.0, 3.05), hedge.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, types.BACK, h.Side)
+	assert.InDelta(t, 6.67, h.Size, 1e-9)
+	assert.InDelta(t, h.ProfitIfWins, h.ProfitIfLoses, 0.02)
+
+	// Half a hedge leaves some of the position running
+	h, err = hedge.Runner(backed, runner(1, 1.98, 2.0), hedge.Options{Fraction: 0.5})
+	require.NoError(t, err)
+	assert.Equal(t, 7.5, h.Size)
+	assert.InDelta(t, 12.5, h.ProfitIfWins, 1e-9)
+	assert.InDelta(t, -2.5, h.ProfitIfLoses, 1e-9)
+
+	_, err = hedge.Runner(backed, runner(1, 1.98, 2.0), hedge.Options{MinStake: 20})
+	assert.True(t, errors.Is(err, hedge.ErrBelowMinStake))
+
+	_, err = hedge.Runner(hedge.RunnerPosition{SelectionId: 1}, runner(1, 2, 2.02), hedge.Options{})
+	assert.True(t, errors.Is(err, hedge.ErrFlat))
+}
+
+func TestAtPrice_RejectsOffLadderPrices(t *testing.T) {
+	backed := hedge.RunnerPosition{SelectionId: 1, Bets: []hedge.Bet{{Side: types.BACK, Price: 3.0, Size: 10}}}
+	_, err := hedge.AtPrice(backed, types.LAY, 2.01, hedge.Options{})
+	assert.Error(t, err)
+}
+
+func TestMarket_EqualisesEveryOutcome(t *testing.T) {
+	book := types.ListMarketBookResponse{MarketId: "1.1", Runners: []types.Runner{
+		runner(1, 1.98, 2.0), runner(2, 3.0, 3.05), runner(3, 8.0, 8.4),
+	}}
+	positions := hedge.FromOrders([]types.CurrentOrderSummary{
+		{SelectionId: 1, Side: types.BACK, AveragePriceMatched: 3.0, SizeMatched: 10},
+		{SelectionId: 2, Side: types.LAY, AveragePriceMatched: 2.0, SizeMatched: 10},
+		{SelectionId: 2, Side: types.BACK, AveragePriceMatched: 5.0, SizeMatched: 0}, // unmatched, ignored
+	})
+	require.Len(t, positions, 2)
+
+	m, err := hedge.Market(positions, book, hedge.Options{})
+	require.NoError(t, err)
+	require.Len(t, m.Hedges, 2)
+
+	// Runner 3 winning is the same as every hedged runner losing
+	for _, profit := range m.Profit {
+		assert.InDelta(t, m.Guaranteed, profit, 0.05)
+	}
+
+	instructions := m.Instructions(types.LAPSE)
+	require.Len(t, instructions, 2)
+	assert.Equal(t, types.LIMIT, instructions[0].OrderType)
+	assert.Equal(t, m.Hedges[0].Size, instructions[0].LimitOrder.Size)
+}
@@ -0,0 +1,399 @@
+// historic/reader.go
+
+package historic
+
+// Reads Betfair historic data (BASIC/ADVANCED/PRO).
+// Each market file is bz2 compressed stream format JSON, one mcm message per line.
+// Purchases are delivered as tar bundles of these files, which are merged by publish time.
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+)
+
+var bz2Magic = []byte("BZh")
+
+const (
+	defaultMaxOpenEntries = 64
+	reopenReadAhead       = 64 // messages read each time a closed entry is reopened
+)
+
+// Yields stream messages in publish time order
+type Reader struct {
+	sources sourceHeap
+	closer  io.Closer
+	started bool
+
+	maxOpen int
+	open    int   // sources currently holding a decompressor
+	tick    int64 // last use of each open source, to close the least recently used
+}
+
+type BundleOption func(*Reader)
+
+// Caps how many bundle entries are decompressed at once, 64 by default
+// Each open bz2 entry holds a few MB, entries closed to stay under the cap are re-read from the start when next needed
+func WithMaxOpenEntries(n int) BundleOption {
+	return func(r *Reader) {
+		if n > 0 {
+			r.maxOpen = n
+		}
+	}
+}
+
+// Opens a market file (bz2 or plain JSON lines) or a tar bundle of market files
+// Tar bundles are recognised by a .tar extension, opts only apply to them
+func Open(filePath string, opts ...BundleOption) (*Reader, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open historic file: %w", err)
+	}
+
+	var r *Reader
+	if strings.HasSuffix(strings.ToLower(filePath), ".tar") {
+		r, err = NewBundleReader(file, opts...)
+	} else {
+		r, err = NewReader(file, filePath)
+	}
+
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	r.closer = file
+	return r, nil
+}
+
+// Reads a single market file, bz2 compression is detected automatically
+func NewReader(src io.Reader, name string) (*Reader, error) {
+	opened := false
+	s := &source{name: name, reopen: func() (io.Reader, error) {
+		if opened {
+			return nil, fmt.Errorf("unable to reopen %s", name)
+		}
+		opened = true
+		return src, nil
+	}}
+
+	return &Reader{sources: sourceHeap{s}, maxOpen: 1}, nil
+}
+
+// Reads every market file in a tar bundle, merged by publish time, without holding the entries in memory
+// Each entry is read in place when src can seek, e.g. an *os.File, any other src is first copied to a temp file
+func NewBundleReader(src io.Reader, opts ...BundleOption) (*Reader, error) {
+	file, ok := src.(bundleFile)
+	var spooled *tempFile
+	if !ok {
+		var err error
+		if spooled, err = spool(src); err != nil {
+			return nil, err
+		}
+		file = spooled
+	}
+
+	r, err := newIndexedBundle(file, opts)
+	if err != nil {
+		if spooled != nil {
+			spooled.Close()
+		}
+		return nil, err
+	}
+
+	if spooled != nil {
+		r.closer = spooled
+	}
+	return r, nil
+}
+
+type bundleFile interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+// Temp file removed on Close
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	return errors.Join(err, os.Remove(f.Name()))
+}
+
+func spool(src io.Reader) (*tempFile, error) {
+	file, err := os.CreateTemp("", "historic-*.tar")
+	if err != nil {
+		return nil, fmt.Errorf("unable to buffer historic bundle: %w", err)
+	}
+	spooled := &tempFile{file}
+
+	if _, err := io.Copy(file, src); err != nil {
+		spooled.Close()
+		return nil, fmt.Errorf("unable to buffer historic bundle: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		spooled.Close()
+		return nil, fmt.Errorf("unable to buffer historic bundle: %w", err)
+	}
+
+	return spooled, nil
+}
+
+// Walks the tar headers and records each entry's section of the file, entries are opened as they are read
+func newIndexedBundle(file bundleFile, opts []BundleOption) (*Reader, error) {
+	r := &Reader{maxOpen: defaultMaxOpenEntries}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	tr := tar.NewReader(file)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read historic bundle: %w", err)
+		}
+
+		if !isMarketFile(hdr) {
+			continue
+		}
+
+		// tar leaves the file at the start of the entry's data
+		offset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s from historic bundle: %w", hdr.Name, err)
+		}
+
+		size := hdr.Size
+		r.sources = append(r.sources, &source{name: hdr.Name, index: len(r.sources), reopen: func() (io.Reader, error) {
+			return io.NewSectionReader(file, offset, size), nil
+		}})
+	}
+
+	return r, nil
+}
+
+func isMarketFile(hdr *tar.Header) bool {
+	return hdr.Typeflag == tar.TypeReg && !strings.HasPrefix(path.Base(hdr.Name), ".")
+}
+
+// Returns the next message across all files, io.EOF once every file is exhausted
+func (r *Reader) Next() (*stream.ResponseMessage, error) {
+	if !r.started {
+		r.started = true
+
+		// Prime each source so the heap can order them by their next message
+		live := r.sources[:0]
+		for _, s := range r.sources {
+			if err := r.advance(s); err != nil {
+				if errors.Is(err, io.EOF) {
+					continue
+				}
+				return nil, err
+			}
+			live = append(live, s)
+		}
+		r.sources = live
+		heap.Init(&r.sources)
+	}
+
+	if len(r.sources) == 0 {
+		return nil, io.EOF
+	}
+
+	s := r.sources[0]
+	msg := s.next
+
+	if err := r.advance(s); err != nil {
+		if !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		heap.Pop(&r.sources)
+	} else {
+		heap.Fix(&r.sources, 0)
+	}
+
+	return msg, nil
+}
+
+// Moves s on to its next message, opening it first if it was closed
+func (r *Reader) advance(s *source) error {
+	if len(s.queue) == 0 {
+		if err := r.fill(s); err != nil {
+			return err
+		}
+	}
+
+	s.next, s.queue = s.queue[0], s.queue[1:]
+	return nil
+}
+
+// Queues the next message of s, or the next reopenReadAhead after a reopen so closed entries aren't reopened on every message
+func (r *Reader) fill(s *source) error {
+	want := 1
+	if s.lines == nil {
+		reopened := s.line > 0
+		if err := r.openSource(s); err != nil {
+			return err
+		}
+		if reopened {
+			want = reopenReadAhead
+		}
+	}
+
+	r.tick++
+	s.used = r.tick
+
+	for len(s.queue) < want {
+		msg, err := s.read()
+		if errors.Is(err, io.EOF) {
+			r.closeSource(s)
+			if len(s.queue) > 0 {
+				return nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+		s.queue = append(s.queue, msg)
+	}
+
+	return nil
+}
+
+// Opens s at the line it had reached, closing the least recently used source when at the cap
+func (r *Reader) openSource(s *source) error {
+	if r.open >= r.maxOpen {
+		var lru *source
+		for _, other := range r.sources {
+			if other != s && other.lines != nil && (lru == nil || other.used < lru.used) {
+				lru = other
+			}
+		}
+		if lru != nil {
+			r.closeSource(lru)
+		}
+	}
+
+	src, err := s.reopen()
+	if err != nil {
+		return err
+	}
+	lines, err := decompress(src, s.name)
+	if err != nil {
+		return err
+	}
+
+	for skip := 0; skip < s.line; skip++ {
+		// The last line may have no newline
+		if line, err := lines.ReadBytes('\n'); err != nil && (!errors.Is(err, io.EOF) || len(line) == 0) {
+			return fmt.Errorf("unable to reopen %s at line %d: %w", s.name, s.line, err)
+		}
+	}
+
+	s.lines = lines
+	r.open++
+	return nil
+}
+
+func (r *Reader) closeSource(s *source) {
+	if s.lines != nil {
+		s.lines = nil
+		r.open--
+	}
+}
+
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// A single market file, only decompressed while open
+type source struct {
+	name   string
+	index  int
+	reopen func() (io.Reader, error) // the file from its start
+	lines  *bufio.Reader             // nil while closed
+	line   int                       // lines read so far
+	used   int64
+	next   *stream.ResponseMessage
+	queue  []*stream.ResponseMessage
+}
+
+func decompress(src io.Reader, name string) (*bufio.Reader, error) {
+	buffered := bufio.NewReaderSize(src, 64*1024)
+
+	magic, err := buffered.Peek(len(bz2Magic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to read %s: %w", name, err)
+	}
+
+	var lines io.Reader = buffered
+	if bytes.Equal(magic, bz2Magic) {
+		lines = bzip2.NewReader(buffered)
+	}
+
+	return bufio.NewReaderSize(lines, 64*1024), nil
+}
+
+// Decodes the next non empty line
+func (s *source) read() (*stream.ResponseMessage, error) {
+	for {
+		line, err := s.lines.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("unable to read %s: %w", s.name, err)
+		}
+		s.line++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg stream.ResponseMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return nil, fmt.Errorf("unable to parse %s line %d: %w", s.name, s.line, err)
+		}
+
+		return &msg, nil
+	}
+}
+
+// Min heap on publish time, ties keep bundle order so replays are deterministic
+type sourceHeap []*source
+
+func (h sourceHeap) Len() int { return len(h) }
+func (h sourceHeap) Less(i, j int) bool {
+	if h[i].next.Pt != h[j].next.Pt {
+		return h[i].next.Pt < h[j].next.Pt
+	}
+	return h[i].index < h[j].index
+}
+func (h sourceHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *sourceHeap) Push(x any)   { *h = append(*h, x.(*source)) }
+func (h *sourceHeap) Pop() any {
+	old := *h
+	s := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return s
+}
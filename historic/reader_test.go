@@ -0,0 +1,169 @@
+// historic/reader_test.go
+
+package historic_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/historic"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bundle(t *testing.T, files map[string]string) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		body := files[name]
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	return &buf
+}
+
+func TestReplayer_MergesBundleByPublishTime(t *testing.T) {
+	buf := bundle(t, map[string]string{
+		"PRO/1.1": `{"op":"mcm","clk":"1","pt":100,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN","runners":[{"id":11,"sortPriority":1,"status":"ACTIVE"}]},"rc":[{"id":11,"atb":[[2.0,10]]}]}]}
+{"op":"mcm","clk":"3","pt":300,"mc":[{"id":"1.1","rc":[{"id":11,"atb":[[2.0,0],[1.9,5]],"ltp":2.0}]}]}
+`,
+		"PRO/1.2": `{"op":"mcm","clk":"2","pt":200,"mc":[{"id":"1.2","img":true,"marketDefinition":{"status":"SUSPENDED"}}]}
+`,
+	})
+
+	reader, err := historic.NewBundleReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	replay := historic.NewReplayer(reader)
+
+	var pts []int64
+	var last historic.Snapshot
+	require.NoError(t, replay.Run(func(s historic.Snapshot) bool {
+		pts = append(pts, s.Pt)
+		last = s
+		return true
+	}))
+
+	assert.Equal(t, []int64{100, 200, 300}, pts)
+	require.Len(t, last.Books, 1)
+	assert.Equal(t, "1.1", last.Books[0].MarketId)
+	assert.Equal(t, float32(1.9), last.Books[0].Runners[0].Ex.Back[0].Price)
+	assert.Equal(t, float32(2.0), last.Books[0].Runners[0].LastPriceTraded)
+
+	book, ok := replay.Cache().MarketBook("1.2")
+	require.True(t, ok)
+	assert.Equal(t, types.SUSPENDED, book.Status)
+}
+
+func publishTimes(t *testing.T, reader *historic.Reader) []int64 {
+	t.Helper()
+
+	var pts []int64
+	for {
+		msg, err := reader.Next()
+		if err == io.EOF {
+			return pts
+		}
+		require.NoError(t, err)
+		pts = append(pts, msg.Pt)
+	}
+}
+
+func TestReader_MergesBz2EntriesInBundle(t *testing.T) {
+	compressed, err := os.ReadFile("testdata/1.3.bz2")
+	require.NoError(t, err)
+
+	buf := bundle(t, map[string]string{
+		"PRO/1.1":       `{"op":"mcm","clk":"1","pt":100,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN"}}]}` + "\n" + `{"op":"mcm","clk":"2","pt":200,"mc":[{"id":"1.1"}]}`,
+		"PRO/1.3.bz2":   string(compressed),
+		"PRO/.DS_Store": "ignored",
+	})
+
+	reader, err := historic.NewBundleReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	assert.Equal(t, []int64{100, 150, 200, 250}, publishTimes(t, reader))
+}
+
+func TestReader_MergesBundleThatCannotSeek(t *testing.T) {
+	compressed, err := os.ReadFile("testdata/1.3.bz2")
+	require.NoError(t, err)
+
+	buf := bundle(t, map[string]string{
+		"PRO/1.1":     `{"op":"mcm","clk":"1","pt":100,"mc":[{"id":"1.1"}]}` + "\n\n" + `{"op":"mcm","clk":"2","pt":200,"mc":[{"id":"1.1"}]}`,
+		"PRO/1.2":     "",
+		"PRO/1.3.bz2": string(compressed),
+	})
+
+	// Hide bytes.Buffer's methods so only io.Reader is left
+	reader, err := historic.NewBundleReader(struct{ io.Reader }{buf})
+	require.NoError(t, err)
+	defer reader.Close()
+
+	assert.Equal(t, []int64{100, 150, 200, 250}, publishTimes(t, reader))
+}
+
+func TestReader_ReopensEntriesOverTheOpenCap(t *testing.T) {
+	compressed, err := os.ReadFile("testdata/1.3.bz2")
+	require.NoError(t, err)
+
+	// Three interleaved markets, long enough to need several reopens each
+	files := map[string]string{"PRO/1.3.bz2": string(compressed)}
+	want := []int64{150, 250}
+	for market := int64(0); market < 3; market++ {
+		var lines []string
+		for i := int64(0); i < 200; i++ {
+			pt := i*3 + market + 1
+			lines = append(lines, fmt.Sprintf(`{"op":"mcm","pt":%d,"mc":[{"id":"1.%d"}]}`, pt, 10+market))
+			want = append(want, pt)
+		}
+		files[fmt.Sprintf("PRO/1.%d", 10+market)] = strings.Join(lines, "\n")
+	}
+	sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+
+	for _, limit := range []int{1, 2, 100} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			reader, err := historic.NewBundleReader(bytes.NewReader(bundle(t, files).Bytes()), historic.WithMaxOpenEntries(limit))
+			require.NoError(t, err)
+
+			assert.Equal(t, want, publishTimes(t, reader))
+		})
+	}
+}
+
+func TestOpen_ReadsBz2File(t *testing.T) {
+	reader, err := historic.Open("testdata/1.3.bz2")
+	require.NoError(t, err)
+	defer reader.Close()
+
+	replay := historic.NewReplayer(reader)
+
+	var last historic.Snapshot
+	require.NoError(t, replay.Run(func(s historic.Snapshot) bool {
+		last = s
+		return true
+	}))
+
+	require.Len(t, last.Books, 1)
+	assert.Equal(t, "1.3", last.Books[0].MarketId)
+	assert.Equal(t, float32(3.5), last.Books[0].Runners[0].LastPriceTraded)
+}
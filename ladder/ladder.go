@@ -0,0 +1,327 @@
+// ladder/ladder.go
+
+package ladder
+
+// Valid Betfair prices.
+// A ladder is a list of bands, each a price range with a fixed increment.
+// Prices are handled as integer units internally so tick maths is exact.
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+var (
+	ErrInvalidPrice = errors.New("price is not on the ladder")
+	ErrOutOfRange   = errors.New("price is outside the ladder")
+)
+
+type Rounding int
+
+const (
+	SNAP_NEAREST Rounding = iota // ties snap up
+	SNAP_UP
+	SNAP_DOWN
+)
+
+// Prices are scaled by this before any maths, line intervals finer than this are not supported
+const unitScale = 10000
+
+// Prices from lo up to hi (inclusive) every step, in units
+type band struct {
+	lo, hi, step int64
+}
+
+type Ladder struct {
+	typ   types.PriceLadderType
+	bands []band
+	first []int // index of each band's lo tick
+	ticks int
+}
+
+var (
+	classic = mustNew(types.CLASSIC, [][3]float64{
+		{1.01, 2, 0.01},
+		{2, 3, 0.02},
+		{3, 4, 0.05},
+		{4, 6, 0.1},
+		{6, 10, 0.2},
+		{10, 20, 0.5},
+		{20, 30, 1},
+		{30, 50, 2},
+		{50, 100, 5},
+		{100, 1000, 10},
+	})
+	finest = mustNew(types.FINEST, [][3]float64{
+		{1.01, 1000, 0.01},
+	})
+)
+
+// The standard odds ladder, 1.01 to 1000
+func Classic() *Ladder {
+	return classic
+}
+
+// 1.01 to 1000 in 0.01 increments
+func Finest() *Ladder {
+	return finest
+}
+
+// Line markets, where the price is the line value
+func LineRange(min, max, interval float64) (*Ladder, error) {
+	if interval <= 0 || max < min {
+		return nil, fmt.Errorf("invalid line range %g to %g every %g", min, max, interval)
+	}
+
+	return newLadder(types.LINE_RANGE, [][3]float64{{min, max, interval}})
+}
+
+// Ladder for a market catalogue entry, CLASSIC unless the description says otherwise
+// Requires the MARKET_DESCRIPTION projection for anything but CLASSIC markets
+func FromDescription(desc *types.MarketDescription) (*Ladder, error) {
+	if desc == nil || desc.PriceLadderDescription == nil {
+		return Classic(), nil
+	}
+
+	switch desc.PriceLadderDescription.Type {
+	case types.FINEST:
+		return Finest(), nil
+	case types.LINE_RANGE:
+		if desc.LineRangeInfo == nil {
+			return nil, fmt.Errorf("line range market without line range info")
+		}
+		info := desc.LineRangeInfo
+		return LineRange(info.MinUnitValue, info.MaxUnitValue, info.Interval)
+	default:
+		return Classic(), nil
+	}
+}
+
+// Ladder for a stream market definition
+func FromDefinition(def *stream.MarketDefinition) (*Ladder, error) {
+	if def == nil || def.PriceLadderDefinition == nil {
+		return Classic(), nil
+	}
+
+	switch types.PriceLadderType(def.PriceLadderDefinition.Type) {
+	case types.FINEST:
+		return Finest(), nil
+	case types.LINE_RANGE:
+		return LineRange(def.LineMinUnit, def.LineMaxUnit, def.LineInterval)
+	default:
+		return Classic(), nil
+	}
+}
+
+func newLadder(typ types.PriceLadderType, spec [][3]float64) (*Ladder, error) {
+	l := &Ladder{typ: typ}
+
+	for _, s := range spec {
+		b := band{lo: toUnits(s[0]), hi: toUnits(s[1]), step: toUnits(s[2])}
+		if b.step <= 0 || (b.hi-b.lo)%b.step != 0 {
+			return nil, fmt.Errorf("band %g to %g is not a whole number of %g increments", s[0], s[1], s[2])
+		}
+
+		// Bands share their boundary price, it is only counted once
+		if len(l.bands) > 0 {
+			l.ticks--
+		}
+
+		l.first = append(l.first, l.ticks)
+		l.bands = append(l.bands, b)
+		l.ticks += int((b.hi-b.lo)/b.step) + 1
+	}
+
+	return l, nil
+}
+
+func mustNew(typ types.PriceLadderType, spec [][3]float64) *Ladder {
+	l, err := newLadder(typ, spec)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+func toUnits(price float64) int64 {
+	return int64(math.Round(price * unitScale))
+}
+
+func fromUnits(units int64) float64 {
+	return float64(units) / unitScale
+}
+
+func (l *Ladder) Type() types.PriceLadderType {
+	return l.typ
+}
+
+func (l *Ladder) Min() float64 {
+	return fromUnits(l.bands[0].lo)
+}
+
+func (l *Ladder) Max() float64 {
+	return fromUnits(l.bands[len(l.bands)-1].hi)
+}
+
+// Number of valid prices
+func (l *Ladder) Len() int {
+	return l.ticks
+}
+
+// Price of the i'th tick, 0 being Min
+func (l *Ladder) Price(i int) (float64, error) {
+	if i < 0 || i >= l.ticks {
+		return 0, fmt.Errorf("tick %d: %w", i, ErrOutOfRange)
+	}
+
+	for b := len(l.bands) - 1; b >= 0; b-- {
+		if i >= l.first[b] {
+			band := l.bands[b]
+			return fromUnits(band.lo + int64(i-l.first[b])*band.step), nil
+		}
+	}
+
+	return 0, fmt.Errorf("tick %d: %w", i, ErrOutOfRange)
+}
+
+// Index of the highest tick at or below units, and whether units is exactly on it
+func (l *Ladder) floor(units int64) (int, bool) {
+	for b, band := range l.bands {
+		if units > band.hi && b < len(l.bands)-1 {
+			continue
+		}
+
+		offset := units - band.lo
+		return l.first[b] + int(offset/band.step), offset%band.step == 0
+	}
+
+	return l.ticks - 1, false
+}
+
+// Tick index of a valid price
+func (l *Ladder) Index(price float64) (int, error) {
+	if err := l.Validate(price); err != nil {
+		return 0, err
+	}
+
+	i, _ := l.floor(toUnits(price))
+	return i, nil
+}
+
+func (l *Ladder) IsValid(price float64) bool {
+	return l.Validate(price) == nil
+}
+
+// Returns nil for prices on the ladder, otherwise an error naming the nearest valid prices
+func (l *Ladder) Validate(price float64) error {
+	units := toUnits(price)
+	if units < l.bands[0].lo || units > l.bands[len(l.bands)-1].hi {
+		return fmt.Errorf("%g (%s %g to %g): %w", price, l.typ, l.Min(), l.Max(), ErrOutOfRange)
+	}
+
+	i, exact := l.floor(units)
+	if exact {
+		return nil
+	}
+
+	below, _ := l.Price(i)
+	above, _ := l.Price(i + 1)
+	return fmt.Errorf("%g (nearest %g or %g): %w", price, below, above, ErrInvalidPrice)
+}
+
+// Moves a price onto the ladder
+// Prices outside the ladder snap to Min or Max when rounding towards it, otherwise ErrOutOfRange
+func (l *Ladder) Snap(price float64, rounding Rounding) (float64, error) {
+	units := toUnits(price)
+
+	if units < l.bands[0].lo {
+		if rounding == SNAP_DOWN {
+			return 0, fmt.Errorf("%g: %w", price, ErrOutOfRange)
+		}
+		return l.Min(), nil
+	}
+
+	if units > l.bands[len(l.bands)-1].hi {
+		if rounding == SNAP_UP {
+			return 0, fmt.Errorf("%g: %w", price, ErrOutOfRange)
+		}
+		return l.Max(), nil
+	}
+
+	i, exact := l.floor(units)
+	if exact {
+		return l.Price(i)
+	}
+
+	switch rounding {
+	case SNAP_DOWN:
+		return l.Price(i)
+	case SNAP_UP:
+		return l.Price(i + 1)
+	}
+
+	below, _ := l.Price(i)
+	above, _ := l.Price(i + 1)
+	if units-toUnits(below) < toUnits(above)-units {
+		return below, nil
+	}
+
+	return above, nil
+}
+
+// Number of ticks from one valid price to another, negative when to is below from
+func (l *Ladder) Ticks(from, to float64) (int, error) {
+	i, err := l.Index(from)
+	if err != nil {
+		return 0, err
+	}
+
+	j, err := l.Index(to)
+	if err != nil {
+		return 0, err
+	}
+
+	return j - i, nil
+}
+
+// Price n ticks away from a valid price, n may be negative
+func (l *Ladder) Move(price float64, n int) (float64, error) {
+	i, err := l.Index(price)
+	if err != nil {
+		return 0, err
+	}
+
+	return l.Price(i + n)
+}
+
+// Every valid price between from and to inclusive, ascending
+// Bounds need not be on the ladder, prices outside it are ignored
+func (l *Ladder) Range(from, to float64) []float64 {
+	if from > to {
+		from, to = to, from
+	}
+
+	lo, err := l.Snap(from, SNAP_UP)
+	if err != nil {
+		return nil
+	}
+	hi, err := l.Snap(to, SNAP_DOWN)
+	if err != nil || hi < lo {
+		return nil
+	}
+
+	i, _ := l.floor(toUnits(lo))
+	j, _ := l.floor(toUnits(hi))
+
+	prices := make([]float64, 0, j-i+1)
+	for k := i; k <= j; k++ {
+		p, _ := l.Price(k)
+		prices = append(prices, p)
+	}
+
+	return prices
+}
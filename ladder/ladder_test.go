@@ -0,0 +1,85 @@
+// ladder/ladder_test.go
+
+package ladder_test
+
+import (
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/ladder"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassic_TickTable(t *testing.T) {
+	l := ladder.Classic()
+	assert.Equal(t, 350, l.Len())
+	assert.Equal(t, 1.01, l.Min())
+	assert.Equal(t, 1000.0, l.Max())
+
+	assert.True(t, l.IsValid(2.02))
+	assert.True(t, l.IsValid(4.1))
+	assert.False(t, l.IsValid(2.01))
+	assert.ErrorIs(t, l.Validate(3.33), ladder.ErrInvalidPrice)
+	assert.ErrorIs(t, l.Validate(1001), ladder.ErrOutOfRange)
+
+	n, err := l.Ticks(1.99, 2.04)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	p, err := l.Move(10, -1)
+	require.NoError(t, err)
+	assert.Equal(t, 9.8, p)
+
+	p, err = l.Move(1000, 1)
+	assert.ErrorIs(t, err, ladder.ErrOutOfRange)
+	assert.Zero(t, p)
+
+	assert.Equal(t, []float64{2.98, 3, 3.05, 3.1}, l.Range(2.97, 3.1))
+}
+
+func TestClassic_Snap(t *testing.T) {
+	l := ladder.Classic()
+
+	for _, tc := range []struct {
+		price    float64
+		rounding ladder.Rounding
+		want     float64
+	}{
+		{3.33, ladder.SNAP_DOWN, 3.3},
+		{3.33, ladder.SNAP_UP, 3.35},
+		{3.33, ladder.SNAP_NEAREST, 3.35},
+		{3.32, ladder.SNAP_NEAREST, 3.3},
+		{2.01, ladder.SNAP_NEAREST, 2.02}, // tie
+		{1.0, ladder.SNAP_UP, 1.01},
+		{1500, ladder.SNAP_NEAREST, 1000},
+	} {
+		got, err := l.Snap(tc.price, tc.rounding)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got, "snap %g", tc.price)
+	}
+}
+
+func TestFromDescription_LineRange(t *testing.T) {
+	l, err := ladder.FromDescription(&types.MarketDescription{
+		PriceLadderDescription: &types.PriceLadderDescription{Type: types.LINE_RANGE},
+		LineRangeInfo:          &types.MarketLineRangeInfo{MinUnitValue: -10.5, MaxUnitValue: 10.5, Interval: 1},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, types.LINE_RANGE, l.Type())
+	assert.Equal(t, 22, l.Len())
+	assert.True(t, l.IsValid(-0.5))
+	assert.False(t, l.IsValid(0))
+
+	p, err := l.Snap(0.2, ladder.SNAP_NEAREST)
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, p)
+
+	finest, err := ladder.FromDescription(&types.MarketDescription{
+		PriceLadderDescription: &types.PriceLadderDescription{Type: types.FINEST},
+	})
+	require.NoError(t, err)
+	assert.True(t, finest.IsValid(3.33))
+}
@@ -0,0 +1,630 @@
+// oms/oms.go
+
+package oms
+
+// Tracks our orders through their lifecycle.
+// Reports from PlaceOrders/CancelOrders/ReplaceOrders are merged with ListCurrentOrders polls
+// and/or order stream events, whichever arrives first wins and stale updates are ignored.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+const (
+	defaultPollInterval   = time.Second
+	defaultPendingTimeout = time.Minute
+	pollPageSize          = 1000
+)
+
+// Order operations the OMS needs, implemented by client.BetfairClient
+type OrderAPI interface {
+	PlaceOrders(req types.PlaceOrdersRequest) (types.PlaceExecutionReport, error)
+	CancelOrders(req types.CancelOrdersRequest) (types.CancelExecutionReport, error)
+	ReplaceOrders(req types.ReplaceOrdersRequest) (types.ReplaceExecutionReport, error)
+	ListCurrentOrders(req types.ListCurrentOrdersRequest) (types.CurrentOrderSummaryReport, error)
+}
+
+// Sent whenever an order changes state or size
+type Change struct {
+	Order    Order
+	Previous State
+}
+
+type Config struct {
+	OnChange     func(Change) // called without locks held, may call back into the OMS
+	OnError      func(error)  // errors from Run
+	PollInterval time.Duration
+
+	// How long an order without a bet id may stay PENDING, default 1 minute
+	// A poll that still can't find it by its CustomerOrderRef after that times it out
+	PendingTimeout time.Duration
+}
+
+type OMS struct {
+	api OrderAPI
+	cfg Config
+
+	mu     sync.RWMutex
+	orders map[string]*Order // by local id
+	byBet  map[string]*Order
+
+	prefix string
+	seq    atomic.Int64
+}
+
+func New(api OrderAPI, cfg Config) *OMS {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.PendingTimeout <= 0 {
+		cfg.PendingTimeout = defaultPendingTimeout
+	}
+
+	return &OMS{
+		api:    api,
+		cfg:    cfg,
+		orders: make(map[string]*Order),
+		byBet:  make(map[string]*Order),
+		prefix: fmt.Sprintf("%x", time.Now().UnixNano()&0xffffffff),
+	}
+}
+
+// customerOrderRef is limited to 32 characters
+func (m *OMS) nextId() string {
+	return fmt.Sprintf("%s-%d", m.prefix, m.seq.Add(1))
+}
+
+// Places orders on one market and tracks them
+// Each instruction's CustomerOrderRef becomes the order's local id, one is generated when empty
+// Orders stay PENDING when the outcome is unknown (async placement, timeouts) until a poll or stream event settles them
+func (m *OMS) Place(marketId, strategyRef string, instructions ...types.PlaceInstruction) ([]Order, error) {
+	now := time.Now()
+	tracked := make([]*Order, len(instructions))
+
+	m.mu.Lock()
+	refs := make(map[string]struct{}, len(instructions))
+	for i := range instructions {
+		ins := &instructions[i]
+		if ins.CustomerOrderRef == "" {
+			ins.CustomerOrderRef = m.nextId()
+		}
+
+		_, exists := m.orders[ins.CustomerOrderRef]
+		if _, repeated := refs[ins.CustomerOrderRef]; exists || repeated {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("order %s already tracked", ins.CustomerOrderRef)
+		}
+		refs[ins.CustomerOrderRef] = struct{}{}
+	}
+
+	// Only track the orders once every ref is known to be free
+	for i, ins := range instructions {
+		o := newOrder(marketId, strategyRef, ins, now)
+		m.orders[o.Id] = o
+		tracked[i] = o
+	}
+	m.mu.Unlock()
+
+	report, err := m.api.PlaceOrders(types.PlaceOrdersRequest{
+		MarketId:            marketId,
+		Instructions:        instructions,
+		CustomerStrategyRef: strategyRef,
+	})
+
+	var changes []Change
+	m.mu.Lock()
+	switch {
+	case err != nil:
+		// Rejected before reaching Betfair (validation, risk limits), nothing was placed
+		// Any other error leaves the orders PENDING, they may have been placed and a poll or stream event settles them
+		var preflight types.PreflightError
+		if errors.As(err, &preflight) {
+			codes := preflight.PreflightCodes()
+			for i, o := range tracked {
+				changes = m.reject(changes, o, codes[i], now)
+			}
+		}
+
+	default:
+		for i, o := range tracked {
+			if i >= len(report.InstructionReports) {
+				if report.Status == types.EXECUTION_FAILURE {
+					changes = m.reject(changes, o, "", now)
+				}
+				continue
+			}
+			changes = m.applyPlaceReport(changes, o, report.InstructionReports[i], now)
+		}
+	}
+
+	result := make([]Order, len(tracked))
+	for i, o := range tracked {
+		result[i] = *o
+	}
+	m.mu.Unlock()
+
+	m.notify(changes)
+
+	if err != nil {
+		return result, err
+	}
+	if report.Status != types.EXECUTION_SUCCESS {
+		return result, fmt.Errorf("place orders on %s: %s %s", marketId, report.Status, report.ErrorCode)
+	}
+
+	return result, nil
+}
+
+func newOrder(marketId, strategyRef string, ins types.PlaceInstruction, now time.Time) *Order {
+	o := &Order{
+		Id:          ins.CustomerOrderRef,
+		MarketId:    marketId,
+		SelectionId: ins.SelectionId,
+		Handicap:    ins.Handicap,
+		Side:        ins.Side,
+		StrategyRef: strategyRef,
+		State:       STATE_PENDING,
+		PlacedAt:    now,
+		UpdatedAt:   now,
+	}
+
+	switch {
+	case ins.LimitOrder != nil:
+		o.Price = ins.LimitOrder.Price
+		o.Size = ins.LimitOrder.Size
+	case ins.LimitOnCloseOrder != nil:
+		o.Price = ins.LimitOnCloseOrder.Price
+		o.Size = ins.LimitOnCloseOrder.Liability
+	case ins.MarketOnCloseOrder != nil:
+		o.Size = ins.MarketOnCloseOrder.Liability
+	}
+	o.SizeRemaining = o.Size
+
+	return o
+}
+
+func (m *OMS) applyPlaceReport(changes []Change, o *Order, r types.PlaceInstructionReport, now time.Time) []Change {
+	switch r.Status {
+	case types.INSTRUCTION_FAILURE:
+		return m.reject(changes, o, r.ErrorCode, now)
+	case types.INSTRUCTION_TIMEOUT:
+		return changes
+	}
+
+	// Async placement, the bet id arrives later
+	if r.BetId == "" || r.OrderStatus == types.ORDER_PENDING {
+		return changes
+	}
+
+	return m.applyLocked(changes, o, update{
+		betId:         r.BetId,
+		complete:      r.OrderStatus == types.ORDER_EXECUTION_COMPLETE,
+		sizeMatched:   r.SizeMatched,
+		avgPrice:      r.AveragePriceMatched,
+		sizeRemaining: max(o.Size-r.SizeMatched, 0),
+	}, now)
+}
+
+func (m *OMS) reject(changes []Change, o *Order, code types.InstructionReportErrorCode, now time.Time) []Change {
+	if !canTransition(o.State, STATE_REJECTED) {
+		return changes
+	}
+
+	previous := o.State
+	o.State = STATE_REJECTED
+	o.ErrorCode = code
+	o.SizeRemaining = 0
+	o.UpdatedAt = now
+
+	return append(changes, Change{Order: *o, Previous: previous})
+}
+
+func (m *OMS) timeOut(changes []Change, o *Order, now time.Time) []Change {
+	previous := o.State
+	o.State = STATE_TIMED_OUT
+	o.SizeRemaining = 0
+	o.UpdatedAt = now
+
+	return append(changes, Change{Order: *o, Previous: previous})
+}
+
+// Cancels the unmatched part of orders by local id
+func (m *OMS) Cancel(ids ...string) error {
+	byMarket := make(map[string][]types.CancelInstruction)
+
+	m.mu.RLock()
+	for _, id := range ids {
+		o, ok := m.orders[id]
+		if !ok {
+			m.mu.RUnlock()
+			return fmt.Errorf("unknown order %s", id)
+		}
+		if o.BetId == "" || !o.open() {
+			continue
+		}
+		byMarket[o.MarketId] = append(byMarket[o.MarketId], types.CancelInstruction{BetId: o.BetId})
+	}
+	m.mu.RUnlock()
+
+	var errs []error
+	for marketId, instructions := range byMarket {
+		report, err := m.api.CancelOrders(types.CancelOrdersRequest{MarketId: marketId, Instructions: instructions})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cancel orders on %s: %w", marketId, err))
+			continue
+		}
+
+		now := time.Now()
+		var changes []Change
+		m.mu.Lock()
+		for _, r := range report.InstructionReports {
+			if r.Status != types.INSTRUCTION_SUCCESS || r.Instruction == nil {
+				continue
+			}
+			if o, ok := m.byBet[r.Instruction.BetId]; ok {
+				changes = m.applyCancelReport(changes, o, r, now)
+			}
+		}
+		m.mu.Unlock()
+		m.notify(changes)
+
+		if report.Status != types.EXECUTION_SUCCESS {
+			errs = append(errs, fmt.Errorf("cancel orders on %s: %s %s", marketId, report.Status, report.ErrorCode))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *OMS) applyCancelReport(changes []Change, o *Order, r types.CancelInstructionReport, now time.Time) []Change {
+	remaining := max(o.SizeRemaining-r.SizeCancelled, 0)
+
+	return m.applyLocked(changes, o, update{
+		complete:      remaining == 0,
+		sizeMatched:   o.SizeMatched,
+		avgPrice:      o.AveragePriceMatched,
+		sizeRemaining: remaining,
+		sizeCancelled: o.SizeCancelled + r.SizeCancelled,
+		sizeLapsed:    o.SizeLapsed,
+		sizeVoided:    o.SizeVoided,
+	}, now)
+}
+
+// Moves the unmatched part of an order to a new price
+// Betfair cancels the old bet and places a new one, which is tracked as a new order
+func (m *OMS) Replace(id string, newPrice float64) (Order, error) {
+	m.mu.RLock()
+	o, ok := m.orders[id]
+	var betId, marketId string
+	if ok {
+		betId, marketId = o.BetId, o.MarketId
+	}
+	m.mu.RUnlock()
+
+	if !ok {
+		return Order{}, fmt.Errorf("unknown order %s", id)
+	}
+	if betId == "" {
+		return Order{}, fmt.Errorf("order %s has no bet id yet", id)
+	}
+
+	report, err := m.api.ReplaceOrders(types.ReplaceOrdersRequest{
+		MarketId:     marketId,
+		Instructions: []types.ReplaceInstruction{{BetId: betId, NewPrice: newPrice}},
+	})
+	if err != nil {
+		return Order{}, fmt.Errorf("replace order %s: %w", id, err)
+	}
+	if len(report.InstructionReports) == 0 || report.InstructionReports[0].Status != types.INSTRUCTION_SUCCESS {
+		return Order{}, fmt.Errorf("replace order %s: %s %s", id, report.Status, report.ErrorCode)
+	}
+
+	r := report.InstructionReports[0]
+	now := time.Now()
+	var changes []Change
+
+	m.mu.Lock()
+	size := o.SizeRemaining
+	if r.CancelInstructionReport != nil {
+		size = r.CancelInstructionReport.SizeCancelled
+		changes = m.applyCancelReport(changes, o, *r.CancelInstructionReport, now)
+	}
+
+	var replacement Order
+	if pr := r.PlaceInstructionReport; pr != nil {
+		// The stream or a poll may have seen the new bet first and already be tracking it
+		n, seen := m.byBet[pr.BetId]
+		if pr.BetId == "" || !seen {
+			n = &Order{
+				Id:            m.nextId(),
+				MarketId:      o.MarketId,
+				SelectionId:   o.SelectionId,
+				Handicap:      o.Handicap,
+				Side:          o.Side,
+				Price:         newPrice,
+				Size:          size,
+				SizeRemaining: size,
+				StrategyRef:   o.StrategyRef,
+				State:         STATE_PENDING,
+				PlacedAt:      now,
+				UpdatedAt:     now,
+			}
+			m.orders[n.Id] = n
+		}
+		if n.StrategyRef == "" {
+			n.StrategyRef = o.StrategyRef
+		}
+		n.ReplacedFrom = o.Id
+		o.ReplacedBy = n.Id
+
+		changes = m.applyPlaceReport(changes, n, *pr, now)
+		replacement = *n
+	}
+	m.mu.Unlock()
+
+	m.notify(changes)
+
+	return replacement, nil
+}
+
+// Refreshes every open order from ListCurrentOrders
+func (m *OMS) Poll() error {
+	m.mu.RLock()
+	markets := make(map[string]struct{})
+	for _, o := range m.orders {
+		if o.open() {
+			markets[o.MarketId] = struct{}{}
+		}
+	}
+	m.mu.RUnlock()
+
+	if len(markets) == 0 {
+		return nil
+	}
+
+	marketIds := make([]string, 0, len(markets))
+	for id := range markets {
+		marketIds = append(marketIds, id)
+	}
+	sort.Strings(marketIds)
+
+	started := time.Now()
+	seen := make(map[string]bool)
+	seenRefs := make(map[string]bool)
+
+	for from := 0; ; from += pollPageSize {
+		report, err := m.api.ListCurrentOrders(types.ListCurrentOrdersRequest{
+			MarketIds:       marketIds,
+			OrderProjection: types.ALL,
+			FromRecord:      from,
+			RecordCount:     pollPageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to poll current orders: %w", err)
+		}
+
+		for _, summary := range report.CurrentOrders {
+			seen[summary.BetId] = true
+			if summary.CustomerOrderRef != "" {
+				seenRefs[summary.CustomerOrderRef] = true
+			}
+			m.apply(fromSummary(summary))
+		}
+
+		if !report.MoreAvailable {
+			break
+		}
+	}
+
+	m.completeMissing(markets, seen, seenRefs, started)
+	return nil
+}
+
+// Completes open orders that dropped out of a poll of their market
+// listCurrentOrders keeps completed orders until the market settles, so a missing bet has settled
+// and whatever was still unmatched lapsed. Orders changed since the poll started are left alone.
+// Orders that never got a bet id are looked up by their CustomerOrderRef and time out once
+// PendingTimeout has passed without a trace of them
+func (m *OMS) completeMissing(markets map[string]struct{}, seen, seenRefs map[string]bool, started time.Time) {
+	now := time.Now()
+	var changes []Change
+
+	m.mu.Lock()
+	for _, o := range m.orders {
+		if !o.open() || !o.UpdatedAt.Before(started) {
+			continue
+		}
+		if _, polled := markets[o.MarketId]; !polled {
+			continue
+		}
+
+		if o.BetId == "" {
+			if !seenRefs[o.Id] && started.Sub(o.PlacedAt) >= m.cfg.PendingTimeout {
+				changes = m.timeOut(changes, o, now)
+			}
+			continue
+		}
+		if seen[o.BetId] {
+			continue
+		}
+
+		changes = m.applyLocked(changes, o, update{
+			complete:      true,
+			sizeMatched:   o.SizeMatched,
+			avgPrice:      o.AveragePriceMatched,
+			sizeCancelled: o.SizeCancelled,
+			sizeLapsed:    o.SizeLapsed + o.SizeRemaining,
+			sizeVoided:    o.SizeVoided,
+		}, now)
+	}
+	m.mu.Unlock()
+
+	m.notify(changes)
+}
+
+// Polls every PollInterval until ctx is done
+func (m *OMS) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.Poll(); err != nil && m.cfg.OnError != nil {
+			m.cfg.OnError(err)
+		}
+	}
+}
+
+// Merges an order stream event, pass as stream.ClientConfig.OnOrderEvent
+func (m *OMS) HandleOrderEvent(e stream.OrderEvent) {
+	m.apply(fromStream(e))
+}
+
+func (m *OMS) apply(u update) {
+	if u.betId == "" {
+		return
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	o, ok := m.byBet[u.betId]
+	if !ok && u.orderRef != "" {
+		o, ok = m.orders[u.orderRef]
+	}
+
+	// Placed outside this OMS, track it under its bet id
+	if !ok {
+		o = &Order{
+			Id:            u.betId,
+			MarketId:      u.marketId,
+			SelectionId:   u.selectionId,
+			Handicap:      u.handicap,
+			Side:          u.side,
+			Price:         u.price,
+			Size:          u.size,
+			SizeRemaining: u.size,
+			StrategyRef:   u.strategyRef,
+			State:         STATE_PENDING,
+			PlacedAt:      now,
+		}
+		m.orders[o.Id] = o
+	}
+
+	changes := m.applyLocked(nil, o, u, now)
+	m.mu.Unlock()
+
+	m.notify(changes)
+}
+
+func (m *OMS) applyLocked(changes []Change, o *Order, u update, now time.Time) []Change {
+	previous := o.State
+	if !o.apply(u, now) {
+		return changes
+	}
+
+	if o.BetId != "" {
+		m.byBet[o.BetId] = o
+	}
+
+	return append(changes, Change{Order: *o, Previous: previous})
+}
+
+func (m *OMS) notify(changes []Change) {
+	if m.cfg.OnChange == nil {
+		return
+	}
+	for _, c := range changes {
+		m.cfg.OnChange(c)
+	}
+}
+
+// Looks an order up by local id
+func (m *OMS) Order(id string) (Order, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	o, ok := m.orders[id]
+	if !ok {
+		return Order{}, false
+	}
+	return *o, true
+}
+
+func (m *OMS) OrderByBetId(betId string) (Order, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	o, ok := m.byBet[betId]
+	if !ok {
+		return Order{}, false
+	}
+	return *o, true
+}
+
+// Every tracked order matching fn, oldest first
+func (m *OMS) Orders(fn func(Order) bool) []Order {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []Order
+	for _, o := range m.orders {
+		if fn == nil || fn(*o) {
+			result = append(result, *o)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].PlacedAt.Equal(result[j].PlacedAt) {
+			return result[i].PlacedAt.Before(result[j].PlacedAt)
+		}
+		return result[i].Id < result[j].Id
+	})
+
+	return result
+}
+
+// Pending and executable orders on a market, every market if marketId is empty
+func (m *OMS) OpenOrders(marketId string) []Order {
+	return m.Orders(func(o Order) bool {
+		return o.open() && (marketId == "" || o.MarketId == marketId)
+	})
+}
+
+func (m *OMS) OpenOrdersByStrategy(strategyRef string) []Order {
+	return m.Orders(func(o Order) bool {
+		return o.open() && o.StrategyRef == strategyRef
+	})
+}
+
+// Total matched size and its average price on one side of a runner
+func (m *OMS) Matched(marketId string, selectionId int64, handicap float64, side types.Side) (float64, float64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var size, weighted float64
+	for _, o := range m.orders {
+		if o.MarketId == marketId && o.SelectionId == selectionId && o.Handicap == handicap && o.Side == side {
+			size += o.SizeMatched
+			weighted += o.SizeMatched * o.AveragePriceMatched
+		}
+	}
+
+	if size == 0 {
+		return 0, 0
+	}
+	return size, weighted / size
+}
@@ -0,0 +1,267 @@
+// oms/oms_test.go
+
+package oms_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/betfairtest"
+	"github.com/Bazcampbell/betfair-api-go-sdk/client"
+	"github.com/Bazcampbell/betfair-api-go-sdk/oms"
+	"github.com/Bazcampbell/betfair-api-go-sdk/risk"
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAPI struct {
+	current  []types.CurrentOrderSummary
+	placeErr error
+	places   int
+}
+
+func (f *fakeAPI) PlaceOrders(req types.PlaceOrdersRequest) (types.PlaceExecutionReport, error) {
+	f.places++
+	if f.placeErr != nil {
+		return types.PlaceExecutionReport{}, f.placeErr
+	}
+
+	report := types.PlaceExecutionReport{Status: types.EXECUTION_SUCCESS, MarketId: req.MarketId}
+	for i, ins := range req.Instructions {
+		if ins.LimitOrder.Price > 100 {
+			report.Status = types.EXECUTION_FAILURE
+			report.InstructionReports = append(report.InstructionReports, types.PlaceInstructionReport{
+				Status: types.INSTRUCTION_FAILURE, ErrorCode: types.INVALID_ODDS, Instruction: ins})
+			continue
+		}
+		report.InstructionReports = append(report.InstructionReports, types.PlaceInstructionReport{
+			Status: types.INSTRUCTION_SUCCESS, OrderStatus: types.ORDER_EXECUTABLE, Instruction: ins,
+			BetId: []string{"b1", "b2"}[i], SizeMatched: 2, AveragePriceMatched: ins.LimitOrder.Price,
+		})
+	}
+	return report, nil
+}
+
+func (f *fakeAPI) CancelOrders(req types.CancelOrdersRequest) (types.CancelExecutionReport, error) {
+	report := types.CancelExecutionReport{Status: types.EXECUTION_SUCCESS, MarketId: req.MarketId}
+	for _, ins := range req.Instructions {
+		ins := ins
+		report.InstructionReports = append(report.InstructionReports, types.CancelInstructionReport{
+			Status: types.INSTRUCTION_SUCCESS, Instruction: &ins, SizeCancelled: 8})
+	}
+	return report, nil
+}
+
+func (f *fakeAPI) ReplaceOrders(req types.ReplaceOrdersRequest) (types.ReplaceExecutionReport, error) {
+	return types.ReplaceExecutionReport{Status: types.EXECUTION_SUCCESS, InstructionReports: []types.ReplaceInstructionReport{{
+		Status:                  types.INSTRUCTION_SUCCESS,
+		CancelInstructionReport: &types.CancelInstructionReport{Status: types.INSTRUCTION_SUCCESS, SizeCancelled: 8},
+		PlaceInstructionReport: &types.PlaceInstructionReport{Status: types.INSTRUCTION_SUCCESS,
+			OrderStatus: types.ORDER_EXECUTABLE, BetId: "b3"},
+	}}}, nil
+}
+
+func (f *fakeAPI) ListCurrentOrders(req types.ListCurrentOrdersRequest) (types.CurrentOrderSummaryReport, error) {
+	return types.CurrentOrderSummaryReport{CurrentOrders: f.current}, nil
+}
+
+func back(price, size float64) types.PlaceInstruction {
+	return types.PlaceInstruction{OrderType: types.LIMIT, SelectionId: 11, Side: types.BACK,
+		LimitOrder: &types.LimitOrder{Price: price, Size: size, PersistenceType: types.LAPSE}}
+}
+
+func TestOMS_Lifecycle(t *testing.T) {
+	api := &fakeAPI{}
+	var changes []oms.Change
+	m := oms.New(api, oms.Config{OnChange: func(c oms.Change) { changes = append(changes, c) }})
+
+	placed, err := m.Place("1.1", "strat", back(3, 10), back(200, 10))
+	require.Error(t, err)
+	require.Len(t, placed, 2)
+	assert.Equal(t, oms.STATE_EXECUTABLE, placed[0].State)
+	assert.Equal(t, "b1", placed[0].BetId)
+	assert.Equal(t, oms.STATE_REJECTED, placed[1].State)
+	assert.Equal(t, types.INVALID_ODDS, placed[1].ErrorCode)
+
+	assert.Len(t, m.OpenOrdersByStrategy("strat"), 1)
+
+	// Stream reports more matched
+	m.HandleOrderEvent(stream.OrderEvent{MarketId: "1.1", SelectionId: 11, Order: stream.Order{
+		Id: "b1", Price: 3, Size: 10, Side: stream.ORDER_SIDE_BACK, Status: stream.ORDER_STATUS_EXECUTABLE,
+		SizeMatched: 4, SizeRemaining: 6, AveragePriceMatched: 3,
+	}})
+
+	// A lagging poll must not undo it
+	api.current = []types.CurrentOrderSummary{{BetId: "b1", MarketId: "1.1", SelectionId: 11, Side: types.BACK,
+		Status: types.ORDER_EXECUTABLE, SizeMatched: 2, SizeRemaining: 8, AveragePriceMatched: 3}}
+	require.NoError(t, m.Poll())
+
+	size, avg := m.Matched("1.1", 11, 0, types.BACK)
+	assert.Equal(t, 4.0, size)
+	assert.Equal(t, 3.0, avg)
+
+	replacement, err := m.Replace(placed[0].Id, 3.5)
+	require.NoError(t, err)
+	assert.Equal(t, "b3", replacement.BetId)
+	assert.Equal(t, placed[0].Id, replacement.ReplacedFrom)
+
+	old, ok := m.Order(placed[0].Id)
+	require.True(t, ok)
+	assert.Equal(t, oms.STATE_EXECUTION_COMPLETE, old.State)
+
+	require.NoError(t, m.Cancel(replacement.Id))
+	cancelled, _ := m.Order(replacement.Id)
+	assert.Equal(t, oms.STATE_CANCELLED, cancelled.State)
+	assert.Empty(t, m.OpenOrders("1.1"))
+
+	require.NotEmpty(t, changes)
+	assert.Equal(t, oms.STATE_EXECUTABLE, changes[len(changes)-1].Previous)
+}
+
+func TestOMS_RiskRejectionRejectsOrders(t *testing.T) {
+	srv, err := betfairtest.NewServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	bf, err := client.NewSession(srv.Credentials(), func(error) {},
+		client.WithBaseURL(srv.URL()), client.WithRiskEngine(risk.New(risk.Limits{MaxStake: 10})))
+	require.NoError(t, err)
+
+	m := oms.New(bf, oms.Config{})
+
+	placed, err := m.Place("1.1", "", back(3, 5), back(3, 20))
+	var rej *risk.Rejection
+	require.ErrorAs(t, err, &rej)
+	require.Len(t, placed, 2)
+	for _, o := range placed {
+		assert.Equal(t, oms.STATE_REJECTED, o.State)
+	}
+	assert.Equal(t, types.INVALID_BET_SIZE, placed[1].ErrorCode)
+
+	assert.Empty(t, m.OpenOrders(""))
+	require.NoError(t, m.Poll())
+	srv.AssertNotCalled(t, "placeOrders")
+	srv.AssertNotCalled(t, "listCurrentOrders")
+}
+
+func TestOMS_PollCompletesSettledOrders(t *testing.T) {
+	api := &fakeAPI{}
+	m := oms.New(api, oms.Config{})
+
+	placed, err := m.Place("1.1", "", back(3, 10))
+	require.NoError(t, err)
+	require.Equal(t, oms.STATE_EXECUTABLE, placed[0].State)
+
+	// Still listed, nothing changes
+	api.current = []types.CurrentOrderSummary{{BetId: "b1", MarketId: "1.1", SelectionId: 11, Side: types.BACK,
+		Status: types.ORDER_EXECUTABLE, SizeMatched: 2, SizeRemaining: 8, AveragePriceMatched: 3}}
+	require.NoError(t, m.Poll())
+	assert.Len(t, m.OpenOrders("1.1"), 1)
+
+	// The market settled and the bet dropped out of listCurrentOrders
+	api.current = nil
+	require.NoError(t, m.Poll())
+
+	o, ok := m.Order(placed[0].Id)
+	require.True(t, ok)
+	assert.Equal(t, oms.STATE_EXECUTION_COMPLETE, o.State)
+	assert.Equal(t, 2.0, o.SizeMatched)
+	assert.Equal(t, 8.0, o.SizeLapsed)
+	assert.Zero(t, o.SizeRemaining)
+	assert.Empty(t, m.OpenOrders(""))
+}
+
+func TestOMS_PlaceRejectsDuplicateRefsBeforeTracking(t *testing.T) {
+	api := &fakeAPI{}
+	m := oms.New(api, oms.Config{})
+
+	first, second := back(3, 10), back(4, 10)
+	first.CustomerOrderRef, second.CustomerOrderRef = "a", "a"
+	_, err := m.Place("1.1", "", first, second)
+	require.ErrorContains(t, err, "order a already tracked")
+	assert.Empty(t, m.Orders(nil))
+
+	placed, err := m.Place("1.1", "", first)
+	require.NoError(t, err)
+	assert.Equal(t, "a", placed[0].Id)
+
+	// The free ref in front of a taken one is not tracked either
+	free := back(3, 10)
+	free.CustomerOrderRef = "b"
+	_, err = m.Place("1.1", "", free, first)
+	require.ErrorContains(t, err, "order a already tracked")
+	_, ok := m.Order("b")
+	assert.False(t, ok)
+	assert.Equal(t, 1, api.places)
+}
+
+func TestOMS_PollResolvesLostPlacesByCustomerOrderRef(t *testing.T) {
+	api := &fakeAPI{placeErr: errors.New("connection reset")}
+	m := oms.New(api, oms.Config{PendingTimeout: time.Nanosecond})
+
+	placed, err := m.Place("1.1", "", back(3, 10), back(4, 10))
+	require.Error(t, err)
+	for _, o := range placed {
+		require.Equal(t, oms.STATE_PENDING, o.State)
+		require.Empty(t, o.BetId)
+	}
+
+	// The first reached Betfair and is listed under its ref, the second never arrived
+	api.current = []types.CurrentOrderSummary{{BetId: "b7", CustomerOrderRef: placed[0].Id, MarketId: "1.1", SelectionId: 11,
+		Side: types.BACK, Status: types.ORDER_EXECUTABLE, SizeRemaining: 10}}
+	time.Sleep(time.Millisecond)
+	require.NoError(t, m.Poll())
+
+	found, _ := m.Order(placed[0].Id)
+	assert.Equal(t, oms.STATE_EXECUTABLE, found.State)
+	assert.Equal(t, "b7", found.BetId)
+
+	lost, _ := m.Order(placed[1].Id)
+	assert.Equal(t, oms.STATE_TIMED_OUT, lost.State)
+	assert.Zero(t, lost.SizeRemaining)
+	assert.Len(t, m.OpenOrders("1.1"), 1)
+}
+
+func TestOMS_PendingOrdersWaitForTimeout(t *testing.T) {
+	api := &fakeAPI{placeErr: errors.New("connection reset")}
+	m := oms.New(api, oms.Config{})
+
+	placed, err := m.Place("1.1", "", back(3, 10))
+	require.Error(t, err)
+
+	require.NoError(t, m.Poll())
+	o, _ := m.Order(placed[0].Id)
+	assert.Equal(t, oms.STATE_PENDING, o.State)
+}
+
+func TestOMS_ReplaceReusesOrderSeenOnStream(t *testing.T) {
+	api := &fakeAPI{}
+	m := oms.New(api, oms.Config{})
+
+	placed, err := m.Place("1.1", "strat", back(3, 10))
+	require.NoError(t, err)
+
+	// The replacement bet arrives on the stream before ReplaceOrders returns
+	m.HandleOrderEvent(stream.OrderEvent{MarketId: "1.1", SelectionId: 11, Order: stream.Order{
+		Id: "b3", Price: 3.5, Size: 8, Side: stream.ORDER_SIDE_BACK, Status: stream.ORDER_STATUS_EXECUTABLE, SizeRemaining: 8,
+	}})
+
+	replacement, err := m.Replace(placed[0].Id, 3.5)
+	require.NoError(t, err)
+	assert.Equal(t, "b3", replacement.BetId)
+	assert.Equal(t, placed[0].Id, replacement.ReplacedFrom)
+	assert.Equal(t, "strat", replacement.StrategyRef)
+
+	old, _ := m.Order(placed[0].Id)
+	assert.Equal(t, replacement.Id, old.ReplacedBy)
+
+	byBet, ok := m.OrderByBetId("b3")
+	require.True(t, ok)
+	assert.Equal(t, replacement.Id, byBet.Id)
+	assert.Len(t, m.Orders(func(o oms.Order) bool { return o.BetId == "b3" }), 1)
+	assert.Len(t, m.OpenOrders("1.1"), 1)
+}
@@ -0,0 +1,200 @@
+// oms/order.go
+
+package oms
+
+// Order lifecycle
+// PENDING -> EXECUTABLE -> EXECUTION_COMPLETE / LAPSED / CANCELLED, REJECTED if Betfair refused it
+// and TIMED_OUT if it was never acknowledged
+
+import (
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+type State string
+
+const (
+	STATE_PENDING            State = "PENDING"            // sent, no report yet
+	STATE_EXECUTABLE         State = "EXECUTABLE"         // live on the exchange, possibly part matched
+	STATE_EXECUTION_COMPLETE State = "EXECUTION_COMPLETE" // nothing left unmatched, some or all of it matched
+	STATE_LAPSED             State = "LAPSED"             // lapsed without matching
+	STATE_CANCELLED          State = "CANCELLED"          // cancelled without matching
+	STATE_REJECTED           State = "REJECTED"           // Betfair refused the instruction
+	STATE_TIMED_OUT          State = "TIMED_OUT"          // never acknowledged and not found by a poll within PendingTimeout
+)
+
+// Terminal states never change again
+func (s State) Terminal() bool {
+	switch s {
+	case STATE_EXECUTION_COMPLETE, STATE_LAPSED, STATE_CANCELLED, STATE_REJECTED, STATE_TIMED_OUT:
+		return true
+	}
+	return false
+}
+
+func canTransition(from, to State) bool {
+	switch from {
+	case STATE_PENDING:
+		return to != STATE_PENDING
+	case STATE_EXECUTABLE:
+		return to != STATE_PENDING && to != STATE_REJECTED
+	}
+	return false
+}
+
+// Snapshot of a tracked order
+type Order struct {
+	Id          string // local id, sent as the customerOrderRef
+	BetId       string // empty until Betfair accepts the order
+	MarketId    string
+	SelectionId int64
+	Handicap    float64
+	Side        types.Side
+	Price       float64
+	Size        float64
+	StrategyRef string
+
+	State               State
+	SizeMatched         float64
+	AveragePriceMatched float64
+	SizeRemaining       float64
+	SizeCancelled       float64
+	SizeLapsed          float64
+	SizeVoided          float64
+
+	ReplacedBy   string // local id of the order that replaced this one
+	ReplacedFrom string // local id of the order this one replaced
+	ErrorCode    types.InstructionReportErrorCode
+
+	PlacedAt  time.Time
+	UpdatedAt time.Time
+}
+
+// Full state of an order from a report, poll or stream message
+type update struct {
+	betId       string
+	orderRef    string
+	strategyRef string
+	marketId    string
+	selectionId int64
+	handicap    float64
+	side        types.Side
+	price       float64
+	size        float64
+
+	complete      bool
+	sizeMatched   float64
+	avgPrice      float64
+	sizeRemaining float64
+	sizeCancelled float64
+	sizeLapsed    float64
+	sizeVoided    float64
+}
+
+func fromSummary(s types.CurrentOrderSummary) update {
+	return update{
+		betId:         s.BetId,
+		orderRef:      s.CustomerOrderRef,
+		strategyRef:   s.CustomerStrategyRef,
+		marketId:      s.MarketId,
+		selectionId:   s.SelectionId,
+		handicap:      s.Handicap,
+		side:          s.Side,
+		price:         s.PriceSize.Price,
+		size:          s.PriceSize.Size,
+		complete:      s.Status == types.ORDER_EXECUTION_COMPLETE,
+		sizeMatched:   s.SizeMatched,
+		avgPrice:      s.AveragePriceMatched,
+		sizeRemaining: s.SizeRemaining,
+		sizeCancelled: s.SizeCancelled,
+		sizeLapsed:    s.SizeLapsed,
+		sizeVoided:    s.SizeVoided,
+	}
+}
+
+func fromStream(e stream.OrderEvent) update {
+	side := types.BACK
+	if e.Order.Side == stream.ORDER_SIDE_LAY {
+		side = types.LAY
+	}
+
+	return update{
+		betId:         e.Order.Id,
+		orderRef:      e.Order.OrderRef,
+		strategyRef:   e.Order.StrategyRef,
+		marketId:      e.MarketId,
+		selectionId:   e.SelectionId,
+		handicap:      e.Handicap,
+		side:          side,
+		price:         e.Order.Price,
+		size:          e.Order.Size,
+		complete:      e.Order.Status == stream.ORDER_STATUS_EXECUTION_COMPLETE,
+		sizeMatched:   e.Order.SizeMatched,
+		avgPrice:      e.Order.AveragePriceMatched,
+		sizeRemaining: e.Order.SizeRemaining,
+		sizeCancelled: e.Order.SizeCancelled,
+		sizeLapsed:    e.Order.SizeLapsed,
+		sizeVoided:    e.Order.SizeVoided,
+	}
+}
+
+// State an update puts an order in
+// A completed order that matched nothing is reported by why it ended
+func (u update) state() State {
+	switch {
+	case !u.complete:
+		return STATE_EXECUTABLE
+	case u.sizeMatched > 0:
+		return STATE_EXECUTION_COMPLETE
+	case u.sizeLapsed > 0:
+		return STATE_LAPSED
+	case u.sizeCancelled > 0:
+		return STATE_CANCELLED
+	}
+	return STATE_EXECUTION_COMPLETE
+}
+
+// Applies an update, returning false if it was stale or changed nothing
+func (o *Order) apply(u update, now time.Time) bool {
+	next := u.state()
+
+	// Polls can lag the stream, never go back in time
+	if next != o.State && !canTransition(o.State, next) {
+		return false
+	}
+	if u.sizeMatched < o.SizeMatched {
+		return false
+	}
+
+	changed := next != o.State ||
+		u.sizeMatched != o.SizeMatched ||
+		u.sizeRemaining != o.SizeRemaining ||
+		u.sizeCancelled != o.SizeCancelled ||
+		u.sizeLapsed != o.SizeLapsed ||
+		u.sizeVoided != o.SizeVoided ||
+		(o.BetId == "" && u.betId != "")
+
+	if !changed {
+		return false
+	}
+
+	if o.BetId == "" {
+		o.BetId = u.betId
+	}
+	o.State = next
+	o.SizeMatched = u.sizeMatched
+	o.AveragePriceMatched = u.avgPrice
+	o.SizeRemaining = u.sizeRemaining
+	o.SizeCancelled = u.sizeCancelled
+	o.SizeLapsed = u.sizeLapsed
+	o.SizeVoided = u.sizeVoided
+	o.UpdatedAt = now
+
+	return true
+}
+
+func (o *Order) open() bool {
+	return !o.State.Terminal()
+}
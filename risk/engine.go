@@ -0,0 +1,590 @@
+// risk/engine.go
+
+package risk
+
+// Client-side risk checks.
+// The engine keeps a book of matched and unmatched positions, fed by order reports, ListCurrentOrders
+// and order stream events, and rejects requests that would breach a limit in the worst case:
+// every unmatched bet is assumed to match and the market is assumed to settle the worst way.
+// Requests in flight count as provisional positions until their report, a Refresh or the order stream resolves them,
+// and a market's positions are dropped once it settles or the order stream reports it closed.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+const refreshPageSize = 1000
+
+// Implemented by client.BetfairClient
+type OrderLister interface {
+	ListCurrentOrders(req types.ListCurrentOrdersRequest) (types.CurrentOrderSummaryReport, error)
+}
+
+type position struct {
+	marketId    string
+	strategyRef string
+	runner      runnerKey
+	side        types.Side
+	price       float64 // limit price of the unmatched size
+	matched     float64
+	avgPrice    float64
+	remaining   float64
+	bsp         float64 // unmatched BSP liability
+
+	// Provisional positions of a request whose outcome is unknown
+	pending  bool
+	size     float64
+	orderRef string
+}
+
+type runnerKey struct {
+	selectionId int64
+	handicap    float64
+}
+
+// Profit if the position's runner wins and if it loses, unmatched size counted only where it hurts
+func (p *position) outcomes() (ifWins, ifLoses float64) {
+	if p.side == types.LAY {
+		ifWins = -p.matched*(p.avgPrice-1) - p.remaining*(p.price-1) - p.bsp
+		ifLoses = p.matched
+		return
+	}
+	ifWins = p.matched * (p.avgPrice - 1)
+	ifLoses = -p.matched - p.remaining - p.bsp
+	return
+}
+
+func (p *position) open() bool {
+	return p.remaining > 0 || p.bsp > 0
+}
+
+type Engine struct {
+	limits Limits
+	now    func() time.Time
+
+	mu        sync.Mutex
+	positions map[string]*position // by betId
+	events    map[string]string    // marketId to eventId
+	lossDay   string
+	dailyLoss float64
+}
+
+func New(limits Limits) *Engine {
+	return &Engine{
+		limits:    limits,
+		now:       time.Now,
+		positions: make(map[string]*position),
+		events:    make(map[string]string),
+	}
+}
+
+// Maps a market to its event so MaxEventLiability can add up markets, e.g. from a market catalogue
+func (e *Engine) SetEvent(marketId, eventId string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.events[marketId] = eventId
+}
+
+// Records a settled profit or loss towards MaxDailyLoss
+// The positions behind it stay in the book, use Settled for a market the book holds
+func (e *Engine) RecordSettled(profit float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rollDay()
+	e.dailyLoss -= profit
+}
+
+// Records a market's settled profit or loss and drops its positions, so its worst case no longer counts
+func (e *Engine) Settled(marketId string, profit float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rollDay()
+	e.dailyLoss -= profit
+	e.forget(marketId)
+}
+
+// Drops every position in a market, e.g. once it has closed
+func (e *Engine) ForgetMarket(marketId string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.forget(marketId)
+}
+
+func (e *Engine) forget(marketId string) {
+	for id, p := range e.positions {
+		if p.marketId == marketId {
+			delete(e.positions, id)
+		}
+	}
+}
+
+// Net settled loss today, negative when in profit
+func (e *Engine) DailyLoss() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rollDay()
+	return e.dailyLoss
+}
+
+func (e *Engine) rollDay() {
+	day := e.now().UTC().Format(time.DateOnly)
+	if day != e.lossDay {
+		e.lossDay = day
+		e.dailyLoss = 0
+	}
+}
+
+// Worst case loss of a market given the current book
+func (e *Engine) MarketLiability(marketId string) float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return marketLiability(e.inMarket(e.positions, marketId))
+}
+
+// Replaces the book with every current order from ListCurrentOrders, which also resolves provisional positions
+func (e *Engine) Refresh(api OrderLister) error {
+	var summaries []types.CurrentOrderSummary
+	for from := 0; ; from += refreshPageSize {
+		report, err := api.ListCurrentOrders(types.ListCurrentOrdersRequest{
+			OrderProjection: types.ALL,
+			FromRecord:      from,
+			RecordCount:     refreshPageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to refresh risk positions: %w", err)
+		}
+
+		summaries = append(summaries, report.CurrentOrders...)
+		if !report.MoreAvailable {
+			break
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.positions = make(map[string]*position, len(summaries))
+	for _, s := range summaries {
+		e.positions[s.BetId] = &position{
+			marketId:    s.MarketId,
+			strategyRef: s.CustomerStrategyRef,
+			runner:      runnerKey{s.SelectionId, s.Handicap},
+			side:        s.Side,
+			price:       s.PriceSize.Price,
+			matched:     s.SizeMatched,
+			avgPrice:    s.AveragePriceMatched,
+			remaining:   s.SizeRemaining,
+			bsp:         unmatchedBsp(s.OrderType, s.Status == types.ORDER_EXECUTION_COMPLETE, s.BspLiability),
+		}
+	}
+
+	return nil
+}
+
+// Keeps the book up to date from the order stream, pass to stream.ClientConfig.OnOrderEvent
+// A closed market's positions are dropped
+func (e *Engine) HandleOrderEvent(ev stream.OrderEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if ev.Type == stream.ORDER_MARKET_CLOSED {
+		e.forget(ev.MarketId)
+		return
+	}
+
+	side := types.BACK
+	if ev.Order.Side == stream.ORDER_SIDE_LAY {
+		side = types.LAY
+	}
+
+	if _, known := e.positions[ev.Order.Id]; !known {
+		e.resolvePending(ev.MarketId, runnerKey{ev.SelectionId, ev.Handicap}, side, ev.Order)
+	}
+
+	complete := ev.Order.Status == stream.ORDER_STATUS_EXECUTION_COMPLETE
+	e.positions[ev.Order.Id] = &position{
+		marketId:    ev.MarketId,
+		strategyRef: ev.Order.StrategyRef,
+		runner:      runnerKey{ev.SelectionId, ev.Handicap},
+		side:        side,
+		price:       ev.Order.Price,
+		matched:     ev.Order.SizeMatched,
+		avgPrice:    ev.Order.AveragePriceMatched,
+		remaining:   ev.Order.SizeRemaining,
+		bsp:         unmatchedBsp(types.OrderType(ev.Order.OrderType), complete, ev.Order.BspLiability),
+	}
+}
+
+func unmatchedBsp(orderType types.OrderType, complete bool, liability float64) float64 {
+	if complete || orderType == types.LIMIT {
+		return 0
+	}
+	return liability
+}
+
+// Drops the first provisional position a new bet from the order stream accounts for
+func (e *Engine) resolvePending(marketId string, runner runnerKey, side types.Side, o stream.Order) {
+	ids := make([]string, 0)
+	for id, p := range e.positions {
+		if p.pending && p.marketId == marketId && p.runner == runner && p.side == side &&
+			(p.orderRef == "" || p.orderRef == o.OrderRef) &&
+			((p.size > 0 && p.price == o.Price && p.size == o.Size) || (p.size == 0 && p.bsp == o.BspLiability)) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	sort.Strings(ids)
+	delete(e.positions, ids[0])
+}
+
+func pendingPrefix(customerRef string) string {
+	return "pending:" + customerRef + ":"
+}
+
+// Adds the instructions of a request about to be sent as provisional positions
+// They count towards every limit until Placed, a Refresh or a matching order stream event resolves them,
+// so a request lost to a transport error is still covered while its outcome is unknown
+func (e *Engine) Pending(req types.PlaceOrdersRequest) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, ins := range req.Instructions {
+		p := newPosition(req.MarketId, req.CustomerStrategyRef, ins)
+		p.pending = true
+		p.size = p.remaining
+		p.orderRef = ins.CustomerOrderRef
+		e.positions[fmt.Sprintf("%s%d", pendingPrefix(req.CustomerRef), i)] = p
+	}
+}
+
+// Adds the accepted instructions of a place report to the book, replacing the request's provisional positions
+func (e *Engine) Placed(req types.PlaceOrdersRequest, report types.PlaceExecutionReport) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	prefix := pendingPrefix(req.CustomerRef)
+	for id, p := range e.positions {
+		if p.pending && strings.HasPrefix(id, prefix) {
+			delete(e.positions, id)
+		}
+	}
+
+	for _, ir := range report.InstructionReports {
+		if ir.Status != types.INSTRUCTION_SUCCESS || ir.BetId == "" {
+			continue
+		}
+		p := newPosition(req.MarketId, req.CustomerStrategyRef, ir.Instruction)
+		p.matched = ir.SizeMatched
+		p.avgPrice = ir.AveragePriceMatched
+		p.remaining = max(p.remaining-ir.SizeMatched, 0)
+		if ir.OrderStatus == types.ORDER_EXECUTION_COMPLETE {
+			p.remaining, p.bsp = 0, 0
+		}
+		e.positions[ir.BetId] = p
+	}
+}
+
+// Removes cancelled size from the book
+func (e *Engine) Cancelled(report types.CancelExecutionReport) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, ir := range report.InstructionReports {
+		e.cancelled(ir)
+	}
+}
+
+func (e *Engine) cancelled(ir types.CancelInstructionReport) {
+	if ir.Status != types.INSTRUCTION_SUCCESS || ir.Instruction == nil {
+		return
+	}
+	if p, ok := e.positions[ir.Instruction.BetId]; ok {
+		p.remaining = max(p.remaining-ir.SizeCancelled, 0)
+		if p.remaining == 0 {
+			p.bsp = 0
+		}
+	}
+}
+
+// Moves replaced size to the new bets
+func (e *Engine) Replaced(req types.ReplaceOrdersRequest, report types.ReplaceExecutionReport) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, ir := range report.InstructionReports {
+		strategyRef := ""
+		if ir.CancelInstructionReport != nil {
+			if ins := ir.CancelInstructionReport.Instruction; ins != nil {
+				if p, ok := e.positions[ins.BetId]; ok {
+					strategyRef = p.strategyRef
+				}
+			}
+			e.cancelled(*ir.CancelInstructionReport)
+		}
+
+		pr := ir.PlaceInstructionReport
+		if pr == nil || pr.Status != types.INSTRUCTION_SUCCESS || pr.BetId == "" {
+			continue
+		}
+		p := newPosition(req.MarketId, strategyRef, pr.Instruction)
+		p.matched = pr.SizeMatched
+		p.avgPrice = pr.AveragePriceMatched
+		p.remaining = max(p.remaining-pr.SizeMatched, 0)
+		e.positions[pr.BetId] = p
+	}
+}
+
+func newPosition(marketId, strategyRef string, ins types.PlaceInstruction) *position {
+	p := &position{
+		marketId:    marketId,
+		strategyRef: strategyRef,
+		runner:      runnerKey{ins.SelectionId, ins.Handicap},
+		side:        ins.Side,
+	}
+
+	switch {
+	case ins.LimitOrder != nil:
+		p.price = ins.LimitOrder.Price
+		p.remaining = ins.LimitOrder.Size
+	case ins.LimitOnCloseOrder != nil:
+		p.bsp = ins.LimitOnCloseOrder.Liability
+	case ins.MarketOnCloseOrder != nil:
+		p.bsp = ins.MarketOnCloseOrder.Liability
+	}
+
+	return p
+}
+
+// Stake of an instruction as MaxStake sees it
+func stake(ins types.PlaceInstruction) float64 {
+	switch {
+	case ins.LimitOrder != nil:
+		return ins.LimitOrder.Size
+	case ins.LimitOnCloseOrder != nil:
+		return ins.LimitOnCloseOrder.Liability
+	case ins.MarketOnCloseOrder != nil:
+		return ins.MarketOnCloseOrder.Liability
+	}
+	return 0
+}
+
+// Returns a *Rejection if any instruction would breach a limit
+func (e *Engine) CheckPlace(req types.PlaceOrdersRequest) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	book := e.clone()
+	for i, ins := range req.Instructions {
+		reject := func(reason RejectReason, limit, value float64, scope string) error {
+			return &Rejection{Reason: reason, MarketId: req.MarketId, Index: i, Limit: limit, Value: value, Scope: scope}
+		}
+
+		if s := stake(ins); e.limits.MaxStake > 0 && s > e.limits.MaxStake {
+			return reject(REJECT_MAX_STAKE, e.limits.MaxStake, s, "")
+		}
+
+		book[fmt.Sprintf("pending-%d", i)] = newPosition(req.MarketId, req.CustomerStrategyRef, ins)
+		if err := e.check(book, req.MarketId, runnerKey{ins.SelectionId, ins.Handicap}, req.CustomerStrategyRef, reject); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns a *Rejection if moving any bet to its new price would breach a limit
+// Bets missing from the book are only checked once it knows about them
+func (e *Engine) CheckReplace(req types.ReplaceOrdersRequest) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	book := e.clone()
+	for i, ins := range req.Instructions {
+		reject := func(reason RejectReason, limit, value float64, scope string) error {
+			return &Rejection{Reason: reason, MarketId: req.MarketId, Index: i, Limit: limit, Value: value, Scope: scope}
+		}
+
+		old, ok := book[ins.BetId]
+		if !ok || old.remaining == 0 {
+			continue
+		}
+
+		moved := *old
+		moved.matched, moved.avgPrice = 0, 0
+		moved.price = ins.NewPrice
+		remaining := *old
+		remaining.remaining = 0
+
+		book[ins.BetId] = &remaining
+		book[ins.BetId+"-replacement"] = &moved
+
+		if err := e.check(book, req.MarketId, old.runner, old.strategyRef, reject); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type rejectFunc func(reason RejectReason, limit, value float64, scope string) error
+
+// Checks every limit affected by a change to one runner of a market
+func (e *Engine) check(book map[string]*position, marketId string, runner runnerKey, strategyRef string, reject rejectFunc) error {
+	l := e.limits
+	market := e.inMarket(book, marketId)
+
+	if l.MaxRunnerLiability > 0 {
+		var onRunner []*position
+		for _, p := range market {
+			if p.runner == runner {
+				onRunner = append(onRunner, p)
+			}
+		}
+		if v := runnerLiability(onRunner); v > l.MaxRunnerLiability {
+			return reject(REJECT_RUNNER_LIABILITY, l.MaxRunnerLiability, v, fmt.Sprintf("selection %d", runner.selectionId))
+		}
+	}
+
+	if v := marketLiability(market); l.MaxMarketLiability > 0 && v > l.MaxMarketLiability {
+		return reject(REJECT_MARKET_LIABILITY, l.MaxMarketLiability, v, "")
+	}
+
+	if eventId, ok := e.events[marketId]; ok && l.MaxEventLiability > 0 {
+		var v float64
+		for _, id := range e.marketIds(book) {
+			if e.events[id] == eventId {
+				v += marketLiability(e.inMarket(book, id))
+			}
+		}
+		if v > l.MaxEventLiability {
+			return reject(REJECT_EVENT_LIABILITY, l.MaxEventLiability, v, "event "+eventId)
+		}
+	}
+
+	if l.MaxOpenOrders > 0 {
+		open := 0
+		for _, p := range book {
+			if p.open() {
+				open++
+			}
+		}
+		if open > l.MaxOpenOrders {
+			return reject(REJECT_MAX_OPEN_ORDERS, float64(l.MaxOpenOrders), float64(open), "")
+		}
+	}
+
+	if budget, ok := l.StrategyBudgets[strategyRef]; ok {
+		var v float64
+		for _, id := range e.marketIds(book) {
+			var ofStrategy []*position
+			for _, p := range e.inMarket(book, id) {
+				if p.strategyRef == strategyRef {
+					ofStrategy = append(ofStrategy, p)
+				}
+			}
+			v += marketLiability(ofStrategy)
+		}
+		if v > budget {
+			return reject(REJECT_STRATEGY_BUDGET, budget, v, "strategy "+strategyRef)
+		}
+	}
+
+	if l.MaxDailyLoss > 0 {
+		e.rollDay()
+		v := e.dailyLoss
+		for _, id := range e.marketIds(book) {
+			v += marketLiability(e.inMarket(book, id))
+		}
+		if v > l.MaxDailyLoss {
+			return reject(REJECT_DAILY_LOSS, l.MaxDailyLoss, v, "")
+		}
+	}
+
+	return nil
+}
+
+func (e *Engine) clone() map[string]*position {
+	book := make(map[string]*position, len(e.positions))
+	for id, p := range e.positions {
+		book[id] = p
+	}
+	return book
+}
+
+func (e *Engine) inMarket(book map[string]*position, marketId string) []*position {
+	var market []*position
+	for _, p := range book {
+		if p.marketId == marketId {
+			market = append(market, p)
+		}
+	}
+	return market
+}
+
+func (e *Engine) marketIds(book map[string]*position) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, p := range book {
+		if !seen[p.marketId] {
+			seen[p.marketId] = true
+			ids = append(ids, p.marketId)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Worst case loss of bets on a single runner
+func runnerLiability(positions []*position) float64 {
+	var ifWins, ifLoses float64
+	for _, p := range positions {
+		w, l := p.outcomes()
+		ifWins += w
+		ifLoses += l
+	}
+	return max(0, -ifWins, -ifLoses)
+}
+
+// Worst case loss over each runner winning, or none of the runners bet on winning
+func marketLiability(positions []*position) float64 {
+	runners := make(map[runnerKey]bool)
+	for _, p := range positions {
+		runners[p.runner] = true
+	}
+
+	var none float64
+	pnl := make(map[runnerKey]float64, len(runners))
+	for _, p := range positions {
+		ifWins, ifLoses := p.outcomes()
+		none += ifLoses
+		for r := range runners {
+			if r == p.runner {
+				pnl[r] += ifWins
+			} else {
+				pnl[r] += ifLoses
+			}
+		}
+	}
+
+	worst := min(0, none)
+	for _, v := range pnl {
+		worst = min(worst, v)
+	}
+	return -worst
+}
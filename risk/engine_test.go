@@ -0,0 +1,188 @@
+// risk/engine_test.go
+
+package risk_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/betfairtest"
+	"github.com/Bazcampbell/betfair-api-go-sdk/client"
+	"github.com/Bazcampbell/betfair-api-go-sdk/risk"
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func limit(selectionId int64, side types.Side, price, size float64) types.PlaceInstruction {
+	return types.PlaceInstruction{
+		OrderType:   types.LIMIT,
+		SelectionId: selectionId,
+		Side:        side,
+		LimitOrder:  &types.LimitOrder{Price: price, Size: size, PersistenceType: types.LAPSE},
+	}
+}
+
+func placed(req types.PlaceOrdersRequest, betIds ...string) types.PlaceExecutionReport {
+	report := types.PlaceExecutionReport{Status: types.EXECUTION_SUCCESS, MarketId: req.MarketId}
+	for i, ins := range req.Instructions {
+		report.InstructionReports = append(report.InstructionReports, types.PlaceInstructionReport{
+			Status: types.INSTRUCTION_SUCCESS, OrderStatus: types.ORDER_EXECUTABLE, Instruction: ins, BetId: betIds[i]})
+	}
+	return report
+}
+
+func rejection(t *testing.T, err error) *risk.Rejection {
+	t.Helper()
+
+	var rej *risk.Rejection
+	require.True(t, errors.As(err, &rej), "expected a rejection, got %v", err)
+	return rej
+}
+
+func TestCheckPlace_StakeAndWorstCaseLiability(t *testing.T) {
+	e := risk.New(risk.Limits{MaxStake: 50, MaxRunnerLiability: 60, MaxMarketLiability: 100})
+
+	rej := rejection(t, e.CheckPlace(types.PlaceOrdersRequest{MarketId: "1.1",
+		Instructions: []types.PlaceInstruction{limit(1, types.BACK, 2, 60)}}))
+	assert.Equal(t, risk.REJECT_MAX_STAKE, rej.Reason)
+
+	// Lay 20 at 4.0 risks 60 if the runner wins
+	rej = rejection(t, e.CheckPlace(types.PlaceOrdersRequest{MarketId: "1.1",
+		Instructions: []types.PlaceInstruction{limit(1, types.LAY, 4.0, 20.5)}}))
+	assert.Equal(t, risk.REJECT_RUNNER_LIABILITY, rej.Reason)
+	assert.InDelta(t, 61.5, rej.Value, 1e-9)
+
+	req := types.PlaceOrdersRequest{MarketId: "1.1", Instructions: []types.PlaceInstruction{
+		limit(1, types.LAY, 4.0, 20), limit(2, types.BACK, 3.0, 30),
+	}}
+	require.NoError(t, e.CheckPlace(req))
+	e.Placed(req, placed(req, "b1", "b2"))
+
+	// Runner 1 winning loses 60 + 30, a further back on runner 3 loses in every other outcome too
+	assert.InDelta(t, 90, e.MarketLiability("1.1"), 1e-9)
+	rej = rejection(t, e.CheckPlace(types.PlaceOrdersRequest{MarketId: "1.1",
+		Instructions: []types.PlaceInstruction{limit(3, types.BACK, 5.0, 15)}}))
+	assert.Equal(t, risk.REJECT_MARKET_LIABILITY, rej.Reason)
+	assert.InDelta(t, 105, rej.Value, 1e-9)
+
+	// Cancelling the lay frees up its liability
+	e.Cancelled(types.CancelExecutionReport{InstructionReports: []types.CancelInstructionReport{{
+		Status: types.INSTRUCTION_SUCCESS, Instruction: &types.CancelInstruction{BetId: "b1"}, SizeCancelled: 20}}})
+	assert.InDelta(t, 30, e.MarketLiability("1.1"), 1e-9)
+}
+
+func TestCheckPlace_OpenOrdersStrategyBudgetAndDailyLoss(t *testing.T) {
+	e := risk.New(risk.Limits{MaxOpenOrders: 2, StrategyBudgets: map[string]float64{"scalper": 25}, MaxDailyLoss: 100})
+
+	req := types.PlaceOrdersRequest{MarketId: "1.1", CustomerStrategyRef: "scalper",
+		Instructions: []types.PlaceInstruction{limit(1, types.BACK, 2, 10), limit(2, types.BACK, 2, 10), limit(3, types.BACK, 2, 10)}}
+	rej := rejection(t, e.CheckPlace(req))
+	assert.Equal(t, risk.REJECT_MAX_OPEN_ORDERS, rej.Reason)
+	assert.Equal(t, 2, rej.Index)
+
+	req.Instructions = req.Instructions[:1]
+	req.Instructions[0].LimitOrder.Size = 30
+	assert.Equal(t, risk.REJECT_STRATEGY_BUDGET, rejection(t, e.CheckPlace(req)).Reason)
+
+	e.RecordSettled(-80)
+	req.CustomerStrategyRef = ""
+	assert.Equal(t, risk.REJECT_DAILY_LOSS, rejection(t, e.CheckPlace(req)).Reason)
+}
+
+func TestClient_RejectedOrdersNeverLeaveTheProcess(t *testing.T) {
+	srv, err := betfairtest.NewServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	e := risk.New(risk.Limits{MaxStake: 10})
+	bf, err := client.NewSession(srv.Credentials(), func(error) {},
+		client.WithBaseURL(srv.URL()), client.WithRiskEngine(e))
+	require.NoError(t, err)
+
+	_, err = bf.PlaceOrders(types.PlaceOrdersRequest{MarketId: "1.1",
+		Instructions: []types.PlaceInstruction{limit(1, types.BACK, 2, 20)}})
+	assert.Equal(t, risk.REJECT_MAX_STAKE, rejection(t, err).Reason)
+	srv.AssertNotCalled(t, "placeOrders")
+}
+
+func TestSettled_DropsMarketPositions(t *testing.T) {
+	e := risk.New(risk.Limits{MaxMarketLiability: 100, MaxDailyLoss: 70})
+
+	req := types.PlaceOrdersRequest{MarketId: "1.1", Instructions: []types.PlaceInstruction{limit(1, types.LAY, 3, 40)}}
+	e.Placed(req, placed(req, "b1"))
+	other := types.PlaceOrdersRequest{MarketId: "1.2", Instructions: []types.PlaceInstruction{limit(1, types.BACK, 2, 10)}}
+	e.Placed(other, placed(other, "b2"))
+	assert.InDelta(t, 80, e.MarketLiability("1.1"), 1e-9)
+
+	e.Settled("1.1", -40)
+	assert.Zero(t, e.MarketLiability("1.1"))
+	assert.InDelta(t, 10, e.MarketLiability("1.2"), 1e-9)
+	assert.InDelta(t, 40, e.DailyLoss(), 1e-9)
+
+	// The settled lay no longer counts, only the loss it made
+	req.Instructions[0].LimitOrder.Size = 5
+	require.NoError(t, e.CheckPlace(req))
+}
+
+func TestHandleOrderEvent_MarketClosedDropsPositions(t *testing.T) {
+	e := risk.New(risk.Limits{})
+
+	cache := stream.NewOrderCache(e.HandleOrderEvent)
+	cache.Apply(1, &stream.OrderMarketChange{Id: "1.1", Orc: []*stream.OrderRunnerChange{{Id: 1, Uo: []*stream.Order{{
+		Id: "b1", Side: stream.ORDER_SIDE_BACK, Price: 2, Size: 10, SizeRemaining: 10, Status: stream.ORDER_STATUS_EXECUTABLE}}}}})
+	assert.InDelta(t, 10, e.MarketLiability("1.1"), 1e-9)
+
+	cache.Apply(2, &stream.OrderMarketChange{Id: "1.1", Closed: true})
+	assert.Zero(t, e.MarketLiability("1.1"))
+}
+
+func TestClient_LostPlaceStaysProvisional(t *testing.T) {
+	srv, err := betfairtest.NewServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	e := risk.New(risk.Limits{MaxMarketLiability: 15})
+	bf, err := client.NewSession(srv.Credentials(), func(error) {}, client.WithBaseURL(srv.URL()), client.WithRiskEngine(e),
+		client.WithRoundTripper(func(next http.RoundTripper) http.RoundTripper {
+			return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				if strings.Contains(r.URL.Path, "placeOrders") {
+					return nil, errors.New("connection reset")
+				}
+				return next.RoundTrip(r)
+			})
+		}))
+	require.NoError(t, err)
+
+	_, err = bf.PlaceOrders(types.PlaceOrdersRequest{MarketId: "1.1",
+		Instructions: []types.PlaceInstruction{limit(1, types.BACK, 2, 10)}})
+	require.Error(t, err)
+
+	// The order may have reached Betfair, so its stake still counts
+	assert.InDelta(t, 10, e.MarketLiability("1.1"), 1e-9)
+	assert.Equal(t, risk.REJECT_MARKET_LIABILITY, rejection(t, e.CheckPlace(types.PlaceOrdersRequest{MarketId: "1.1",
+		Instructions: []types.PlaceInstruction{limit(2, types.BACK, 2, 10)}})).Reason)
+
+	// The stream reporting the bet replaces the provisional position rather than adding to it
+	e.HandleOrderEvent(stream.OrderEvent{Type: stream.ORDER_PLACED, MarketId: "1.1", SelectionId: 1, Order: stream.Order{
+		Id: "b1", Side: stream.ORDER_SIDE_BACK, Price: 2, Size: 10, SizeRemaining: 10, Status: stream.ORDER_STATUS_EXECUTABLE}})
+	assert.InDelta(t, 10, e.MarketLiability("1.1"), 1e-9)
+
+	// A refresh that finds nothing resolves it too
+	_, err = bf.PlaceOrders(types.PlaceOrdersRequest{MarketId: "1.2",
+		Instructions: []types.PlaceInstruction{limit(1, types.BACK, 2, 10)}})
+	require.Error(t, err)
+	assert.InDelta(t, 10, e.MarketLiability("1.2"), 1e-9)
+
+	require.NoError(t, srv.SetFixture("listCurrentOrders", types.CurrentOrderSummaryReport{}))
+	require.NoError(t, e.Refresh(bf))
+	assert.Zero(t, e.MarketLiability("1.2"))
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,64 @@
+// risk/limits.go
+
+package risk
+
+import (
+	"fmt"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+// Zero values mean no limit
+type Limits struct {
+	MaxStake           float64 // per order, the size of a limit order or the liability of a BSP order
+	MaxRunnerLiability float64 // worst case loss from bets on one runner
+	MaxMarketLiability float64 // worst case loss over every outcome of a market
+	MaxEventLiability  float64 // sum of market liabilities in an event, needs SetEvent
+	MaxOpenOrders      int     // orders with unmatched size
+	MaxDailyLoss       float64 // settled losses today (UTC) plus worst case loss of every open market
+
+	// Worst case liability per customerStrategyRef, strategies without a budget are unlimited
+	StrategyBudgets map[string]float64
+}
+
+type RejectReason string
+
+const (
+	REJECT_MAX_STAKE        RejectReason = "MAX_STAKE"
+	REJECT_RUNNER_LIABILITY RejectReason = "RUNNER_LIABILITY"
+	REJECT_MARKET_LIABILITY RejectReason = "MARKET_LIABILITY"
+	REJECT_EVENT_LIABILITY  RejectReason = "EVENT_LIABILITY"
+	REJECT_MAX_OPEN_ORDERS  RejectReason = "MAX_OPEN_ORDERS"
+	REJECT_DAILY_LOSS       RejectReason = "DAILY_LOSS"
+	REJECT_STRATEGY_BUDGET  RejectReason = "STRATEGY_BUDGET"
+)
+
+var _ types.PreflightError = (*Rejection)(nil)
+
+// Returned when a request would breach a limit, nothing is sent to Betfair
+type Rejection struct {
+	Reason   RejectReason
+	MarketId string
+	Index    int     // instruction that breached the limit
+	Limit    float64 // the configured limit
+	Value    float64 // what it would have reached
+	Scope    string  // runner, event or strategy the limit applies to, if any
+}
+
+func (r *Rejection) Error() string {
+	scope := ""
+	if r.Scope != "" {
+		scope = " " + r.Scope
+	}
+	return fmt.Sprintf("risk rejected instruction %d on market %s: %s%s %.2f exceeds limit %.2f",
+		r.Index, r.MarketId, r.Reason, scope, r.Value, r.Limit)
+}
+
+// Satisfies types.PreflightError, Reason says which limit was breached
+func (r *Rejection) PreflightCodes() map[int]types.InstructionReportErrorCode {
+	code := types.ERROR_IN_ORDER
+	if r.Reason == REJECT_MAX_STAKE {
+		code = types.INVALID_BET_SIZE
+	}
+	return map[int]types.InstructionReportErrorCode{r.Index: code}
+}
@@ -0,0 +1,28 @@
+// sim/data.go
+
+package sim
+
+// Where the simulated exchange gets its prices
+
+import (
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+// Real market data, implemented by client.BetfairClient
+type MarketData interface {
+	ListMarketBook(req types.ListMarketBookRequest) ([]types.ListMarketBookResponse, error)
+}
+
+type cacheData struct {
+	cache *stream.MarketCache
+}
+
+// Serves market books from a stream market cache instead of polling
+func FromCache(cache *stream.MarketCache) MarketData {
+	return cacheData{cache: cache}
+}
+
+func (c cacheData) ListMarketBook(req types.ListMarketBookRequest) ([]types.ListMarketBookResponse, error) {
+	return c.cache.MarketBooks(req.MarketIds...), nil
+}
@@ -0,0 +1,454 @@
+// sim/exchange.go
+
+package sim
+
+// Paper trading exchange.
+// Implements the order and market data methods of BetfairClient, filling orders against real
+// market data with a virtual balance, so strategies can run live without risking money.
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+	"github.com/Bazcampbell/betfair-api-go-sdk/validation"
+)
+
+const defaultBalance = 1000
+
+type Config struct {
+	Data      MarketData
+	Balance   float64               // starting balance, defaults to 1000
+	Validator *validation.Validator // defaults to GBP minimums
+	Now       func() time.Time      // defaults to time.Now, override to control bet delay in tests
+}
+
+type simOrder struct {
+	summary      types.CurrentOrderSummary
+	activeAt     time.Time // bet delay, the order cannot match before this
+	placedInPlay bool
+}
+
+type Exchange struct {
+	cfg Config
+
+	mu       sync.Mutex
+	orders   map[string]*simOrder
+	sequence []string // bet ids in placement order
+	nextBet  int64
+	balance  float64
+	traded   map[tradeKey]float64 // last seen traded volume, fills resting orders as it grows
+	seen     map[string]bool      // markets with traded volume recorded
+	settled  map[string]bool
+}
+
+type tradeKey struct {
+	marketId    string
+	selectionId int64
+	handicap    float64
+	price       float64
+}
+
+func New(cfg Config) (*Exchange, error) {
+	if cfg.Data == nil {
+		return nil, fmt.Errorf("simulated exchange needs market data")
+	}
+	if cfg.Balance <= 0 {
+		cfg.Balance = defaultBalance
+	}
+	if cfg.Validator == nil {
+		v, err := validation.ForCurrency("GBP")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Validator = v
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	return &Exchange{
+		cfg:     cfg,
+		orders:  make(map[string]*simOrder),
+		balance: cfg.Balance,
+		traded:  make(map[tradeKey]float64),
+		seen:    make(map[string]bool),
+		settled: make(map[string]bool),
+	}, nil
+}
+
+// Current market books, also used to progress matching on the returned markets
+func (e *Exchange) ListMarketBook(req types.ListMarketBookRequest) ([]types.ListMarketBookResponse, error) {
+	books, err := e.cfg.Data.ListMarketBook(req)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	for _, book := range books {
+		e.match(book)
+	}
+	e.mu.Unlock()
+
+	return books, nil
+}
+
+func (e *Exchange) PlaceOrders(req types.PlaceOrdersRequest) (types.PlaceExecutionReport, error) {
+	report := types.PlaceExecutionReport{
+		CustomerRef: req.CustomerRef,
+		MarketId:    req.MarketId,
+		Status:      types.EXECUTION_SUCCESS,
+	}
+
+	if err := e.cfg.Validator.ValidatePlace(req); err != nil {
+		return report, err
+	}
+
+	book, err := e.book(req.MarketId)
+	if err != nil {
+		return report, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if book.Status != types.OPEN {
+		report.Status = types.EXECUTION_FAILURE
+		report.ErrorCode = types.MARKET_NOT_OPEN_FOR_BETTING
+		return report, nil
+	}
+
+	now := e.cfg.Now()
+	var placed []*simOrder
+
+	for _, ins := range req.Instructions {
+		ir := types.PlaceInstructionReport{Instruction: ins}
+
+		if ins.OrderType != types.LIMIT {
+			ir.Status = types.INSTRUCTION_FAILURE
+			ir.ErrorCode = types.INVALID_ORDER_TYPE
+			report.InstructionReports = append(report.InstructionReports, ir)
+			report.Status = types.EXECUTION_FAILURE
+			continue
+		}
+
+		order := ins.LimitOrder
+		if liability(ins.Side, order.Price, order.Size) > e.availableLocked() {
+			ir.Status = types.INSTRUCTION_FAILURE
+			ir.ErrorCode = types.INSTRUCTION_INSUFFICIENT_FUNDS
+			report.Status = types.EXECUTION_FAILURE
+			report.ErrorCode = types.INSUFFICIENT_FUNDS
+			report.InstructionReports = append(report.InstructionReports, ir)
+			continue
+		}
+
+		e.nextBet++
+		o := &simOrder{
+			summary: types.CurrentOrderSummary{
+				BetId:               fmt.Sprintf("%d", e.nextBet),
+				MarketId:            req.MarketId,
+				SelectionId:         ins.SelectionId,
+				Handicap:            ins.Handicap,
+				PriceSize:           types.PriceSize{Price: order.Price, Size: order.Size},
+				Side:                ins.Side,
+				Status:              types.ORDER_EXECUTABLE,
+				PersistenceType:     order.PersistenceType,
+				OrderType:           types.LIMIT,
+				PlacedDate:          now.UTC().Format(time.RFC3339Nano),
+				SizeRemaining:       order.Size,
+				CustomerOrderRef:    ins.CustomerOrderRef,
+				CustomerStrategyRef: req.CustomerStrategyRef,
+			},
+			placedInPlay: book.InPlay,
+		}
+
+		// In-play bets wait out the bet delay before they can match
+		if book.InPlay && book.BetDelay > 0 {
+			o.activeAt = now.Add(time.Duration(book.BetDelay) * time.Second)
+			o.summary.Status = types.ORDER_PENDING
+		}
+
+		e.orders[o.summary.BetId] = o
+		e.sequence = append(e.sequence, o.summary.BetId)
+		placed = append(placed, o)
+
+		ir.Status = types.INSTRUCTION_SUCCESS
+		ir.BetId = o.summary.BetId
+		ir.PlacedDate = o.summary.PlacedDate
+		report.InstructionReports = append(report.InstructionReports, ir)
+	}
+
+	// Orders that cross the spread match straight away
+	e.match(book)
+
+	i := 0
+	for r := range report.InstructionReports {
+		ir := &report.InstructionReports[r]
+		if ir.Status != types.INSTRUCTION_SUCCESS {
+			continue
+		}
+		o := placed[i]
+		i++
+		ir.OrderStatus = o.summary.Status
+		ir.SizeMatched = o.summary.SizeMatched
+		ir.AveragePriceMatched = o.summary.AveragePriceMatched
+	}
+
+	if report.Status == types.EXECUTION_FAILURE && report.ErrorCode == "" {
+		report.ErrorCode = types.PROCESSED_WITH_ERRORS
+	}
+
+	return report, nil
+}
+
+// Leave MarketId empty to cancel everything
+func (e *Exchange) CancelOrders(req types.CancelOrdersRequest) (types.CancelExecutionReport, error) {
+	report := types.CancelExecutionReport{CustomerRef: req.CustomerRef, MarketId: req.MarketId, Status: types.EXECUTION_SUCCESS}
+
+	if err := e.cfg.Validator.ValidateCancel(req); err != nil {
+		return report, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	instructions := req.Instructions
+	if len(instructions) == 0 {
+		for _, betId := range e.sequence {
+			o := e.orders[betId]
+			if o.open() && (req.MarketId == "" || o.summary.MarketId == req.MarketId) {
+				instructions = append(instructions, types.CancelInstruction{BetId: betId})
+			}
+		}
+	}
+
+	now := e.cfg.Now().UTC().Format(time.RFC3339Nano)
+	for _, ins := range instructions {
+		ins := ins
+		ir := types.CancelInstructionReport{Instruction: &ins}
+
+		o, ok := e.orders[ins.BetId]
+		if !ok || !o.open() || (req.MarketId != "" && o.summary.MarketId != req.MarketId) {
+			ir.Status = types.INSTRUCTION_FAILURE
+			ir.ErrorCode = types.BET_TAKEN_OR_LAPSED
+			report.Status = types.EXECUTION_FAILURE
+			report.ErrorCode = types.PROCESSED_WITH_ERRORS
+			report.InstructionReports = append(report.InstructionReports, ir)
+			continue
+		}
+
+		size := o.summary.SizeRemaining
+		if ins.SizeReduction != nil {
+			size = math.Min(size, *ins.SizeReduction)
+		}
+
+		o.summary.SizeRemaining = round2(o.summary.SizeRemaining - size)
+		o.summary.SizeCancelled = round2(o.summary.SizeCancelled + size)
+		if o.summary.SizeRemaining == 0 {
+			o.summary.Status = types.ORDER_EXECUTION_COMPLETE
+		}
+
+		ir.Status = types.INSTRUCTION_SUCCESS
+		ir.SizeCancelled = size
+		ir.CancelledDate = now
+		report.InstructionReports = append(report.InstructionReports, ir)
+	}
+
+	return report, nil
+}
+
+// Cancels the remaining size of each bet and places it again at the new price
+func (e *Exchange) ReplaceOrders(req types.ReplaceOrdersRequest) (types.ReplaceExecutionReport, error) {
+	report := types.ReplaceExecutionReport{CustomerRef: req.CustomerRef, MarketId: req.MarketId, Status: types.EXECUTION_SUCCESS}
+
+	if err := e.cfg.Validator.ValidateReplace(req); err != nil {
+		return report, err
+	}
+
+	for _, ins := range req.Instructions {
+		e.mu.Lock()
+		o, ok := e.orders[ins.BetId]
+		var original types.CurrentOrderSummary
+		if ok {
+			original = o.summary
+		}
+		e.mu.Unlock()
+
+		if !ok || original.Status == types.ORDER_EXECUTION_COMPLETE {
+			report.Status = types.EXECUTION_FAILURE
+			report.ErrorCode = types.PROCESSED_WITH_ERRORS
+			report.InstructionReports = append(report.InstructionReports, types.ReplaceInstructionReport{
+				Status: types.INSTRUCTION_FAILURE, ErrorCode: types.BET_TAKEN_OR_LAPSED})
+			continue
+		}
+
+		cancel, err := e.CancelOrders(types.CancelOrdersRequest{MarketId: req.MarketId,
+			Instructions: []types.CancelInstruction{{BetId: ins.BetId}}})
+		if err != nil {
+			return report, err
+		}
+		cr := cancel.InstructionReports[0]
+
+		ir := types.ReplaceInstructionReport{Status: cr.Status, ErrorCode: cr.ErrorCode, CancelInstructionReport: &cr}
+		if cr.Status == types.INSTRUCTION_SUCCESS {
+			place, err := e.PlaceOrders(types.PlaceOrdersRequest{
+				MarketId:            req.MarketId,
+				CustomerStrategyRef: original.CustomerStrategyRef,
+				Instructions: []types.PlaceInstruction{{
+					OrderType:        types.LIMIT,
+					SelectionId:      original.SelectionId,
+					Handicap:         original.Handicap,
+					Side:             original.Side,
+					CustomerOrderRef: original.CustomerOrderRef,
+					LimitOrder: &types.LimitOrder{
+						Price:           ins.NewPrice,
+						Size:            cr.SizeCancelled,
+						PersistenceType: original.PersistenceType,
+					},
+				}},
+			})
+			if err != nil {
+				return report, err
+			}
+			if len(place.InstructionReports) > 0 {
+				pr := place.InstructionReports[0]
+				ir.PlaceInstructionReport = &pr
+				ir.Status = pr.Status
+				ir.ErrorCode = pr.ErrorCode
+			}
+		}
+
+		if ir.Status != types.INSTRUCTION_SUCCESS {
+			report.Status = types.EXECUTION_FAILURE
+			report.ErrorCode = types.PROCESSED_WITH_ERRORS
+		}
+		report.InstructionReports = append(report.InstructionReports, ir)
+	}
+
+	return report, nil
+}
+
+// Matching is brought up to date for the markets involved before orders are listed
+func (e *Exchange) ListCurrentOrders(req types.ListCurrentOrdersRequest) (types.CurrentOrderSummaryReport, error) {
+	if err := e.Sync(); err != nil {
+		return types.CurrentOrderSummaryReport{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	betIds := toSet(req.BetIds)
+	marketIds := toSet(req.MarketIds)
+	orderRefs := toSet(req.CustomerOrderRefs)
+	strategyRefs := toSet(req.CustomerStrategyRefs)
+
+	var matching []types.CurrentOrderSummary
+	for _, betId := range e.sequence {
+		s := e.orders[betId].summary
+		switch {
+		case betIds != nil && !betIds[s.BetId],
+			marketIds != nil && !marketIds[s.MarketId],
+			orderRefs != nil && !orderRefs[s.CustomerOrderRef],
+			strategyRefs != nil && !strategyRefs[s.CustomerStrategyRef],
+			req.OrderProjection == types.EXECUTABLE && s.Status == types.ORDER_EXECUTION_COMPLETE,
+			req.OrderProjection == types.EXECUTION_COMPLETE && s.Status != types.ORDER_EXECUTION_COMPLETE:
+			continue
+		}
+		matching = append(matching, s)
+	}
+
+	from := min(req.FromRecord, len(matching))
+	to := len(matching)
+	if req.RecordCount > 0 {
+		to = min(from+req.RecordCount, len(matching))
+	}
+
+	return types.CurrentOrderSummaryReport{
+		CurrentOrders: matching[from:to],
+		MoreAvailable: to < len(matching),
+	}, nil
+}
+
+// Virtual balance, exposure is the worst case loss of open and matched orders in unsettled markets
+func (e *Exchange) GetAccountFunds(req types.GetAccountFundsRequest) (types.AccountFundsResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	exposure := e.exposureLocked()
+	return types.AccountFundsResponse{
+		AvailableToBetBalance: round2(e.balance - exposure),
+		Exposure:              -exposure,
+		Wallet:                req.Wallet,
+	}, nil
+}
+
+// Settled balance, excluding open positions
+func (e *Exchange) Balance() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.balance
+}
+
+func (e *Exchange) book(marketId string) (types.ListMarketBookResponse, error) {
+	books, err := e.cfg.Data.ListMarketBook(types.ListMarketBookRequest{
+		MarketIds: []string{marketId},
+		PriceProjection: types.PriceProjection{
+			PriceData: []types.PriceData{types.EX_ALL_OFFERS, types.EX_TRADED},
+		},
+	})
+	if err != nil {
+		return types.ListMarketBookResponse{}, fmt.Errorf("unable to get market book for %s: %w", marketId, err)
+	}
+	if len(books) == 0 {
+		return types.ListMarketBookResponse{}, fmt.Errorf("no market book for %s", marketId)
+	}
+
+	return books[0], nil
+}
+
+func (e *Exchange) availableLocked() float64 {
+	return e.balance - e.exposureLocked()
+}
+
+func (e *Exchange) exposureLocked() float64 {
+	var exposure float64
+	for _, o := range e.orders {
+		if e.settled[o.summary.MarketId] {
+			continue
+		}
+		s := o.summary
+		exposure += liability(s.Side, s.PriceSize.Price, s.SizeRemaining)
+		exposure += liability(s.Side, s.AveragePriceMatched, s.SizeMatched)
+	}
+	return exposure
+}
+
+// Worst case loss of a bet
+func liability(side types.Side, price, size float64) float64 {
+	if side == types.LAY {
+		return size * (price - 1)
+	}
+	return size
+}
+
+func (o *simOrder) open() bool {
+	return o.summary.Status != types.ORDER_EXECUTION_COMPLETE
+}
+
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,126 @@
+// sim/exchange_test.go
+
+package sim_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/oms"
+	"github.com/Bazcampbell/betfair-api-go-sdk/sim"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ oms.OrderAPI = (*sim.Exchange)(nil)
+
+type fakeData struct {
+	book types.ListMarketBookResponse
+}
+
+func (f *fakeData) ListMarketBook(req types.ListMarketBookRequest) ([]types.ListMarketBookResponse, error) {
+	return []types.ListMarketBookResponse{f.book}, nil
+}
+
+func newBook() types.ListMarketBookResponse {
+	return types.ListMarketBookResponse{
+		MarketId: "1.1",
+		Status:   types.OPEN,
+		BetDelay: 5,
+		Runners: []types.Runner{{
+			SelectionId: 10,
+			Status:      types.ACTIVE,
+			Ex: types.Ex{
+				Back:   []types.RunnerPrice{{Price: 3.0, Size: 4}, {Price: 2.98, Size: 10}},
+				Lay:    []types.RunnerPrice{{Price: 3.05, Size: 20}},
+				Traded: []types.RunnerPrice{{Price: 2.9, Size: 100}},
+			},
+		}},
+	}
+}
+
+func limit(side types.Side, price, size float64, persistence types.PersistenceType) types.PlaceOrdersRequest {
+	return types.PlaceOrdersRequest{
+		MarketId: "1.1",
+		Instructions: []types.PlaceInstruction{{
+			OrderType:   types.LIMIT,
+			SelectionId: 10,
+			Side:        side,
+			LimitOrder:  &types.LimitOrder{Price: price, Size: size, PersistenceType: persistence},
+		}},
+	}
+}
+
+func TestExchange_PartialFillRestingFillAndSettlement(t *testing.T) {
+	data := &fakeData{book: newBook()}
+	ex, err := sim.New(sim.Config{Data: data, Balance: 100})
+	require.NoError(t, err)
+
+	// Crosses the spread, takes 3.0 and 2.98 then rests the rest at 2.96
+	report, err := ex.PlaceOrders(limit(types.BACK, 2.96, 20, types.LAPSE))
+	require.NoError(t, err)
+	require.Equal(t, types.EXECUTION_SUCCESS, report.Status)
+	ir := report.InstructionReports[0]
+	assert.Equal(t, types.ORDER_EXECUTABLE, ir.OrderStatus)
+	assert.Equal(t, 14.0, ir.SizeMatched)
+	assert.InDelta(t, (3.0*4+2.98*10)/14, ir.AveragePriceMatched, 1e-9)
+
+	// Volume trading at 2.96 fills the resting remainder
+	data.book.Runners[0].Ex.Traded = append(data.book.Runners[0].Ex.Traded, types.RunnerPrice{Price: 2.96, Size: 10})
+	orders, err := ex.ListCurrentOrders(types.ListCurrentOrdersRequest{})
+	require.NoError(t, err)
+	require.Len(t, orders.CurrentOrders, 1)
+	assert.Equal(t, types.ORDER_EXECUTION_COMPLETE, orders.CurrentOrders[0].Status)
+	assert.Equal(t, 20.0, orders.CurrentOrders[0].SizeMatched)
+
+	funds, err := ex.GetAccountFunds(types.GetAccountFundsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 80.0, funds.AvailableToBetBalance)
+
+	data.book.Status = types.CLOSED
+	data.book.Runners[0].Status = types.WINNER
+	require.NoError(t, ex.Sync())
+
+	avg := orders.CurrentOrders[0].AveragePriceMatched
+	assert.InDelta(t, 100+20*(avg-1), ex.Balance(), 0.01)
+}
+
+func TestExchange_BetDelayAndLapseAtTheOff(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	data := &fakeData{book: newBook()}
+	ex, err := sim.New(sim.Config{Data: data, Now: func() time.Time { return now }})
+	require.NoError(t, err)
+
+	// Placed pre-off, lapses when the market turns in play
+	_, err = ex.PlaceOrders(limit(types.LAY, 2.5, 10, types.LAPSE))
+	require.NoError(t, err)
+
+	data.book.InPlay = true
+	report, err := ex.PlaceOrders(limit(types.BACK, 3.0, 2, types.LAPSE))
+	require.NoError(t, err)
+	assert.Equal(t, types.ORDER_PENDING, report.InstructionReports[0].OrderStatus)
+	assert.Zero(t, report.InstructionReports[0].SizeMatched)
+
+	now = now.Add(5 * time.Second)
+	orders, err := ex.ListCurrentOrders(types.ListCurrentOrdersRequest{})
+	require.NoError(t, err)
+	require.Len(t, orders.CurrentOrders, 2)
+
+	assert.Equal(t, 10.0, orders.CurrentOrders[0].SizeLapsed)
+	assert.Equal(t, types.ORDER_EXECUTION_COMPLETE, orders.CurrentOrders[0].Status)
+	assert.Equal(t, 2.0, orders.CurrentOrders[1].SizeMatched)
+}
+
+func TestExchange_InsufficientFunds(t *testing.T) {
+	ex, err := sim.New(sim.Config{Data: &fakeData{book: newBook()}, Balance: 10})
+	require.NoError(t, err)
+
+	report, err := ex.PlaceOrders(limit(types.LAY, 5.0, 5, types.LAPSE))
+	require.NoError(t, err)
+	assert.Equal(t, types.EXECUTION_FAILURE, report.Status)
+	assert.Equal(t, types.INSUFFICIENT_FUNDS, report.ErrorCode)
+	require.Len(t, report.InstructionReports, 1)
+	assert.Equal(t, types.INSTRUCTION_INSUFFICIENT_FUNDS, report.InstructionReports[0].ErrorCode)
+}
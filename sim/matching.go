@@ -0,0 +1,266 @@
+// sim/matching.go
+
+package sim
+
+// Fill model
+// Orders that cross the spread take the liquidity on offer at their price or better, best price first.
+// Resting orders fill at their own price as traded volume at that price grows.
+// Queue position is not modelled: a resting order is assumed to be first in line, so any new volume traded
+// at its price fills it, even though the money queued there before it would match first on the real exchange.
+// Fills of resting orders are therefore optimistic, more so at prices with a lot of money waiting.
+// Unmatched LAPSE orders lapse at the off, orders on a removed runner lapse, everything unmatched lapses at close.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+// Fetches books for every market with open or unsettled orders and progresses matching on them
+func (e *Exchange) Sync() error {
+	e.mu.Lock()
+	var marketIds []string
+	seen := make(map[string]bool)
+	for _, betId := range e.sequence {
+		marketId := e.orders[betId].summary.MarketId
+		if !seen[marketId] && !e.settled[marketId] {
+			seen[marketId] = true
+			marketIds = append(marketIds, marketId)
+		}
+	}
+	e.mu.Unlock()
+
+	if len(marketIds) == 0 {
+		return nil
+	}
+
+	books, err := e.cfg.Data.ListMarketBook(types.ListMarketBookRequest{
+		MarketIds: marketIds,
+		PriceProjection: types.PriceProjection{
+			PriceData: []types.PriceData{types.EX_ALL_OFFERS, types.EX_TRADED},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to sync simulated orders: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, book := range books {
+		e.match(book)
+	}
+
+	return nil
+}
+
+// Syncs every interval until ctx is done, onError gets failed syncs and may be nil
+func (e *Exchange) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Sync(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Must hold e.mu
+func (e *Exchange) match(book types.ListMarketBookResponse) {
+	if e.settled[book.MarketId] {
+		return
+	}
+
+	now := e.cfg.Now()
+	stamp := now.UTC().Format(time.RFC3339Nano)
+
+	var orders []*simOrder
+	for _, betId := range e.sequence {
+		o := e.orders[betId]
+		if o.summary.MarketId != book.MarketId {
+			continue
+		}
+		orders = append(orders, o)
+		if o.summary.Status == types.ORDER_PENDING && !now.Before(o.activeAt) {
+			o.summary.Status = types.ORDER_EXECUTABLE
+		}
+	}
+
+	if book.Status == types.CLOSED {
+		for _, o := range orders {
+			o.lapse()
+		}
+		e.settle(book, orders)
+		return
+	}
+
+	runners := make(map[runnerKey]types.Runner, len(book.Runners))
+	for _, r := range book.Runners {
+		runners[keyOf(r)] = r
+	}
+
+	for _, o := range orders {
+		if !o.open() {
+			continue
+		}
+		r, ok := runners[runnerKey{o.summary.SelectionId, o.summary.Handicap}]
+		switch {
+		case ok && (r.Status == types.REMOVED || r.Status == types.REMOVED_VACANT):
+			o.lapse()
+		case book.InPlay && !o.placedInPlay && o.summary.PersistenceType != types.PERSIST:
+			o.lapse()
+		}
+	}
+
+	if book.Status != types.OPEN {
+		e.recordTraded(book)
+		return
+	}
+
+	// Liquidity taken earlier in this pass is not available to later orders
+	taken := make(map[tradeKey]float64)
+
+	for _, o := range orders {
+		if o.summary.Status != types.ORDER_EXECUTABLE {
+			continue
+		}
+		r, ok := runners[runnerKey{o.summary.SelectionId, o.summary.Handicap}]
+		if !ok {
+			continue
+		}
+
+		levels := r.Ex.Back
+		crosses := func(p float64) bool { return p >= o.summary.PriceSize.Price }
+		if o.summary.Side == types.LAY {
+			levels = r.Ex.Lay
+			crosses = func(p float64) bool { return p <= o.summary.PriceSize.Price }
+		}
+
+		for _, level := range levels {
+			if o.summary.SizeRemaining == 0 {
+				break
+			}
+			price := round2(float64(level.Price))
+			if !crosses(price) {
+				break
+			}
+			key := tradeKey{book.MarketId, o.summary.SelectionId, o.summary.Handicap, price}
+			available := round2(float64(level.Size) - taken[key])
+			if available <= 0 {
+				continue
+			}
+			size := min(available, o.summary.SizeRemaining)
+			taken[key] += size
+			o.fill(price, size, stamp)
+		}
+	}
+
+	// Resting orders fill from new volume traded at their price, first placed first filled
+	for _, r := range book.Runners {
+		for _, level := range r.Ex.Traded {
+			price := round2(float64(level.Price))
+			key := tradeKey{book.MarketId, int64(r.SelectionId), float64(r.Handicap), price}
+			previous, ok := e.traded[key]
+			if !ok && !e.seen[book.MarketId] {
+				continue
+			}
+			volume := round2(float64(level.Size) - previous)
+
+			for _, o := range orders {
+				if volume <= 0 {
+					break
+				}
+				s := o.summary
+				if s.Status != types.ORDER_EXECUTABLE || s.PriceSize.Price != price ||
+					s.SelectionId != key.selectionId || s.Handicap != key.handicap {
+					continue
+				}
+				size := min(volume, s.SizeRemaining)
+				volume = round2(volume - size)
+				o.fill(price, size, stamp)
+			}
+		}
+	}
+
+	e.recordTraded(book)
+}
+
+type runnerKey struct {
+	selectionId int64
+	handicap    float64
+}
+
+func keyOf(r types.Runner) runnerKey {
+	return runnerKey{int64(r.SelectionId), float64(r.Handicap)}
+}
+
+func (e *Exchange) recordTraded(book types.ListMarketBookResponse) {
+	for _, r := range book.Runners {
+		for _, level := range r.Ex.Traded {
+			key := tradeKey{book.MarketId, int64(r.SelectionId), float64(r.Handicap), round2(float64(level.Price))}
+			e.traded[key] = float64(level.Size)
+		}
+	}
+	e.seen[book.MarketId] = true
+}
+
+// Pays out matched bets on winners and losers, bets on any other runner are void
+func (e *Exchange) settle(book types.ListMarketBookResponse, orders []*simOrder) {
+	status := make(map[runnerKey]types.RunnerStatus, len(book.Runners))
+	for _, r := range book.Runners {
+		status[keyOf(r)] = r.Status
+	}
+
+	for _, o := range orders {
+		s := &o.summary
+		if s.SizeMatched == 0 {
+			continue
+		}
+
+		// Back profit if the runner wins, lay profit if it loses
+		ifWins := s.SizeMatched * (s.AveragePriceMatched - 1)
+		ifLoses := -s.SizeMatched
+		if s.Side == types.LAY {
+			ifWins, ifLoses = -ifWins, -ifLoses
+		}
+
+		switch status[runnerKey{s.SelectionId, s.Handicap}] {
+		case types.WINNER:
+			e.balance = round2(e.balance + ifWins)
+		case types.LOSER:
+			e.balance = round2(e.balance + ifLoses)
+		default:
+			s.SizeVoided = s.SizeMatched
+		}
+	}
+
+	e.settled[book.MarketId] = true
+}
+
+func (o *simOrder) fill(price, size float64, stamp string) {
+	s := &o.summary
+	s.AveragePriceMatched = (s.AveragePriceMatched*s.SizeMatched + price*size) / (s.SizeMatched + size)
+	s.SizeMatched = round2(s.SizeMatched + size)
+	s.SizeRemaining = round2(s.SizeRemaining - size)
+	s.MatchedDate = stamp
+	if s.SizeRemaining == 0 {
+		s.Status = types.ORDER_EXECUTION_COMPLETE
+	}
+}
+
+func (o *simOrder) lapse() {
+	if !o.open() {
+		return
+	}
+	s := &o.summary
+	s.SizeLapsed = round2(s.SizeLapsed + s.SizeRemaining)
+	s.SizeRemaining = 0
+	s.Status = types.ORDER_EXECUTION_COMPLETE
+}
@@ -0,0 +1,553 @@
+// stream/client.go
+
+package stream
+
+// Subscription management on top of a single Conn.
+// Keeps the market and order caches up to date from incoming change messages
+// and reconnects when the connection drops (see resume.go).
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+// Settings of a Client, Config applies to each connection it opens
+type ClientConfig struct {
+	Config
+
+	// Called from the read loop for every change to one of our orders
+	OnOrderEvent func(OrderEvent)
+
+	// Background failures such as dropped connections
+	OnError func(error)
+
+	// 0 retries forever
+	MaxReconnectAttempts int
+
+	// Reported through OnError when exceeded, 0 disables
+	LatencyThreshold time.Duration
+
+	// Silence after which the connection is considered dead
+	// Defaults to three heartbeat intervals
+	HeartbeatTimeout time.Duration
+
+	// Closed markets are evicted from the market cache unless set
+	KeepClosedMarkets bool
+
+	// Called with the final book of each market as it closes
+	OnMarketClosed func(types.ListMarketBookResponse)
+
+	// How long complete orders stay in the order cache
+	// Defaults to 1 minute, negative keeps them until the market is removed
+	OrderRetention time.Duration
+}
+
+type Client struct {
+	cfg  ClientConfig
+	auth Authenticator
+
+	mu   sync.Mutex
+	conn *Conn
+
+	markets    *MarketCache
+	orders     *OrderCache
+	dispatcher *Dispatcher
+
+	marketSub *marketSubscription
+	orderSub  *orderSubscription
+
+	// Markets received on the market subscription, the part of the cache this client owns
+	owned map[string]struct{}
+	// Subscription sent by UpdateMarketSubscription, it replaces marketSub once Betfair answers it
+	pending *marketUpdate
+	// Cache and dispatcher belong to a Pool and outlive the client
+	shared bool
+
+	stats statsTracker
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started atomic.Bool
+	closed  atomic.Bool
+	gaveUp  atomic.Bool // stopped reconnecting, see ClientConfig.MaxReconnectAttempts
+}
+
+type marketSubscription struct {
+	filter     MarketFilter
+	dataFilter MarketDataFilter
+	opts       subscriptionOptions
+	clocks     clocks
+	requestId  int64 // id of the last request sending this subscription
+}
+
+// A market subscription waiting for its first image or delta
+type marketUpdate struct {
+	sub   *marketSubscription
+	after int64               // last request id on the connection before it was sent, its answer has a later one
+	keep  map[string]struct{} // markets it keeps when resumed from clocks, nil when sent for a full image
+
+	answered chan map[string]struct{} // markets held once the answer is applied
+}
+
+type orderSubscription struct {
+	filter OrderFilter
+	opts   subscriptionOptions
+	clocks clocks
+}
+
+func NewClient(cfg ClientConfig, auth Authenticator) *Client {
+	markets := newMarketCache(cfg)
+	return newClient(cfg, auth, markets, NewDispatcher(markets))
+}
+
+func newMarketCache(cfg ClientConfig) *MarketCache {
+	if cfg.KeepClosedMarkets {
+		return NewMarketCache()
+	}
+	return NewMarketCache(WithClosedMarketEviction(cfg.OnMarketClosed))
+}
+
+func newClient(cfg ClientConfig, auth Authenticator, markets *MarketCache, dispatcher *Dispatcher) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	var orderOpts []OrderCacheOption
+	if cfg.OrderRetention != 0 {
+		orderOpts = append(orderOpts, WithOrderRetention(cfg.OrderRetention))
+	}
+
+	return &Client{
+		cfg:        cfg,
+		auth:       auth,
+		markets:    markets,
+		orders:     NewOrderCache(cfg.OnOrderEvent, orderOpts...),
+		dispatcher: dispatcher,
+		owned:      make(map[string]struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Opens and authenticates the underlying connection
+// Once connected the client reconnects and resubscribes by itself until Close
+func (c *Client) Connect(ctx context.Context) error {
+	if c.closed.Load() {
+		return fmt.Errorf("stream client closed")
+	}
+
+	c.mu.Lock()
+	if c.conn != nil && c.conn.Err() == nil {
+		c.mu.Unlock()
+		return fmt.Errorf("stream client already connected")
+	}
+	c.mu.Unlock()
+
+	if err := c.dial(ctx); err != nil {
+		return err
+	}
+
+	if c.started.CompareAndSwap(false, true) {
+		c.wg.Add(2)
+		go c.supervise()
+		go c.monitor()
+	}
+
+	return nil
+}
+
+func (c *Client) dial(ctx context.Context) error {
+	cfg := c.cfg.Config
+	segments := newSegmentBuffer()
+	cfg.OnChange = func(msg *ResponseMessage) {
+		if err := c.stats.record(msg, c.cfg.LatencyThreshold); err != nil {
+			c.onError(err)
+		}
+
+		if complete := segments.add(msg); complete != nil {
+			c.handleChange(complete)
+		}
+	}
+
+	conn, err := Dial(ctx, cfg, c.auth)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *Client) currentConn() (*Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil, fmt.Errorf("stream client not connected")
+	}
+
+	return c.conn, nil
+}
+
+// Subscribes to markets matching filter
+// Betfair replaces any previous market subscription on the connection
+func (c *Client) SubscribeMarkets(ctx context.Context, filter MarketFilter, dataFilter MarketDataFilter, opts ...SubscriptionOption) error {
+	conn, err := c.currentConn()
+	if err != nil {
+		return err
+	}
+
+	// Record the subscription before sending so clocks from the initial image are not lost
+	sub := &marketSubscription{filter: filter, dataFilter: dataFilter, opts: newSubscriptionOptions(opts)}
+
+	c.mu.Lock()
+	previous := c.marketSub
+	c.marketSub = sub
+	c.pending = nil
+	c.mu.Unlock()
+
+	if err := c.sendMarketSubscription(ctx, conn, sub); err != nil {
+		c.mu.Lock()
+		c.marketSub = previous
+		c.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// Markets added and dropped by UpdateMarketSubscription
+type SubscriptionDiff struct {
+	Added   []string
+	Removed []string
+}
+
+// Changes the market filter of the live subscription without reconnecting
+// A filter of market ids only is sent with the current clocks, so Betfair resumes the markets already
+// held and only sends images of the new ones. Any other filter, or clocks Betfair no longer accepts,
+// gets a full image of the new set. Until the answer arrives the previous subscription stays current,
+// markets the new filter drops are evicted from the cache once it does.
+func (c *Client) UpdateMarketSubscription(ctx context.Context, filter MarketFilter) (SubscriptionDiff, error) {
+	conn, err := c.currentConn()
+	if err != nil {
+		return SubscriptionDiff{}, err
+	}
+
+	c.mu.Lock()
+	previous := c.marketSub
+	if previous == nil {
+		c.mu.Unlock()
+		return SubscriptionDiff{}, fmt.Errorf("no market subscription to update")
+	}
+
+	before := make(map[string]struct{}, len(c.owned))
+	for id := range c.owned {
+		before[id] = struct{}{}
+	}
+
+	sub := &marketSubscription{filter: filter, dataFilter: previous.dataFilter, opts: previous.opts}
+	update := &marketUpdate{sub: sub, after: conn.nextId.Load(), answered: make(chan map[string]struct{}, 1)}
+	if onlyMarketIds(filter) {
+		sub.clocks = previous.clocks
+		update.keep = make(map[string]struct{}, len(filter.MarketIds))
+		for _, id := range filter.MarketIds {
+			update.keep[id] = struct{}{}
+		}
+	}
+	c.pending = update
+	c.mu.Unlock()
+
+	err = c.sendMarketSubscription(ctx, conn, sub)
+	if IsErrorCode(err, INVALID_CLOCK) {
+		c.mu.Lock()
+		sub.clocks.reset()
+		c.mu.Unlock()
+		err = c.sendMarketSubscription(ctx, conn, sub)
+	}
+	if err != nil {
+		c.mu.Lock()
+		if c.pending == update {
+			c.pending = nil
+		}
+		c.mu.Unlock()
+		return SubscriptionDiff{}, err
+	}
+
+	// Past this point Betfair has accepted the subscription, a late answer still switches over to it
+	timer := time.NewTimer(c.cfg.RequestTimeout)
+	defer timer.Stop()
+
+	var after map[string]struct{}
+	select {
+	case after = <-update.answered:
+	case <-conn.Done():
+		return SubscriptionDiff{}, fmt.Errorf("connection lost waiting for market image: %w", conn.Err())
+	case <-c.ctx.Done():
+		return SubscriptionDiff{}, fmt.Errorf("stream client closed waiting for market image")
+	case <-timer.C:
+		return SubscriptionDiff{}, fmt.Errorf("timeout waiting for market image")
+	case <-ctx.Done():
+		return SubscriptionDiff{}, fmt.Errorf("waiting for market image: %w", ctx.Err())
+	}
+
+	// The answer has already updated the cache, only listener state is left to drop
+	diff := diffMarkets(before, after)
+	for _, id := range diff.Removed {
+		c.dispatcher.forget(id)
+	}
+
+	return diff, nil
+}
+
+func onlyMarketIds(filter MarketFilter) bool {
+	return len(filter.MarketIds) > 0 &&
+		filter.BspMarket == nil && len(filter.BettingTypes) == 0 && len(filter.EventTypeIds) == 0 &&
+		len(filter.EventIds) == 0 && filter.TurnInPlayEnabled == nil && len(filter.MarketTypes) == 0 &&
+		len(filter.Venues) == 0 && len(filter.CountryCodes) == 0 && len(filter.RaceTypes) == 0
+}
+
+func diffMarkets(before, after map[string]struct{}) SubscriptionDiff {
+	var diff SubscriptionDiff
+	for id := range after {
+		if _, ok := before[id]; !ok {
+			diff.Added = append(diff.Added, id)
+		}
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	return diff
+}
+
+// Subscribes to changes to our own orders
+// Betfair replaces any previous order subscription on the connection
+func (c *Client) SubscribeOrders(ctx context.Context, filter OrderFilter, opts ...SubscriptionOption) error {
+	conn, err := c.currentConn()
+	if err != nil {
+		return err
+	}
+
+	sub := &orderSubscription{filter: filter, opts: newSubscriptionOptions(opts)}
+
+	c.mu.Lock()
+	previous := c.orderSub
+	c.orderSub = sub
+	c.mu.Unlock()
+
+	if err := c.sendOrderSubscription(ctx, conn, sub); err != nil {
+		c.mu.Lock()
+		c.orderSub = previous
+		c.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) sendMarketSubscription(ctx context.Context, conn *Conn, sub *marketSubscription) error {
+	c.mu.Lock()
+	initialClk, clk := sub.clocks.get()
+	sub.requestId = 0 // ids start again on a new connection
+	c.mu.Unlock()
+
+	filter, dataFilter := sub.filter, sub.dataFilter
+	msg := &RequestMessage{
+		Op:               OP_MARKET_SUB,
+		MarketFilter:     &filter,
+		MarketDataFilter: &dataFilter,
+		InitialClk:       initialClk,
+		Clk:              clk,
+		ConflateMs:       sub.opts.conflateMs,
+		HeartbeatMs:      sub.opts.heartbeatMs,
+	}
+
+	if _, err := conn.Request(ctx, msg); err != nil {
+		return fmt.Errorf("market subscription failed: %w", err)
+	}
+
+	c.mu.Lock()
+	sub.requestId = msg.Id
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *Client) sendOrderSubscription(ctx context.Context, conn *Conn, sub *orderSubscription) error {
+	c.mu.Lock()
+	initialClk, clk := sub.clocks.get()
+	c.mu.Unlock()
+
+	filter := sub.filter
+	msg := &RequestMessage{
+		Op:          OP_ORDER_SUB,
+		OrderFilter: &filter,
+		InitialClk:  initialClk,
+		Clk:         clk,
+		ConflateMs:  sub.opts.conflateMs,
+		HeartbeatMs: sub.opts.heartbeatMs,
+	}
+
+	if _, err := conn.Request(ctx, msg); err != nil {
+		return fmt.Errorf("order subscription failed: %w", err)
+	}
+
+	return nil
+}
+
+// Segmented messages have already been merged, so images are applied in one step
+func (c *Client) handleChange(msg *ResponseMessage) {
+	switch msg.Op {
+	case OP_MCM:
+		c.mu.Lock()
+		// The first image or delta answering UpdateMarketSubscription makes its subscription current
+		var update *marketUpdate
+		if p := c.pending; p != nil && msg.Id > p.after && (msg.Ct == CT_SUB_IMAGE || msg.Ct == CT_RESUB_DELTA) {
+			c.marketSub, c.pending = p.sub, nil
+			p.sub.requestId = msg.Id
+			update = p
+		}
+
+		// Clocks still in flight for an earlier subscription would not resume this one
+		if sub := c.marketSub; sub != nil && (msg.Id == 0 || msg.Id >= sub.requestId) {
+			sub.clocks.update(msg)
+		}
+
+		// A full image replaces everything this client owns, including markets that left the subscription,
+		// a resumed update only drops the markets it no longer keeps
+		var dropped []string
+		for id := range c.owned {
+			if msg.Ct == CT_SUB_IMAGE || (update != nil && !contains(update.keep, id)) {
+				dropped = append(dropped, id)
+				delete(c.owned, id)
+			}
+		}
+		for _, mc := range msg.Mc {
+			if def := mc.MarketDefinition; def != nil && isClosed(def) && !c.cfg.KeepClosedMarkets {
+				delete(c.owned, mc.Id)
+				continue
+			}
+			c.owned[mc.Id] = struct{}{}
+		}
+
+		// Markets that left the subscription must not carry their transitions into a later one
+		var gone []string
+		for _, id := range dropped {
+			if !contains(c.owned, id) {
+				gone = append(gone, id)
+			}
+		}
+
+		var held map[string]struct{}
+		if update != nil {
+			held = make(map[string]struct{}, len(c.owned))
+			for id := range c.owned {
+				held[id] = struct{}{}
+			}
+		}
+		c.mu.Unlock()
+
+		if len(dropped) > 0 || msg.Ct == CT_SUB_IMAGE {
+			c.markets.Replace(dropped, msg)
+		} else {
+			c.markets.ApplyMessage(msg)
+		}
+		c.dispatcher.Handle(msg)
+		for _, id := range gone {
+			c.dispatcher.forget(id)
+		}
+
+		if update != nil {
+			update.answered <- held
+		}
+
+	case OP_OCM:
+		c.mu.Lock()
+		if c.orderSub != nil {
+			c.orderSub.clocks.update(msg)
+		}
+		c.mu.Unlock()
+
+		// A full image replaces every order, orders that finished during a gap drop out of it
+		if msg.Ct == CT_SUB_IMAGE {
+			c.orders.ReplaceAll(msg)
+		} else {
+			c.orders.ApplyMessage(msg)
+		}
+	}
+
+	if c.cfg.OnChange != nil {
+		c.cfg.OnChange(msg)
+	}
+}
+
+func contains(set map[string]struct{}, id string) bool {
+	_, ok := set[id]
+	return ok
+}
+
+// Market cache fed by the market subscription
+func (c *Client) Markets() *MarketCache {
+	return c.markets
+}
+
+// Order cache fed by the order subscription
+func (c *Client) Orders() *OrderCache {
+	return c.orders
+}
+
+// Delivers typed events for a single market of the market subscription
+func (c *Client) Listen(marketId string, opts ListenerOptions) *Listener {
+	return c.dispatcher.Listen(marketId, opts)
+}
+
+// Reports whether the client currently has a live connection
+func (c *Client) Connected() bool {
+	conn, err := c.currentConn()
+	return err == nil && conn.Err() == nil
+}
+
+func (c *Client) onError(err error) {
+	if c.cfg.OnError != nil {
+		c.cfg.OnError(err)
+	}
+}
+
+// Stops reconnecting and closes the connection
+func (c *Client) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return fmt.Errorf("stream client already closed")
+	}
+
+	c.cancel()
+
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+
+	c.wg.Wait()
+	if !c.shared {
+		c.dispatcher.CloseAll()
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+// stream/conn.go
+
+package stream
+
+// Single authenticated connection to the Exchange Stream API.
+// Handles framing, request/response correlation and connection level status.
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const STREAM_ADDR = "stream-api.betfair.com:443"
+
+const (
+	defaultDialTimeout    = 10 * time.Second
+	defaultRequestTimeout = 15 * time.Second
+	defaultWriteTimeout   = 10 * time.Second
+)
+
+// Supplies credentials for the authentication message
+// *client.BetfairClient satisfies this
+type Authenticator interface {
+	AppKey() string
+	SessionToken() (string, error)
+}
+
+// Settings of a single connection, see ClientConfig for the Client on top of it
+type Config struct {
+	Addr           string      // defaults to STREAM_ADDR
+	TLSConfig      *tls.Config // optional, defaults to system roots
+	DisableTLS     bool        // plain TCP, only useful against local test servers
+	DialTimeout    time.Duration
+	RequestTimeout time.Duration
+	WriteTimeout   time.Duration // a write blocked for longer fails the connection, defaults to 10 seconds
+
+	// Called from the read loop for every mcm/ocm message, must not block for long
+	OnChange func(*ResponseMessage)
+
+	// Called from the read loop with every raw message as received, without the CRLF
+	// The slice is not reused, so it can be retained, e.g. Recorder.Record
+	OnRawMessage func(data []byte, receivedAt time.Time)
+
+	// Clock for receive times, latency and heartbeat checks, defaults to time.Now
+	Now func() time.Time
+}
+
+type Conn struct {
+	cfg          Config
+	conn         net.Conn
+	reader       *bufio.Reader
+	connectionId string
+
+	writeMu sync.Mutex
+	nextId  atomic.Int64
+
+	pendingMu sync.Mutex
+	pending   map[int64]chan *ResponseMessage
+
+	closed    atomic.Bool
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// Opens a connection, waits for the connection message and authenticates
+func Dial(ctx context.Context, cfg Config, auth Authenticator) (*Conn, error) {
+	if cfg.Addr == "" {
+		cfg.Addr = STREAM_ADDR
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = defaultDialTimeout
+	}
+	if cfg.RequestTimeout == 0 {
+		cfg.RequestTimeout = defaultRequestTimeout
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = defaultWriteTimeout
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout}
+
+	var netConn net.Conn
+	var err error
+	if cfg.DisableTLS {
+		netConn, err = dialer.DialContext(ctx, "tcp", cfg.Addr)
+	} else {
+		tlsConfig := cfg.TLSConfig
+		if tlsConfig == nil {
+			host, _, splitErr := net.SplitHostPort(cfg.Addr)
+			if splitErr != nil {
+				return nil, fmt.Errorf("invalid stream address %s: %w", cfg.Addr, splitErr)
+			}
+			tlsConfig = &tls.Config{ServerName: host}
+		}
+
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsConfig}
+		netConn, err = tlsDialer.DialContext(ctx, "tcp", cfg.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to stream: %w", err)
+	}
+
+	c := &Conn{
+		cfg:     cfg,
+		conn:    netConn,
+		reader:  bufio.NewReaderSize(netConn, 64*1024),
+		pending: make(map[int64]chan *ResponseMessage),
+		done:    make(chan struct{}),
+	}
+
+	// Betfair sends the connection message before anything else
+	netConn.SetReadDeadline(time.Now().Add(cfg.RequestTimeout))
+	first, err := c.readMessage()
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("unable to read connection message: %w", err)
+	}
+	netConn.SetReadDeadline(time.Time{})
+
+	if first.Op != OP_CONNECTION {
+		netConn.Close()
+		if first.Op == OP_STATUS && first.StatusCode == STATUS_FAILURE {
+			return nil, statusError(first, "")
+		}
+		return nil, fmt.Errorf("expected connection message, got %s", first.Op)
+	}
+	c.connectionId = first.ConnectionId
+
+	go c.readLoop()
+
+	if err := c.authenticate(ctx, auth); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Conn) authenticate(ctx context.Context, auth Authenticator) error {
+	token, err := auth.SessionToken()
+	if err != nil {
+		return fmt.Errorf("unable to get session token: %w", err)
+	}
+
+	_, err = c.Request(ctx, &RequestMessage{
+		Op:      OP_AUTHENTICATION,
+		AppKey:  auth.AppKey(),
+		Session: token,
+	})
+	if err != nil {
+		return fmt.Errorf("stream authentication failed: %w", err)
+	}
+
+	return nil
+}
+
+// Sends a request and waits for its status reply
+// A FAILURE status is returned as *StatusError
+func (c *Conn) Request(ctx context.Context, msg *RequestMessage) (*ResponseMessage, error) {
+	id := c.nextId.Add(1)
+	msg.Id = id
+
+	reply := make(chan *ResponseMessage, 1)
+	c.pendingMu.Lock()
+	c.pending[id] = reply
+	c.pendingMu.Unlock()
+
+	defer func() {
+		c.pendingMu.Lock()
+		delete(c.pending, id)
+		c.pendingMu.Unlock()
+	}()
+
+	if err := c.send(msg); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(c.cfg.RequestTimeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-reply:
+		if resp.StatusCode == STATUS_FAILURE {
+			return resp, statusError(resp, c.connectionId)
+		}
+		return resp, nil
+	case <-c.done:
+		return nil, c.Err()
+	case <-timer.C:
+		return nil, fmt.Errorf("timeout waiting for %s response (id %d)", msg.Op, id)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Conn) Heartbeat(ctx context.Context) error {
+	_, err := c.Request(ctx, &RequestMessage{Op: OP_HEARTBEAT})
+	return err
+}
+
+func (c *Conn) send(msg *RequestMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s message: %w", msg.Op, err)
+	}
+	data = append(data, '\r', '\n')
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed.Load() {
+		return ErrConnClosed
+	}
+
+	// A peer that stops reading must not block the writer, and everyone queued on writeMu, forever
+	c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+	if _, err := c.conn.Write(data); err != nil {
+		c.fail(fmt.Errorf("unable to write %s message: %w", msg.Op, err))
+		return c.Err()
+	}
+
+	return nil
+}
+
+func (c *Conn) readMessage() (*ResponseMessage, error) {
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	line = bytes.TrimRight(line, "\r\n")
+	receivedAt := c.cfg.Now()
+
+	if c.cfg.OnRawMessage != nil {
+		c.cfg.OnRawMessage(line, receivedAt)
+	}
+
+	msg := ResponseMessage{ReceivedAt: receivedAt}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return nil, fmt.Errorf("unable to parse stream message: %w", err)
+	}
+
+	return &msg, nil
+}
+
+func (c *Conn) readLoop() {
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		switch {
+		case msg.IsChange():
+			if c.cfg.OnChange != nil {
+				c.cfg.OnChange(msg)
+			}
+
+		case msg.Op == OP_STATUS:
+			if msg.Id != 0 && c.deliver(msg) {
+				if msg.ConnectionClosed {
+					c.fail(statusError(msg, c.connectionId))
+					return
+				}
+				continue
+			}
+
+			// Unsolicited status, Betfair is about to drop the connection
+			if msg.StatusCode == STATUS_FAILURE || msg.ConnectionClosed {
+				c.fail(statusError(msg, c.connectionId))
+				return
+			}
+		}
+	}
+}
+
+func (c *Conn) deliver(msg *ResponseMessage) bool {
+	c.pendingMu.Lock()
+	reply, ok := c.pending[msg.Id]
+	c.pendingMu.Unlock()
+
+	if ok {
+		reply <- msg
+	}
+	return ok
+}
+
+func (c *Conn) fail(err error) {
+	c.closeOnce.Do(func() {
+		// Read errors caused by our own Close are not failures
+		if c.closed.Load() {
+			err = ErrConnClosed
+		}
+		c.err = err
+		c.closed.Store(true)
+		c.conn.Close()
+		close(c.done)
+	})
+}
+
+func (c *Conn) ConnectionId() string {
+	return c.connectionId
+}
+
+// Closed when the connection terminates for any reason
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Reason the connection terminated, nil while it is open
+func (c *Conn) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+func (c *Conn) Close() error {
+	c.closed.Store(true)
+	c.fail(ErrConnClosed)
+	return nil
+}
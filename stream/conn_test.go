@@ -0,0 +1,304 @@
+// stream/conn_test.go
+
+package stream_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream/streamtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dial(t *testing.T, srv *streamtest.Server, cfg stream.Config) (*stream.Conn, *streamtest.ServerConn) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := stream.Dial(ctx, cfg, streamtest.DefaultCredentials())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	server, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	return conn, server
+}
+
+func TestDial_Authenticates(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		start func() (*streamtest.Server, error)
+	}{
+		{"tls", streamtest.NewServer},
+		{"plain", streamtest.NewPlainServer},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, err := tc.start()
+			require.NoError(t, err)
+			defer srv.Close()
+
+			conn, server := dial(t, srv, srv.Config())
+			assert.Equal(t, server.ConnectionId(), conn.ConnectionId())
+
+			requests := server.Requests()
+			require.Len(t, requests, 1)
+			assert.Equal(t, stream.OP_AUTHENTICATION, requests[0].Op)
+			assert.Equal(t, streamtest.APP_KEY, requests[0].AppKey)
+			assert.Equal(t, streamtest.SESSION_TOKEN, requests[0].Session)
+			assert.NotZero(t, requests[0].Id)
+
+			require.NoError(t, conn.Heartbeat(context.Background()))
+			assert.NoError(t, conn.Err())
+		})
+	}
+}
+
+func TestDial_AuthFailure(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = stream.Dial(ctx, srv.Config(), streamtest.Credentials{Key: "wrong", Token: streamtest.SESSION_TOKEN})
+	require.Error(t, err)
+
+	var statusErr *stream.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, stream.INVALID_SESSION_INFORMATION, statusErr.Code)
+	assert.True(t, statusErr.ConnectionClosed)
+	assert.NotEmpty(t, statusErr.ConnectionId)
+	assert.True(t, stream.IsErrorCode(err, stream.INVALID_SESSION_INFORMATION))
+}
+
+func TestConn_FailedRequestKeepsConnection(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	conn, _ := dial(t, srv, srv.Config())
+
+	srv.FailNext(stream.OP_HEARTBEAT, stream.TOO_MANY_REQUESTS)
+	err = conn.Heartbeat(context.Background())
+	assert.True(t, stream.IsErrorCode(err, stream.TOO_MANY_REQUESTS))
+
+	require.NoError(t, conn.Heartbeat(context.Background()))
+	assert.NoError(t, conn.Err())
+}
+
+func TestConn_UnsolicitedFailureClosesConnection(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	conn, server := dial(t, srv, srv.Config())
+	server.SendFailure(stream.MAX_CONNECTION_LIMIT_EXCEEDED, "too many connections")
+
+	select {
+	case <-conn.Done():
+	case <-time.After(time.Second):
+		t.Fatal("connection still open")
+	}
+
+	assert.True(t, stream.IsErrorCode(conn.Err(), stream.MAX_CONNECTION_LIMIT_EXCEEDED))
+	assert.ErrorIs(t, conn.Heartbeat(context.Background()), stream.ErrConnClosed)
+}
+
+func TestConn_MalformedFrameClosesConnection(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	conn, server := dial(t, srv, srv.Config())
+	require.NoError(t, server.SendRaw([]byte(`{"op":"mcm","mc":[`)))
+
+	select {
+	case <-conn.Done():
+	case <-time.After(time.Second):
+		t.Fatal("connection still open")
+	}
+
+	assert.ErrorContains(t, conn.Err(), "unable to parse stream message")
+}
+
+func TestConn_CloseIsNotAFailure(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	conn, _ := dial(t, srv, srv.Config())
+	assert.NoError(t, conn.Err())
+
+	require.NoError(t, conn.Close())
+	<-conn.Done()
+	assert.ErrorIs(t, conn.Err(), stream.ErrConnClosed)
+}
+
+// Hand written server, so the exact bytes on the wire can be checked
+func rawServer(t *testing.T, serve func(net.Conn, *bufio.Reader)) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		ln.Close()
+		wg.Wait()
+	})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		netConn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer netConn.Close()
+
+		serve(netConn, bufio.NewReader(netConn))
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestConn_CRLFFraming(t *testing.T) {
+	lines := make(chan string, 4)
+
+	addr := rawServer(t, func(netConn net.Conn, reader *bufio.Reader) {
+		// Connection message split across writes
+		netConn.Write([]byte(`{"op":"connec`))
+		time.Sleep(10 * time.Millisecond)
+		netConn.Write([]byte("tion\",\"connectionId\":\"raw-1\"}\r\n"))
+
+		for id := 1; id <= 2; id++ {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines <- line
+
+			// Reply and an unrelated message in the same write
+			fmt.Fprintf(netConn, "{\"op\":\"status\",\"id\":%d,\"statusCode\":\"SUCCESS\"}\r\n{\"op\":\"heartbeat\"}\r\n", id)
+		}
+
+		reader.ReadString('\n')
+	})
+
+	var mu sync.Mutex
+	var raw []string
+	cfg := stream.Config{Addr: addr, DisableTLS: true, RequestTimeout: time.Second,
+		OnRawMessage: func(data []byte, _ time.Time) {
+			mu.Lock()
+			raw = append(raw, string(data))
+			mu.Unlock()
+		}}
+
+	conn, err := stream.Dial(context.Background(), cfg, streamtest.DefaultCredentials())
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "raw-1", conn.ConnectionId())
+
+	require.NoError(t, conn.Heartbeat(context.Background()))
+
+	auth := <-lines
+	assert.Regexp(t, `^\{"op":"authentication","id":1,.*\}\r\n$`, auth)
+	assert.Equal(t, "{\"op\":\"heartbeat\",\"id\":2}\r\n", <-lines)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, raw)
+	assert.Equal(t, `{"op":"connection","connectionId":"raw-1"}`, raw[0])
+	for _, line := range raw {
+		assert.NotContains(t, line, "\r")
+		assert.NotContains(t, line, "\n")
+	}
+}
+
+func TestConn_WriteTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	addr := rawServer(t, func(netConn net.Conn, reader *bufio.Reader) {
+		netConn.Write([]byte(`{"op":"connection","connectionId":"raw-1"}` + "\r\n"))
+		reader.ReadString('\n')
+		netConn.Write([]byte(`{"op":"status","id":1,"statusCode":"SUCCESS"}` + "\r\n"))
+
+		// Stop reading so the client's writes back up
+		<-release
+	})
+
+	cfg := stream.Config{Addr: addr, DisableTLS: true, RequestTimeout: 30 * time.Second, WriteTimeout: 100 * time.Millisecond}
+	conn, err := stream.Dial(context.Background(), cfg, streamtest.DefaultCredentials())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Far more than the socket buffers hold
+	ids := slices.Repeat([]string{"1.234567890"}, 1_000_000)
+
+	start := time.Now()
+	_, err = conn.Request(context.Background(), &stream.RequestMessage{Op: stream.OP_MARKET_SUB, MarketFilter: &stream.MarketFilter{MarketIds: ids}})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.ErrorIs(t, conn.Err(), os.ErrDeadlineExceeded)
+}
+
+func TestDial_MalformedConnectionMessage(t *testing.T) {
+	addr := rawServer(t, func(netConn net.Conn, _ *bufio.Reader) {
+		netConn.Write([]byte("not json\r\n"))
+	})
+
+	_, err := stream.Dial(context.Background(), stream.Config{Addr: addr, DisableTLS: true, RequestTimeout: time.Second}, streamtest.DefaultCredentials())
+	assert.ErrorContains(t, err, "unable to read connection message")
+}
+
+func TestDial_FailureInsteadOfConnectionMessage(t *testing.T) {
+	addr := rawServer(t, func(netConn net.Conn, _ *bufio.Reader) {
+		netConn.Write([]byte(`{"op":"status","statusCode":"FAILURE","errorCode":"MAX_CONNECTION_LIMIT_EXCEEDED","errorMessage":"limit","connectionClosed":true}` + "\r\n"))
+	})
+
+	_, err := stream.Dial(context.Background(), stream.Config{Addr: addr, DisableTLS: true, RequestTimeout: time.Second}, streamtest.DefaultCredentials())
+	assert.True(t, stream.IsErrorCode(err, stream.MAX_CONNECTION_LIMIT_EXCEEDED))
+}
+
+func TestStatusError(t *testing.T) {
+	statusErr := &stream.StatusError{Code: stream.INVALID_CLOCK, Message: "clock too old", ConnectionClosed: true}
+	assert.Equal(t, "stream status INVALID_CLOCK: clock too old (connection closed)", statusErr.Error())
+
+	open := &stream.StatusError{Code: stream.TIMEOUT, Message: "slow"}
+	assert.Equal(t, "stream status TIMEOUT: slow", open.Error())
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		code stream.ErrorCode
+		want bool
+	}{
+		{"direct", statusErr, stream.INVALID_CLOCK, true},
+		{"wrapped", fmt.Errorf("resubscribe: %w", statusErr), stream.INVALID_CLOCK, true},
+		{"joined", errors.Join(errors.New("other"), statusErr), stream.INVALID_CLOCK, true},
+		{"other code", statusErr, stream.TIMEOUT, false},
+		{"plain error", errors.New("INVALID_CLOCK"), stream.INVALID_CLOCK, false},
+		{"nil", nil, stream.INVALID_CLOCK, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, stream.IsErrorCode(tc.err, tc.code))
+		})
+	}
+}
@@ -0,0 +1,421 @@
+// stream/dispatcher.go
+
+package stream
+
+// Fans market changes out to per-market listeners as typed events.
+// Each listener has a bounded buffer and a policy for when it falls behind.
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+// Implemented by every event delivered to a Listener
+type MarketEvent interface {
+	Market() string
+	coalesceKey() string
+}
+
+// A new market definition arrived
+type MarketDefinitionChanged struct {
+	MarketId   string
+	Pt         int64
+	Definition *MarketDefinition
+}
+
+type StatusChanged struct {
+	MarketId string
+	Pt       int64
+	Previous types.MarketStatus // empty for the first definition seen
+	Status   types.MarketStatus
+}
+
+type InPlayStarted struct {
+	MarketId string
+	Pt       int64
+}
+
+type RunnerRemoved struct {
+	MarketId         string
+	Pt               int64
+	SelectionId      int64
+	Handicap         float64
+	AdjustmentFactor float64
+}
+
+// Offers changed for a runner, Runner holds the resulting prices
+type PriceChanged struct {
+	MarketId string
+	Pt       int64
+	Runner   types.Runner
+}
+
+// Money was matched on a runner
+type Traded struct {
+	MarketId        string
+	Pt              int64
+	SelectionId     int64
+	Handicap        float64
+	LastPriceTraded float64
+	TotalMatched    float64
+	Trades          [][]float64 // [price, total size traded at price] deltas
+}
+
+func (e MarketDefinitionChanged) Market() string { return e.MarketId }
+func (e StatusChanged) Market() string           { return e.MarketId }
+func (e InPlayStarted) Market() string           { return e.MarketId }
+func (e RunnerRemoved) Market() string           { return e.MarketId }
+func (e PriceChanged) Market() string            { return e.MarketId }
+func (e Traded) Market() string                  { return e.MarketId }
+
+// Events with the same key can be merged when a listener falls behind, empty keys never are
+func (e MarketDefinitionChanged) coalesceKey() string { return "def" }
+func (e StatusChanged) coalesceKey() string           { return "" }
+func (e InPlayStarted) coalesceKey() string           { return "" }
+func (e RunnerRemoved) coalesceKey() string           { return "" }
+func (e PriceChanged) coalesceKey() string {
+	return "price:" + runnerId(int64(e.Runner.SelectionId), float64(e.Runner.Handicap))
+}
+
+// Trade deltas cannot be merged without losing volume
+func (e Traded) coalesceKey() string { return "" }
+
+type SlowConsumerPolicy int
+
+const (
+	DROP_OLDEST SlowConsumerPolicy = iota // discard the oldest queued event
+	COALESCE                              // replace a queued event of the same kind, else drop the oldest
+	DISCONNECT                            // close the listener with ErrSlowConsumer
+)
+
+var ErrSlowConsumer = errors.New("listener closed: too slow to keep up with the stream")
+
+const defaultListenerBuffer = 256
+
+type ListenerOptions struct {
+	Buffer int // defaults to 256
+	Policy SlowConsumerPolicy
+}
+
+type Listener struct {
+	marketId string
+	policy   SlowConsumerPolicy
+	buffer   int
+	out      chan MarketEvent
+
+	mu      sync.Mutex
+	queue   []MarketEvent
+	notify  chan struct{}
+	done    chan struct{}
+	closed  bool
+	err     error
+	dropped atomic.Int64
+
+	dispatcher *Dispatcher
+}
+
+// Events for the listener's market, closed when the listener closes
+func (l *Listener) C() <-chan MarketEvent {
+	return l.out
+}
+
+// Number of events dropped or merged because the listener fell behind
+func (l *Listener) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// Reason the listener was closed by the dispatcher, nil if closed by the caller
+func (l *Listener) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+func (l *Listener) Close() {
+	l.dispatcher.remove(l)
+	l.close(nil)
+}
+
+func (l *Listener) close(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return
+	}
+
+	l.closed = true
+	l.err = err
+	l.queue = nil
+	close(l.done)
+}
+
+// Queues an event without blocking, applying the slow consumer policy when full
+// Returns false if the listener should be disconnected
+func (l *Listener) push(e MarketEvent) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return true
+	}
+
+	if l.policy == COALESCE {
+		if key := e.coalesceKey(); key != "" {
+			for i, queued := range l.queue {
+				if queued.coalesceKey() == key {
+					l.queue[i] = e
+					l.dropped.Add(1)
+					return true
+				}
+			}
+		}
+	}
+
+	if len(l.queue) >= l.buffer {
+		if l.policy == DISCONNECT {
+			return false
+		}
+
+		l.queue = l.queue[1:]
+		l.dropped.Add(1)
+	}
+
+	l.queue = append(l.queue, e)
+
+	select {
+	case l.notify <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// Moves queued events to the output channel at the consumer's pace
+func (l *Listener) pump() {
+	defer close(l.out)
+
+	for {
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			return
+		}
+
+		var next MarketEvent
+		if len(l.queue) > 0 {
+			next = l.queue[0]
+			l.queue = l.queue[1:]
+		}
+		l.mu.Unlock()
+
+		if next == nil {
+			select {
+			case <-l.notify:
+			case <-l.done:
+				return
+			}
+			continue
+		}
+
+		select {
+		case l.out <- next:
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// Per market state needed to detect transitions
+type marketTransitions struct {
+	status  types.MarketStatus
+	inPlay  bool
+	removed map[string]bool
+}
+
+type Dispatcher struct {
+	cache *MarketCache
+
+	mu        sync.Mutex
+	listeners map[string]map[*Listener]struct{}
+	markets   map[string]*marketTransitions
+}
+
+// Events are built from cache state, so Handle must run after the cache has applied a message
+func NewDispatcher(cache *MarketCache) *Dispatcher {
+	return &Dispatcher{
+		cache:     cache,
+		listeners: make(map[string]map[*Listener]struct{}),
+		markets:   make(map[string]*marketTransitions),
+	}
+}
+
+// Starts delivering events for a single market
+func (d *Dispatcher) Listen(marketId string, opts ListenerOptions) *Listener {
+	if opts.Buffer <= 0 {
+		opts.Buffer = defaultListenerBuffer
+	}
+
+	l := &Listener{
+		marketId:   marketId,
+		policy:     opts.Policy,
+		buffer:     opts.Buffer,
+		out:        make(chan MarketEvent),
+		notify:     make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		dispatcher: d,
+	}
+
+	d.mu.Lock()
+	if d.listeners[marketId] == nil {
+		d.listeners[marketId] = make(map[*Listener]struct{})
+	}
+	d.listeners[marketId][l] = struct{}{}
+	d.mu.Unlock()
+
+	go l.pump()
+
+	return l
+}
+
+func (d *Dispatcher) remove(l *Listener) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.listeners[l.marketId], l)
+	if len(d.listeners[l.marketId]) == 0 {
+		delete(d.listeners, l.marketId)
+	}
+}
+
+// Closes every listener, e.g. on shutdown
+func (d *Dispatcher) CloseAll() {
+	d.mu.Lock()
+	all := d.listeners
+	d.listeners = make(map[string]map[*Listener]struct{})
+	d.mu.Unlock()
+
+	for _, set := range all {
+		for l := range set {
+			l.close(nil)
+		}
+	}
+}
+
+// Drops transition state for a market that left the subscription
+func (d *Dispatcher) forget(marketId string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.markets, marketId)
+}
+
+// Turns an applied mcm message into events for interested listeners
+func (d *Dispatcher) Handle(msg *ResponseMessage) {
+	if msg.Op != OP_MCM {
+		return
+	}
+
+	for _, mc := range msg.Mc {
+		d.handleMarket(msg.Pt, mc)
+	}
+}
+
+func (d *Dispatcher) handleMarket(pt int64, mc *MarketChange) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, ok := d.markets[mc.Id]
+	if !ok {
+		state = &marketTransitions{removed: make(map[string]bool)}
+		d.markets[mc.Id] = state
+	}
+
+	var events []MarketEvent
+
+	if def := mc.MarketDefinition; def != nil {
+		events = append(events, MarketDefinitionChanged{MarketId: mc.Id, Pt: pt, Definition: def})
+
+		status := types.MarketStatus(def.Status)
+		if status != state.status {
+			events = append(events, StatusChanged{MarketId: mc.Id, Pt: pt, Previous: state.status, Status: status})
+			state.status = status
+		}
+
+		if def.InPlay && !state.inPlay {
+			events = append(events, InPlayStarted{MarketId: mc.Id, Pt: pt})
+		}
+		state.inPlay = def.InPlay
+
+		for _, rd := range def.Runners {
+			id := runnerId(rd.Id, rd.Hc)
+			removed := rd.Status == string(types.REMOVED) || rd.Status == string(types.REMOVED_VACANT)
+			if removed && !state.removed[id] {
+				state.removed[id] = true
+				events = append(events, RunnerRemoved{
+					MarketId:         mc.Id,
+					Pt:               pt,
+					SelectionId:      rd.Id,
+					Handicap:         rd.Hc,
+					AdjustmentFactor: rd.AdjustmentFactor,
+				})
+			}
+		}
+	}
+
+	listeners := d.listeners[mc.Id]
+	if len(listeners) == 0 && len(events) == 0 {
+		return
+	}
+
+	// Runner snapshots are only worth building when someone is listening
+	if len(listeners) > 0 {
+		for _, rc := range mc.Rc {
+			if hasPriceChange(rc) {
+				if runner, ok := d.cache.Runner(mc.Id, rc.Id, rc.Hc); ok {
+					events = append(events, PriceChanged{MarketId: mc.Id, Pt: pt, Runner: runner})
+				}
+			}
+
+			if len(rc.Trd) > 0 || rc.Ltp != 0 {
+				traded := Traded{MarketId: mc.Id, Pt: pt, SelectionId: rc.Id, Handicap: rc.Hc, Trades: rc.Trd}
+				if runner, ok := d.cache.Runner(mc.Id, rc.Id, rc.Hc); ok {
+					traded.LastPriceTraded = float64(runner.LastPriceTraded)
+					traded.TotalMatched = float64(runner.TotalMatched)
+				}
+				events = append(events, traded)
+			}
+		}
+	}
+
+	if state.status == types.CLOSED {
+		delete(d.markets, mc.Id)
+	}
+
+	for l := range listeners {
+		for _, e := range events {
+			if !l.push(e) {
+				delete(listeners, l)
+				l.close(ErrSlowConsumer)
+				break
+			}
+		}
+	}
+}
+
+func hasPriceChange(rc *RunnerChange) bool {
+	return len(rc.Atb) > 0 || len(rc.Atl) > 0 ||
+		len(rc.Batb) > 0 || len(rc.Batl) > 0 ||
+		len(rc.Bdatb) > 0 || len(rc.Bdatl) > 0 ||
+		len(rc.Spb) > 0 || len(rc.Spl) > 0 ||
+		rc.Spn != 0 || rc.Spf != 0
+}
+
+func runnerId(selectionId int64, handicap float64) string {
+	return fmt.Sprintf("%d:%g", selectionId, handicap)
+}
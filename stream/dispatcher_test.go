@@ -0,0 +1,99 @@
+// stream/dispatcher_test.go
+
+package stream_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func feed(t *testing.T, cache *stream.MarketCache, d *stream.Dispatcher, raw string) {
+	t.Helper()
+
+	msg := mcm(t, raw)
+	cache.ApplyMessage(msg)
+	d.Handle(msg)
+}
+
+func next(t *testing.T, l *stream.Listener) stream.MarketEvent {
+	t.Helper()
+
+	select {
+	case e, ok := <-l.C():
+		require.True(t, ok, "listener closed")
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return nil
+	}
+}
+
+func TestDispatcher_TypedEvents(t *testing.T) {
+	cache := stream.NewMarketCache()
+	d := stream.NewDispatcher(cache)
+
+	l := d.Listen("1.1", stream.ListenerOptions{})
+	defer l.Close()
+
+	feed(t, cache, d, `{"op":"mcm","pt":1,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN","runners":[{"id":11,"status":"ACTIVE"}]}}]}`)
+	feed(t, cache, d, `{"op":"mcm","pt":2,"mc":[{"id":"1.1","marketDefinition":{"status":"OPEN","inPlay":true,"runners":[{"id":11,"status":"REMOVED","adjustmentFactor":12.5}]}}]}`)
+	feed(t, cache, d, `{"op":"mcm","pt":3,"mc":[{"id":"1.2","rc":[{"id":99,"atb":[[2,1]]}]}]}`)
+	feed(t, cache, d, `{"op":"mcm","pt":4,"mc":[{"id":"1.1","rc":[{"id":22,"atb":[[3,5]],"trd":[[3,2]],"ltp":3}]}]}`)
+
+	assert.IsType(t, stream.MarketDefinitionChanged{}, next(t, l))
+	assert.Equal(t, stream.StatusChanged{MarketId: "1.1", Pt: 1, Status: types.OPEN}, next(t, l))
+	assert.IsType(t, stream.MarketDefinitionChanged{}, next(t, l))
+	assert.Equal(t, stream.InPlayStarted{MarketId: "1.1", Pt: 2}, next(t, l))
+	assert.Equal(t, 12.5, next(t, l).(stream.RunnerRemoved).AdjustmentFactor)
+
+	price := next(t, l).(stream.PriceChanged)
+	assert.Equal(t, []types.RunnerPrice{{Price: 3, Size: 5}}, price.Runner.Ex.Back)
+
+	traded := next(t, l).(stream.Traded)
+	assert.Equal(t, 3.0, traded.LastPriceTraded)
+}
+
+func TestDispatcher_SlowConsumerPolicies(t *testing.T) {
+	cache := stream.NewMarketCache()
+	d := stream.NewDispatcher(cache)
+
+	coalesced := d.Listen("1.1", stream.ListenerOptions{Buffer: 2, Policy: stream.COALESCE})
+	defer coalesced.Close()
+	strict := d.Listen("1.1", stream.ListenerOptions{Buffer: 2, Policy: stream.DISCONNECT})
+
+	for i := 0; i < 10; i++ {
+		feed(t, cache, d, `{"op":"mcm","mc":[{"id":"1.1","rc":[{"id":11,"atb":[[2,1]]}]}]}`)
+	}
+
+	// Nobody is reading, so the strict listener must have been cut off
+	_, open := <-strict.C()
+	for open {
+		_, open = <-strict.C()
+	}
+	assert.ErrorIs(t, strict.Err(), stream.ErrSlowConsumer)
+
+	assert.IsType(t, stream.PriceChanged{}, next(t, coalesced))
+	assert.Positive(t, coalesced.Dropped())
+}
+
+func TestDispatcher_VacantRunnerRemoved(t *testing.T) {
+	cache := stream.NewMarketCache()
+	d := stream.NewDispatcher(cache)
+
+	l := d.Listen("1.1", stream.ListenerOptions{})
+	defer l.Close()
+
+	feed(t, cache, d, `{"op":"mcm","pt":1,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN","runners":[{"id":11,"status":"ACTIVE"}]}}]}`)
+	feed(t, cache, d, `{"op":"mcm","pt":2,"mc":[{"id":"1.1","marketDefinition":{"status":"OPEN","runners":[{"id":11,"status":"REMOVED_VACANT"}]}}]}`)
+
+	assert.IsType(t, stream.MarketDefinitionChanged{}, next(t, l))
+	assert.IsType(t, stream.StatusChanged{}, next(t, l))
+	assert.IsType(t, stream.MarketDefinitionChanged{}, next(t, l))
+	assert.Equal(t, stream.RunnerRemoved{MarketId: "1.1", Pt: 2, SelectionId: 11}, next(t, l))
+}
@@ -0,0 +1,60 @@
+// stream/errors.go
+
+package stream
+
+import (
+	"errors"
+	"fmt"
+)
+
+type ErrorCode string
+
+const (
+	NO_APP_KEY                    ErrorCode = "NO_APP_KEY"
+	INVALID_APP_KEY               ErrorCode = "INVALID_APP_KEY"
+	NO_SESSION                    ErrorCode = "NO_SESSION"
+	INVALID_SESSION_INFORMATION   ErrorCode = "INVALID_SESSION_INFORMATION"
+	NOT_AUTHORIZED                ErrorCode = "NOT_AUTHORIZED"
+	INVALID_INPUT                 ErrorCode = "INVALID_INPUT"
+	INVALID_CLOCK                 ErrorCode = "INVALID_CLOCK"
+	UNEXPECTED_ERROR              ErrorCode = "UNEXPECTED_ERROR"
+	TIMEOUT                       ErrorCode = "TIMEOUT"
+	SUBSCRIPTION_LIMIT_EXCEEDED   ErrorCode = "SUBSCRIPTION_LIMIT_EXCEEDED"
+	INVALID_REQUEST               ErrorCode = "INVALID_REQUEST"
+	CONNECTION_FAILED             ErrorCode = "CONNECTION_FAILED"
+	MAX_CONNECTION_LIMIT_EXCEEDED ErrorCode = "MAX_CONNECTION_LIMIT_EXCEEDED"
+	TOO_MANY_REQUESTS             ErrorCode = "TOO_MANY_REQUESTS"
+)
+
+// Failure status returned by Betfair, either in reply to a request or unsolicited
+type StatusError struct {
+	Code             ErrorCode
+	Message          string
+	ConnectionId     string
+	ConnectionClosed bool
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("stream status %s: %s", e.Code, e.Message)
+	if e.ConnectionClosed {
+		msg += " (connection closed)"
+	}
+	return msg
+}
+
+// Reports whether err is (or wraps) a StatusError with the given code
+func IsErrorCode(err error, code ErrorCode) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.Code == code
+}
+
+var ErrConnClosed = errors.New("stream connection closed")
+
+func statusError(msg *ResponseMessage, connectionId string) *StatusError {
+	return &StatusError{
+		Code:             msg.ErrorCode,
+		Message:          msg.ErrorMessage,
+		ConnectionId:     connectionId,
+		ConnectionClosed: msg.ConnectionClosed,
+	}
+}
@@ -0,0 +1,119 @@
+// stream/market.go
+
+package stream
+
+// Market subscription filters and market change messages
+
+type MarketDataField string
+
+const (
+	EX_BEST_OFFERS_DISP MarketDataField = "EX_BEST_OFFERS_DISP" // virtual prices as displayed on the website
+	EX_BEST_OFFERS      MarketDataField = "EX_BEST_OFFERS"
+	EX_ALL_OFFERS       MarketDataField = "EX_ALL_OFFERS"
+	EX_TRADED           MarketDataField = "EX_TRADED"
+	EX_TRADED_VOL       MarketDataField = "EX_TRADED_VOL"
+	EX_LTP              MarketDataField = "EX_LTP"
+	EX_MARKET_DEF       MarketDataField = "EX_MARKET_DEF"
+	SP_TRADED           MarketDataField = "SP_TRADED"
+	SP_PROJECTED        MarketDataField = "SP_PROJECTED"
+)
+
+// Stream market filter, note it differs from types.MarketFilter used by the REST API
+type MarketFilter struct {
+	MarketIds         []string `json:"marketIds,omitempty"`
+	BspMarket         *bool    `json:"bspMarket,omitempty"`
+	BettingTypes      []string `json:"bettingTypes,omitempty"`
+	EventTypeIds      []string `json:"eventTypeIds,omitempty"`
+	EventIds          []string `json:"eventIds,omitempty"`
+	TurnInPlayEnabled *bool    `json:"turnInPlayEnabled,omitempty"`
+	MarketTypes       []string `json:"marketTypes,omitempty"`
+	Venues            []string `json:"venues,omitempty"`
+	CountryCodes      []string `json:"countryCodes,omitempty"`
+	RaceTypes         []string `json:"raceTypes,omitempty"`
+}
+
+type MarketDataFilter struct {
+	LadderLevels int               `json:"ladderLevels,omitempty"` // depth of EX_BEST_OFFERS, max 10
+	Fields       []MarketDataField `json:"fields,omitempty"`
+}
+
+type MarketChange struct {
+	Id               string            `json:"id"`
+	Img              bool              `json:"img,omitempty"` // full image, replaces any cached state
+	Con              bool              `json:"con,omitempty"` // conflated
+	Tv               float64           `json:"tv,omitempty"`
+	Rc               []*RunnerChange   `json:"rc,omitempty"`
+	MarketDefinition *MarketDefinition `json:"marketDefinition,omitempty"`
+}
+
+// Price ladders are sent as deltas
+// [price, size] pairs for atb/atl/trd/spb/spl and [level, price, size] for batb/batl/bdatb/bdatl
+// A size of 0 removes the price or level
+type RunnerChange struct {
+	Id    int64       `json:"id"`
+	Hc    float64     `json:"hc,omitempty"`
+	Con   bool        `json:"con,omitempty"`
+	Tv    float64     `json:"tv,omitempty"`
+	Ltp   float64     `json:"ltp,omitempty"`
+	Spn   float64     `json:"spn,omitempty"`
+	Spf   float64     `json:"spf,omitempty"`
+	Atb   [][]float64 `json:"atb,omitempty"`
+	Atl   [][]float64 `json:"atl,omitempty"`
+	Batb  [][]float64 `json:"batb,omitempty"`
+	Batl  [][]float64 `json:"batl,omitempty"`
+	Bdatb [][]float64 `json:"bdatb,omitempty"`
+	Bdatl [][]float64 `json:"bdatl,omitempty"`
+	Spb   [][]float64 `json:"spb,omitempty"`
+	Spl   [][]float64 `json:"spl,omitempty"`
+	Trd   [][]float64 `json:"trd,omitempty"`
+}
+
+// Market definitions are always sent in full
+type MarketDefinition struct {
+	Status                string              `json:"status"`
+	InPlay                bool                `json:"inPlay"`
+	BetDelay              int                 `json:"betDelay"`
+	BspMarket             bool                `json:"bspMarket"`
+	BspReconciled         bool                `json:"bspReconciled"`
+	Complete              bool                `json:"complete"`
+	CrossMatching         bool                `json:"crossMatching"`
+	RunnersVoidable       bool                `json:"runnersVoidable"`
+	TurnInPlayEnabled     bool                `json:"turnInPlayEnabled"`
+	PersistenceEnabled    bool                `json:"persistenceEnabled"`
+	DiscountAllowed       bool                `json:"discountAllowed"`
+	MarketBaseRate        float64             `json:"marketBaseRate"`
+	NumberOfWinners       int                 `json:"numberOfWinners"`
+	NumberOfActiveRunners int                 `json:"numberOfActiveRunners"`
+	Version               int64               `json:"version"`
+	EventId               string              `json:"eventId"`
+	EventTypeId           string              `json:"eventTypeId"`
+	EventName             string              `json:"eventName,omitempty"`
+	MarketType            string              `json:"marketType"`
+	BettingType           string              `json:"bettingType"`
+	CountryCode           string              `json:"countryCode,omitempty"`
+	Venue                 string              `json:"venue,omitempty"`
+	Timezone              string              `json:"timezone,omitempty"`
+	MarketTime            string              `json:"marketTime,omitempty"`
+	OpenDate              string              `json:"openDate,omitempty"`
+	SuspendTime           string              `json:"suspendTime,omitempty"`
+	SettledTime           string              `json:"settledTime,omitempty"`
+	PriceLadderDefinition *PriceLadderDef     `json:"priceLadderDefinition,omitempty"`
+	LineMinUnit           float64             `json:"lineMinUnit,omitempty"` // LINE_RANGE markets only
+	LineMaxUnit           float64             `json:"lineMaxUnit,omitempty"`
+	LineInterval          float64             `json:"lineInterval,omitempty"`
+	Runners               []*RunnerDefinition `json:"runners,omitempty"`
+}
+
+type PriceLadderDef struct {
+	Type string `json:"type"` // CLASSIC, FINEST or LINE_RANGE
+}
+
+type RunnerDefinition struct {
+	Id               int64   `json:"id"`
+	Hc               float64 `json:"hc,omitempty"`
+	SortPriority     int     `json:"sortPriority"`
+	Status           string  `json:"status"`
+	AdjustmentFactor float64 `json:"adjustmentFactor,omitempty"`
+	Bsp              float64 `json:"bsp,omitempty"`
+	RemovalDate      string  `json:"removalDate,omitempty"`
+}
@@ -0,0 +1,534 @@
+// stream/market_cache.go
+
+package stream
+
+// Local order book built from mcm deltas.
+// Snapshots are exposed in the same shape as ListMarketBook so REST consumers can switch over.
+// With WithClosedMarketEviction, markets whose definition turns CLOSED are dropped when the next change is applied,
+// so code reacting to the closing message can still read the final book.
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+type runnerKey struct {
+	id int64
+	hc float64
+}
+
+// price -> size
+type priceLadder map[float64]float64
+
+// level -> [price, size]
+type levelLadder map[int][2]float64
+
+type runnerCache struct {
+	key runnerKey
+
+	ltp float64
+	tv  float64
+	spn float64
+	spf float64
+
+	atb priceLadder
+	atl priceLadder
+	trd priceLadder
+	spb priceLadder
+	spl priceLadder
+
+	batb  levelLadder
+	batl  levelLadder
+	bdatb levelLadder
+	bdatl levelLadder
+}
+
+type marketCache struct {
+	id      string
+	def     *MarketDefinition
+	tv      float64
+	pt      int64
+	runners map[runnerKey]*runnerCache
+}
+
+type MarketCache struct {
+	mu      sync.RWMutex
+	markets map[string]*marketCache
+
+	evictClosed bool
+	onClosed    func(types.ListMarketBookResponse)
+	closed      []string // closed by the last change, evicted before the next
+}
+
+type MarketCacheOption func(*MarketCache)
+
+// Evicts markets once their definition turns CLOSED, onClosed (optional) receives each final book first
+// Without it closed markets stay cached until Remove
+func WithClosedMarketEviction(onClosed func(types.ListMarketBookResponse)) MarketCacheOption {
+	return func(c *MarketCache) {
+		c.evictClosed = true
+		c.onClosed = onClosed
+	}
+}
+
+func NewMarketCache(opts ...MarketCacheOption) *MarketCache {
+	c := &MarketCache{markets: make(map[string]*marketCache)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Applies every market change of an mcm message
+func (c *MarketCache) ApplyMessage(msg *ResponseMessage) {
+	if msg.Op != OP_MCM || len(msg.Mc) == 0 {
+		return
+	}
+
+	c.update(func() {
+		for _, mc := range msg.Mc {
+			c.apply(msg.Pt, mc)
+		}
+	})
+}
+
+// Replaces every cached market with the markets of a full image, under a single lock
+// so readers never observe a partially rebuilt cache
+func (c *MarketCache) ReplaceAll(msg *ResponseMessage) {
+	c.update(func() {
+		c.markets = make(map[string]*marketCache)
+		for _, mc := range msg.Mc {
+			c.apply(msg.Pt, mc)
+		}
+	})
+}
+
+// Drops marketIds and applies msg under a single lock
+// Used when a full image replaces only part of the cache, e.g. one connection of a Pool
+func (c *MarketCache) Replace(marketIds []string, msg *ResponseMessage) {
+	c.update(func() {
+		for _, id := range marketIds {
+			delete(c.markets, id)
+		}
+		for _, mc := range msg.Mc {
+			c.apply(msg.Pt, mc)
+		}
+	})
+}
+
+// Applies a single market change published at pt (epoch millis)
+func (c *MarketCache) Apply(pt int64, mc *MarketChange) {
+	c.update(func() {
+		c.apply(pt, mc)
+	})
+}
+
+// Runs apply under the lock, first evicting markets closed by the previous change
+// The final books of markets it closes are handed to onClosed once the lock is released
+func (c *MarketCache) update(apply func()) {
+	c.mu.Lock()
+	for _, id := range c.closed {
+		delete(c.markets, id)
+	}
+	c.closed = c.closed[:0]
+
+	apply()
+
+	var books []types.ListMarketBookResponse
+	if c.onClosed != nil {
+		for _, id := range c.closed {
+			if m, ok := c.markets[id]; ok {
+				books = append(books, m.snapshot())
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	for _, book := range books {
+		c.onClosed(book)
+	}
+}
+
+func (c *MarketCache) apply(pt int64, mc *MarketChange) {
+	m, ok := c.markets[mc.Id]
+	if !ok || mc.Img {
+		m = &marketCache{id: mc.Id, runners: make(map[runnerKey]*runnerCache)}
+		c.markets[mc.Id] = m
+	}
+
+	m.pt = pt
+
+	if def := mc.MarketDefinition; def != nil {
+		if c.evictClosed && isClosed(def) && (m.def == nil || !isClosed(m.def)) {
+			c.closed = append(c.closed, mc.Id)
+		}
+		m.def = def
+	}
+
+	if mc.Tv != 0 {
+		m.tv = mc.Tv
+	}
+
+	for _, rc := range mc.Rc {
+		key := runnerKey{id: rc.Id, hc: rc.Hc}
+		r, ok := m.runners[key]
+		if !ok {
+			r = newRunnerCache(key)
+			m.runners[key] = r
+		}
+		r.apply(rc)
+	}
+}
+
+func isClosed(def *MarketDefinition) bool {
+	return types.MarketStatus(def.Status) == types.CLOSED
+}
+
+func newRunnerCache(key runnerKey) *runnerCache {
+	return &runnerCache{
+		key:   key,
+		atb:   priceLadder{},
+		atl:   priceLadder{},
+		trd:   priceLadder{},
+		spb:   priceLadder{},
+		spl:   priceLadder{},
+		batb:  levelLadder{},
+		batl:  levelLadder{},
+		bdatb: levelLadder{},
+		bdatl: levelLadder{},
+	}
+}
+
+func (r *runnerCache) apply(rc *RunnerChange) {
+	if rc.Ltp != 0 {
+		r.ltp = rc.Ltp
+	}
+	if rc.Tv != 0 {
+		r.tv = rc.Tv
+	}
+	if rc.Spn != 0 {
+		r.spn = rc.Spn
+	}
+	if rc.Spf != 0 {
+		r.spf = rc.Spf
+	}
+
+	r.atb.update(rc.Atb)
+	r.atl.update(rc.Atl)
+	r.trd.update(rc.Trd)
+	r.spb.update(rc.Spb)
+	r.spl.update(rc.Spl)
+
+	r.batb.update(rc.Batb)
+	r.batl.update(rc.Batl)
+	r.bdatb.update(rc.Bdatb)
+	r.bdatl.update(rc.Bdatl)
+}
+
+func (l priceLadder) update(deltas [][]float64) {
+	for _, d := range deltas {
+		if len(d) < 2 {
+			continue
+		}
+
+		if d[1] == 0 {
+			delete(l, d[0])
+		} else {
+			l[d[0]] = d[1]
+		}
+	}
+}
+
+func (l levelLadder) update(deltas [][]float64) {
+	for _, d := range deltas {
+		if len(d) < 3 {
+			continue
+		}
+
+		level := int(d[0])
+		if d[2] == 0 {
+			delete(l, level)
+		} else {
+			l[level] = [2]float64{d[1], d[2]}
+		}
+	}
+}
+
+// Drops a market, e.g. once it has been unsubscribed
+func (c *MarketCache) Remove(marketId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.markets, marketId)
+}
+
+func (c *MarketCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.markets = make(map[string]*marketCache)
+}
+
+func (c *MarketCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.markets)
+}
+
+func (c *MarketCache) MarketIds() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]string, 0, len(c.markets))
+	for id := range c.markets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Returns the latest market definition
+// Definitions are replaced rather than updated, the result must not be modified
+func (c *MarketCache) Definition(marketId string) (*MarketDefinition, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, ok := c.markets[marketId]
+	if !ok || m.def == nil {
+		return nil, false
+	}
+
+	return m.def, true
+}
+
+// Publish time (epoch millis) of the last change applied to the market
+func (c *MarketCache) PublishTime(marketId string) (int64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, ok := c.markets[marketId]
+	if !ok {
+		return 0, false
+	}
+
+	return m.pt, true
+}
+
+// Returns a copy of the market in ListMarketBook form
+func (c *MarketCache) MarketBook(marketId string) (types.ListMarketBookResponse, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, ok := c.markets[marketId]
+	if !ok {
+		return types.ListMarketBookResponse{}, false
+	}
+
+	return m.snapshot(), true
+}
+
+// Returns a copy of a single runner in ListMarketBook form
+func (c *MarketCache) Runner(marketId string, selectionId int64, handicap float64) (types.Runner, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, ok := c.markets[marketId]
+	if !ok {
+		return types.Runner{}, false
+	}
+
+	r, ok := m.runners[runnerKey{id: selectionId, hc: handicap}]
+	if !ok {
+		return types.Runner{}, false
+	}
+
+	runner := types.Runner{SelectionId: int(selectionId), Handicap: float32(handicap)}
+	r.fill(&runner)
+
+	if m.def != nil {
+		for _, rd := range m.def.Runners {
+			if rd.Id == selectionId && rd.Hc == handicap {
+				runner.Status = types.RunnerStatus(rd.Status)
+			}
+		}
+	}
+
+	return runner, true
+}
+
+// Returns snapshots for the given markets, or every cached market if none are given
+// Unknown market ids are skipped, like ListMarketBook
+func (c *MarketCache) MarketBooks(marketIds ...string) []types.ListMarketBookResponse {
+	if len(marketIds) == 0 {
+		marketIds = c.MarketIds()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	books := make([]types.ListMarketBookResponse, 0, len(marketIds))
+	for _, id := range marketIds {
+		if m, ok := c.markets[id]; ok {
+			books = append(books, m.snapshot())
+		}
+	}
+
+	return books
+}
+
+func (m *marketCache) snapshot() types.ListMarketBookResponse {
+	book := types.ListMarketBookResponse{
+		MarketId:     m.id,
+		TotalMatched: float32(m.tv),
+	}
+
+	status := make(map[runnerKey]string)
+	priority := make(map[runnerKey]int)
+	if m.def != nil {
+		book.Status = types.MarketStatus(m.def.Status)
+		book.InPlay = m.def.InPlay
+		book.BetDelay = m.def.BetDelay
+		book.Version = m.def.Version
+
+		for _, rd := range m.def.Runners {
+			key := runnerKey{id: rd.Id, hc: rd.Hc}
+			status[key] = rd.Status
+			priority[key] = rd.SortPriority
+		}
+	}
+
+	keys := make([]runnerKey, 0, len(m.runners))
+	for key := range m.runners {
+		keys = append(keys, key)
+	}
+
+	// Runners known only from the definition are still reported
+	if m.def != nil {
+		for _, rd := range m.def.Runners {
+			key := runnerKey{id: rd.Id, hc: rd.Hc}
+			if _, ok := m.runners[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		pi, oki := priority[keys[i]]
+		pj, okj := priority[keys[j]]
+		if oki && okj && pi != pj {
+			return pi < pj
+		}
+		if oki != okj {
+			return oki
+		}
+		if keys[i].id != keys[j].id {
+			return keys[i].id < keys[j].id
+		}
+		return keys[i].hc < keys[j].hc
+	})
+
+	book.Runners = make([]types.Runner, 0, len(keys))
+	for _, key := range keys {
+		runner := types.Runner{
+			SelectionId: int(key.id),
+			Handicap:    float32(key.hc),
+			Status:      types.RunnerStatus(status[key]),
+		}
+
+		if r, ok := m.runners[key]; ok {
+			r.fill(&runner)
+		}
+
+		if m.def != nil {
+			for _, rd := range m.def.Runners {
+				if rd.Id == key.id && rd.Hc == key.hc && rd.Bsp != 0 {
+					if runner.Sp == nil {
+						runner.Sp = &types.StartingPrices{}
+					}
+					runner.Sp.ActualSP = float32(rd.Bsp)
+				}
+			}
+		}
+
+		book.Runners = append(book.Runners, runner)
+	}
+
+	return book
+}
+
+func (r *runnerCache) fill(runner *types.Runner) {
+	runner.LastPriceTraded = float32(r.ltp)
+	runner.TotalMatched = float32(r.tv)
+
+	if r.spn != 0 || r.spf != 0 {
+		runner.Sp = &types.StartingPrices{
+			NearPrice: float32(r.spn),
+			FarPrice:  float32(r.spf),
+		}
+	}
+
+	// Prefer the full ladder when subscribed to EX_ALL_OFFERS, then best offers, then virtual best offers
+	switch {
+	case len(r.atb) > 0:
+		runner.Ex.Back = r.atb.sorted(true)
+	case len(r.batb) > 0:
+		runner.Ex.Back = r.batb.sorted()
+	default:
+		runner.Ex.Back = r.bdatb.sorted()
+	}
+
+	switch {
+	case len(r.atl) > 0:
+		runner.Ex.Lay = r.atl.sorted(false)
+	case len(r.batl) > 0:
+		runner.Ex.Lay = r.batl.sorted()
+	default:
+		runner.Ex.Lay = r.bdatl.sorted()
+	}
+
+	runner.Ex.Traded = r.trd.sorted(false)
+}
+
+func (l priceLadder) sorted(descending bool) []types.RunnerPrice {
+	if len(l) == 0 {
+		return nil
+	}
+
+	prices := make([]types.RunnerPrice, 0, len(l))
+	for price, size := range l {
+		prices = append(prices, types.RunnerPrice{Price: float32(price), Size: float32(size)})
+	}
+
+	sort.Slice(prices, func(i, j int) bool {
+		if descending {
+			return prices[i].Price > prices[j].Price
+		}
+		return prices[i].Price < prices[j].Price
+	})
+
+	return prices
+}
+
+func (l levelLadder) sorted() []types.RunnerPrice {
+	if len(l) == 0 {
+		return nil
+	}
+
+	levels := make([]int, 0, len(l))
+	for level := range l {
+		levels = append(levels, level)
+	}
+	sort.Ints(levels)
+
+	prices := make([]types.RunnerPrice, 0, len(levels))
+	for _, level := range levels {
+		p := l[level]
+		prices = append(prices, types.RunnerPrice{Price: float32(p[0]), Size: float32(p[1])})
+	}
+
+	return prices
+}
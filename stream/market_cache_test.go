@@ -0,0 +1,112 @@
+// stream/market_cache_test.go
+
+package stream_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mcm(t *testing.T, raw string) *stream.ResponseMessage {
+	t.Helper()
+
+	var msg stream.ResponseMessage
+	require.NoError(t, json.Unmarshal([]byte(raw), &msg))
+	return &msg
+}
+
+func TestMarketCache_AppliesDeltas(t *testing.T) {
+	cache := stream.NewMarketCache()
+
+	cache.ApplyMessage(mcm(t, `{"op":"mcm","pt":1000,"ct":"SUB_IMAGE","mc":[{"id":"1.1","img":true,"tv":50,
+		"marketDefinition":{"status":"OPEN","inPlay":false,"version":7,"runners":[
+			{"id":11,"sortPriority":2,"status":"ACTIVE"},{"id":22,"sortPriority":1,"status":"ACTIVE"}]},
+		"rc":[{"id":11,"ltp":2.5,"tv":30,"atb":[[2.4,10],[2.3,5]],"atl":[[2.6,8]],"trd":[[2.5,30]]},
+		      {"id":22,"batb":[[0,1.8,100],[1,1.7,50]],"batl":[[0,1.9,20]]}]}]}`))
+
+	cache.ApplyMessage(mcm(t, `{"op":"mcm","pt":2000,"mc":[{"id":"1.1",
+		"rc":[{"id":11,"atb":[[2.4,0],[2.2,4]],"ltp":2.6},{"id":22,"batb":[[1,0,0]],"spn":1.85}]}]}`))
+
+	book, ok := cache.MarketBook("1.1")
+	require.True(t, ok)
+	assert.Equal(t, types.OPEN, book.Status)
+	assert.Equal(t, int64(7), book.Version)
+	assert.Equal(t, float32(50), book.TotalMatched)
+
+	// Sorted by definition sort priority
+	require.Len(t, book.Runners, 2)
+	assert.Equal(t, 22, book.Runners[0].SelectionId)
+	assert.Equal(t, []types.RunnerPrice{{Price: 1.8, Size: 100}}, book.Runners[0].Ex.Back)
+	assert.Equal(t, float32(1.85), book.Runners[0].Sp.NearPrice)
+
+	r := book.Runners[1]
+	assert.Equal(t, types.ACTIVE, r.Status)
+	assert.Equal(t, float32(2.6), r.LastPriceTraded)
+	assert.Equal(t, []types.RunnerPrice{{Price: 2.3, Size: 5}, {Price: 2.2, Size: 4}}, r.Ex.Back)
+	assert.Equal(t, []types.RunnerPrice{{Price: 2.6, Size: 8}}, r.Ex.Lay)
+	assert.Equal(t, []types.RunnerPrice{{Price: 2.5, Size: 30}}, r.Ex.Traded)
+
+	pt, _ := cache.PublishTime("1.1")
+	assert.Equal(t, int64(2000), pt)
+}
+
+func TestMarketCache_ImageReplacesState(t *testing.T) {
+	cache := stream.NewMarketCache()
+
+	cache.ApplyMessage(mcm(t, `{"op":"mcm","mc":[{"id":"1.1","img":true,"rc":[{"id":11,"atb":[[2.4,10]]},{"id":22,"atb":[[3,1]]}]}]}`))
+	cache.ApplyMessage(mcm(t, `{"op":"mcm","mc":[{"id":"1.1","img":true,"rc":[{"id":11,"atb":[[2.0,7]]}]}]}`))
+
+	book, ok := cache.MarketBook("1.1")
+	require.True(t, ok)
+	require.Len(t, book.Runners, 1)
+	assert.Equal(t, []types.RunnerPrice{{Price: 2.0, Size: 7}}, book.Runners[0].Ex.Back)
+}
+
+func TestMarketCache_EvictsClosedMarkets(t *testing.T) {
+	var closed []types.ListMarketBookResponse
+	cache := stream.NewMarketCache(stream.WithClosedMarketEviction(func(book types.ListMarketBookResponse) {
+		closed = append(closed, book)
+	}))
+
+	cache.ApplyMessage(mcm(t, `{"op":"mcm","pt":1,"mc":[
+		{"id":"1.1","img":true,"marketDefinition":{"status":"OPEN","runners":[{"id":11,"sortPriority":1,"status":"ACTIVE"}]},"rc":[{"id":11,"ltp":2.5}]},
+		{"id":"1.2","img":true,"marketDefinition":{"status":"OPEN"}}]}`))
+
+	cache.ApplyMessage(mcm(t, `{"op":"mcm","pt":2,"mc":[{"id":"1.1",
+		"marketDefinition":{"status":"CLOSED","runners":[{"id":11,"sortPriority":1,"status":"WINNER"}]}}]}`))
+
+	// The hook gets the final book and it can still be read until the next change
+	require.Len(t, closed, 1)
+	assert.Equal(t, types.CLOSED, closed[0].Status)
+	assert.Equal(t, float32(2.5), closed[0].Runners[0].LastPriceTraded)
+	book, ok := cache.MarketBook("1.1")
+	require.True(t, ok)
+	assert.Equal(t, types.RunnerStatus("WINNER"), book.Runners[0].Status)
+
+	cache.ApplyMessage(mcm(t, `{"op":"mcm","pt":3,"mc":[{"id":"1.2","tv":5}]}`))
+
+	_, ok = cache.MarketBook("1.1")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"1.2"}, cache.MarketIds())
+
+	// Repeating the closed definition does not report the market twice
+	cache.ApplyMessage(mcm(t, `{"op":"mcm","pt":4,"mc":[{"id":"1.2","marketDefinition":{"status":"CLOSED"}}]}`))
+	cache.ApplyMessage(mcm(t, `{"op":"mcm","pt":5,"mc":[{"id":"1.3","img":true,"marketDefinition":{"status":"OPEN"}}]}`))
+	assert.Len(t, closed, 2)
+	assert.Equal(t, []string{"1.3"}, cache.MarketIds())
+}
+
+func TestMarketCache_KeepsClosedMarketsByDefault(t *testing.T) {
+	cache := stream.NewMarketCache()
+
+	cache.ApplyMessage(mcm(t, `{"op":"mcm","pt":1,"mc":[{"id":"1.1","img":true,"marketDefinition":{"status":"CLOSED"}}]}`))
+	cache.ApplyMessage(mcm(t, `{"op":"mcm","pt":2,"mc":[{"id":"1.2","img":true,"marketDefinition":{"status":"OPEN"}}]}`))
+
+	assert.Equal(t, []string{"1.1", "1.2"}, cache.MarketIds())
+}
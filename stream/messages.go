@@ -0,0 +1,125 @@
+// stream/messages.go
+
+package stream
+
+import "time"
+
+// Wire format of the Exchange Stream API
+// Every message is a single JSON object terminated by CRLF
+
+const (
+	OP_AUTHENTICATION = "authentication"
+	OP_HEARTBEAT      = "heartbeat"
+	OP_MARKET_SUB     = "marketSubscription"
+	OP_ORDER_SUB      = "orderSubscription"
+	OP_CONNECTION     = "connection"
+	OP_STATUS         = "status"
+	OP_MCM            = "mcm"
+	OP_OCM            = "ocm"
+)
+
+type StatusCode string
+
+const (
+	STATUS_SUCCESS StatusCode = "SUCCESS"
+	STATUS_FAILURE StatusCode = "FAILURE"
+)
+
+// Change types of mcm/ocm messages
+type ChangeType string
+
+const (
+	CT_HEARTBEAT   ChangeType = "HEARTBEAT"
+	CT_SUB_IMAGE   ChangeType = "SUB_IMAGE"
+	CT_RESUB_DELTA ChangeType = "RESUB_DELTA"
+)
+
+// Large images are split across several messages
+const (
+	SEG_START = "SEG_START"
+	SEG       = "SEG"
+	SEG_END   = "SEG_END"
+)
+
+// Request sent to Betfair
+// Only the fields relevant to Op are populated
+type RequestMessage struct {
+	Op string `json:"op"`
+	Id int64  `json:"id"`
+
+	// authentication
+	AppKey  string `json:"appKey,omitempty"`
+	Session string `json:"session,omitempty"`
+
+	// marketSubscription
+	MarketFilter     *MarketFilter     `json:"marketFilter,omitempty"`
+	MarketDataFilter *MarketDataFilter `json:"marketDataFilter,omitempty"`
+
+	// orderSubscription
+	OrderFilter *OrderFilter `json:"orderFilter,omitempty"`
+
+	// market and order subscriptions, set to resume from where a previous subscription left off
+	InitialClk string `json:"initialClk,omitempty"`
+	Clk        string `json:"clk,omitempty"`
+
+	// market and order subscriptions
+	ConflateMs  int64 `json:"conflateMs,omitempty"`
+	HeartbeatMs int64 `json:"heartbeatMs,omitempty"`
+}
+
+// Any message received from Betfair
+// Only the fields relevant to Op are populated
+type ResponseMessage struct {
+	Op string `json:"op"`
+	Id int64  `json:"id,omitempty"`
+
+	// connection
+	ConnectionId string `json:"connectionId,omitempty"`
+
+	// status
+	StatusCode           StatusCode `json:"statusCode,omitempty"`
+	ErrorCode            ErrorCode  `json:"errorCode,omitempty"`
+	ErrorMessage         string     `json:"errorMessage,omitempty"`
+	ConnectionClosed     bool       `json:"connectionClosed,omitempty"`
+	ConnectionsAvailable int        `json:"connectionsAvailable,omitempty"`
+
+	// mcm / ocm
+	Ct          ChangeType `json:"ct,omitempty"`
+	Clk         string     `json:"clk,omitempty"`
+	InitialClk  string     `json:"initialClk,omitempty"`
+	Pt          int64      `json:"pt,omitempty"` // publish time, epoch millis
+	HeartbeatMs int64      `json:"heartbeatMs,omitempty"`
+	ConflateMs  int64      `json:"conflateMs,omitempty"`
+	SegmentType string     `json:"segmentType,omitempty"`
+	Con         bool       `json:"con,omitempty"` // conflated, more than one change was merged into this message
+
+	// mcm
+	Mc []*MarketChange `json:"mc,omitempty"`
+
+	// ocm
+	Oc []*OrderMarketChange `json:"oc,omitempty"`
+
+	// Local time the message was read off the socket, not part of the wire format
+	ReceivedAt time.Time `json:"-"`
+}
+
+// Time from Betfair publishing the message to us reading it
+// Returns false for messages without a publish time
+func (m *ResponseMessage) Latency() (time.Duration, bool) {
+	if m.Pt == 0 || m.ReceivedAt.IsZero() {
+		return 0, false
+	}
+
+	latency := m.ReceivedAt.Sub(time.UnixMilli(m.Pt))
+	if latency < 0 {
+		// Local clock behind Betfair's
+		latency = 0
+	}
+
+	return latency, true
+}
+
+// Change messages carry market or order deltas, everything else is connection control
+func (m *ResponseMessage) IsChange() bool {
+	return m.Op == OP_MCM || m.Op == OP_OCM
+}
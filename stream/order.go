@@ -0,0 +1,72 @@
+// stream/order.go
+
+package stream
+
+// Order subscription filter and order change messages
+
+type OrderFilter struct {
+	IncludeOverallPosition        *bool    `json:"includeOverallPosition,omitempty"` // defaults to true on Betfair's side
+	AccountIds                    []int64  `json:"accountIds,omitempty"`
+	CustomerStrategyRefs          []string `json:"customerStrategyRefs,omitempty"`
+	PartitionMatchedByStrategyRef bool     `json:"partitionMatchedByStrategyRef,omitempty"`
+}
+
+type OrderMarketChange struct {
+	Id        string               `json:"id"`
+	AccountId int64                `json:"accountId,omitempty"`
+	Closed    bool                 `json:"closed,omitempty"`
+	FullImage bool                 `json:"fullImage,omitempty"`
+	Orc       []*OrderRunnerChange `json:"orc,omitempty"`
+}
+
+// Matched backs/lays are [price, size] deltas, a size of 0 removes the price
+type OrderRunnerChange struct {
+	Id        int64                           `json:"id"`
+	Hc        float64                         `json:"hc,omitempty"`
+	FullImage bool                            `json:"fullImage,omitempty"`
+	Uo        []*Order                        `json:"uo,omitempty"`
+	Mb        [][]float64                     `json:"mb,omitempty"`
+	Ml        [][]float64                     `json:"ml,omitempty"`
+	Smc       map[string]*StrategyMatchChange `json:"smc,omitempty"`
+}
+
+type StrategyMatchChange struct {
+	Mb [][]float64 `json:"mb,omitempty"`
+	Ml [][]float64 `json:"ml,omitempty"`
+}
+
+const (
+	ORDER_SIDE_BACK = "B"
+	ORDER_SIDE_LAY  = "L"
+
+	ORDER_STATUS_EXECUTABLE         = "E"
+	ORDER_STATUS_EXECUTION_COMPLETE = "EC"
+)
+
+// Order as sent on the stream, always a full image of the order
+// Dates are epoch millis
+type Order struct {
+	Id                    string  `json:"id"`
+	Price                 float64 `json:"p"`
+	Size                  float64 `json:"s"`
+	BspLiability          float64 `json:"bsp,omitempty"`
+	Side                  string  `json:"side"`
+	Status                string  `json:"status"`
+	PersistenceType       string  `json:"pt"`
+	OrderType             string  `json:"ot"`
+	PlacedDate            int64   `json:"pd"`
+	MatchedDate           int64   `json:"md,omitempty"`
+	CancelledDate         int64   `json:"cd,omitempty"`
+	LapsedDate            int64   `json:"ld,omitempty"`
+	LapseStatusReasonCode string  `json:"lsrc,omitempty"`
+	AveragePriceMatched   float64 `json:"avp,omitempty"`
+	SizeMatched           float64 `json:"sm"`
+	SizeRemaining         float64 `json:"sr"`
+	SizeLapsed            float64 `json:"sl"`
+	SizeCancelled         float64 `json:"sc"`
+	SizeVoided            float64 `json:"sv"`
+	RegulatorAuthCode     string  `json:"rac,omitempty"`
+	RegulatorCode         string  `json:"rc,omitempty"`
+	OrderRef              string  `json:"rfo,omitempty"`
+	StrategyRef           string  `json:"rfs,omitempty"`
+}
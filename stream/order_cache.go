@@ -0,0 +1,550 @@
+// stream/order_cache.go
+
+package stream
+
+// Local view of our orders and matched positions built from ocm deltas.
+// Emits an OrderEvent for every observable change to an order.
+// Complete orders are evicted once the retention window (by publish time) has passed since they completed.
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+const defaultOrderRetention = time.Minute
+
+type OrderEventType string
+
+const (
+	ORDER_PLACED    OrderEventType = "PLACED"
+	ORDER_MATCHED   OrderEventType = "MATCHED"
+	ORDER_CANCELLED OrderEventType = "CANCELLED"
+	ORDER_LAPSED    OrderEventType = "LAPSED"
+	ORDER_VOIDED    OrderEventType = "VOIDED"
+	ORDER_COMPLETE  OrderEventType = "COMPLETE" // no longer executable, follows the event that completed it
+
+	ORDER_MARKET_CLOSED OrderEventType = "MARKET_CLOSED" // the market closed, Order and SelectionId are empty
+)
+
+type OrderEvent struct {
+	Type        OrderEventType
+	MarketId    string
+	SelectionId int64
+	Handicap    float64
+	Order       Order
+	Previous    *Order // nil for ORDER_PLACED
+	Pt          int64  // publish time of the change, epoch millis
+}
+
+// Matched position on one side of a runner, by price
+type MatchedPositions struct {
+	Backs []types.RunnerPrice
+	Lays  []types.RunnerPrice
+}
+
+// Snapshot of our orders and positions on a single runner
+type RunnerOrders struct {
+	MarketId    string
+	SelectionId int64
+	Handicap    float64
+	Orders      []Order
+	Matched     MatchedPositions
+	Strategies  map[string]MatchedPositions // populated with partitionMatchedByStrategyRef
+}
+
+type orderRunnerCache struct {
+	key        runnerKey
+	orders     map[string]*Order
+	mb         priceLadder
+	ml         priceLadder
+	strategies map[string][2]priceLadder
+}
+
+type orderMarketCache struct {
+	id      string
+	closed  bool
+	runners map[runnerKey]*orderRunnerCache
+}
+
+type OrderCache struct {
+	mu        sync.RWMutex
+	markets   map[string]*orderMarketCache
+	onEvent   func(OrderEvent)
+	retention time.Duration
+
+	// Complete orders in the order they completed, waiting for the retention window to pass
+	completed []completedOrder
+	// Ids of evicted orders per market, so a later image repeating them is ignored
+	evicted map[string]map[string]struct{}
+}
+
+type completedOrder struct {
+	marketId string
+	key      runnerKey
+	id       string
+	pt       int64
+}
+
+type OrderCacheOption func(*OrderCache)
+
+// How long complete orders stay in the cache after completing, defaults to 1 minute
+// 0 evicts them as soon as their ORDER_COMPLETE event is emitted, negative keeps them until the market is removed
+func WithOrderRetention(d time.Duration) OrderCacheOption {
+	return func(c *OrderCache) {
+		c.retention = d
+	}
+}
+
+// onEvent is optional and called synchronously while applying changes
+func NewOrderCache(onEvent func(OrderEvent), opts ...OrderCacheOption) *OrderCache {
+	c := &OrderCache{
+		markets:   make(map[string]*orderMarketCache),
+		onEvent:   onEvent,
+		retention: defaultOrderRetention,
+		evicted:   make(map[string]map[string]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Applies every order change of an ocm message
+func (c *OrderCache) ApplyMessage(msg *ResponseMessage) {
+	if msg.Op != OP_OCM || len(msg.Oc) == 0 {
+		return
+	}
+
+	var events []OrderEvent
+
+	c.mu.Lock()
+	for _, oc := range msg.Oc {
+		events = c.apply(msg.Pt, oc, events)
+	}
+	c.evict(msg.Pt)
+	c.mu.Unlock()
+
+	c.emit(events)
+}
+
+// Applies a single order market change published at pt (epoch millis)
+func (c *OrderCache) Apply(pt int64, oc *OrderMarketChange) {
+	c.mu.Lock()
+	events := c.apply(pt, oc, nil)
+	c.evict(pt)
+	c.mu.Unlock()
+
+	c.emit(events)
+}
+
+func (c *OrderCache) emit(events []OrderEvent) {
+	if c.onEvent == nil {
+		return
+	}
+
+	for _, e := range events {
+		c.onEvent(e)
+	}
+}
+
+func (c *OrderCache) apply(pt int64, oc *OrderMarketChange, events []OrderEvent) []OrderEvent {
+	// A full image replaces the market's orders, the previous ones are only kept to diff against
+	m, ok := c.markets[oc.Id]
+	wasClosed := ok && m.closed
+	var stale *orderMarketCache
+	if !ok || oc.FullImage {
+		if ok {
+			stale = m
+		}
+		m = &orderMarketCache{id: oc.Id, runners: make(map[runnerKey]*orderRunnerCache)}
+		c.markets[oc.Id] = m
+	}
+
+	m.closed = oc.Closed
+
+	for _, orc := range oc.Orc {
+		key := runnerKey{id: orc.Id, hc: orc.Hc}
+
+		var replaced map[string]*Order
+		if stale != nil {
+			if r, ok := stale.runners[key]; ok {
+				replaced = r.orders
+			}
+		}
+
+		r, ok := m.runners[key]
+		if !ok || orc.FullImage {
+			if ok {
+				replaced = r.orders
+			}
+			r = newOrderRunnerCache(key)
+			m.runners[key] = r
+		}
+
+		r.mb.update(orc.Mb)
+		r.ml.update(orc.Ml)
+
+		for ref, smc := range orc.Smc {
+			ladders, ok := r.strategies[ref]
+			if !ok {
+				ladders = [2]priceLadder{{}, {}}
+				r.strategies[ref] = ladders
+			}
+			ladders[0].update(smc.Mb)
+			ladders[1].update(smc.Ml)
+		}
+
+		for _, o := range orc.Uo {
+			previous, seen := r.orders[o.Id]
+			if !seen && replaced != nil {
+				previous, seen = replaced[o.Id]
+			}
+			delete(replaced, o.Id)
+
+			complete := o.Status == ORDER_STATUS_EXECUTION_COMPLETE
+			if _, gone := c.evicted[oc.Id][o.Id]; gone {
+				if complete {
+					continue
+				}
+				delete(c.evicted[oc.Id], o.Id)
+			}
+
+			order := *o
+			r.orders[o.Id] = &order
+
+			if complete && (!seen || previous.Status != ORDER_STATUS_EXECUTION_COMPLETE) {
+				c.completed = append(c.completed, completedOrder{marketId: oc.Id, key: key, id: o.Id, pt: pt})
+			}
+
+			base := OrderEvent{MarketId: oc.Id, SelectionId: key.id, Handicap: key.hc, Order: order, Pt: pt}
+			events = append(events, orderEvents(base, previous, seen)...)
+		}
+
+		if orc.FullImage || stale != nil {
+			events = dropOrders(pt, oc.Id, key, replaced, events)
+		}
+	}
+
+	// Runners the image left out entirely
+	if stale != nil {
+		for key, r := range stale.runners {
+			events = dropOrders(pt, oc.Id, key, r.orders, events)
+		}
+	}
+
+	if m.closed && !wasClosed {
+		events = append(events, OrderEvent{Type: ORDER_MARKET_CLOSED, MarketId: oc.Id, Pt: pt})
+	}
+
+	return events
+}
+
+// Applies a full image (ct=SUB_IMAGE) of every order, e.g. after a reconnect that could not resume
+// Markets missing from the image are dropped along with their orders
+func (c *OrderCache) ReplaceAll(msg *ResponseMessage) {
+	var events []OrderEvent
+
+	c.mu.Lock()
+	imaged := make(map[string]bool, len(msg.Oc))
+	for _, oc := range msg.Oc {
+		imaged[oc.Id] = true
+
+		full := *oc
+		full.FullImage = true
+		events = c.apply(msg.Pt, &full, events)
+	}
+
+	ids := make([]string, 0, len(c.markets))
+	for id := range c.markets {
+		if !imaged[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		for key, r := range c.markets[id].runners {
+			events = dropOrders(msg.Pt, id, key, r.orders, events)
+		}
+		delete(c.markets, id)
+	}
+	c.evict(msg.Pt)
+	c.mu.Unlock()
+
+	c.emit(events)
+}
+
+// Completes executable orders a full image no longer includes, they finished while we were not listening
+// How the remainder finished is unknown, so it is reported as lapsed
+func dropOrders(pt int64, marketId string, key runnerKey, orders map[string]*Order, events []OrderEvent) []OrderEvent {
+	ids := make([]string, 0, len(orders))
+	for id := range orders {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		previous := orders[id]
+		delete(orders, id)
+
+		if previous.Status == ORDER_STATUS_EXECUTION_COMPLETE {
+			continue
+		}
+
+		order := *previous
+		order.Status = ORDER_STATUS_EXECUTION_COMPLETE
+		order.SizeLapsed += order.SizeRemaining
+		order.SizeRemaining = 0
+
+		base := OrderEvent{MarketId: marketId, SelectionId: key.id, Handicap: key.hc, Order: order, Pt: pt}
+		events = append(events, orderEvents(base, previous, true)...)
+	}
+
+	return events
+}
+
+func newOrderRunnerCache(key runnerKey) *orderRunnerCache {
+	return &orderRunnerCache{
+		key:        key,
+		orders:     make(map[string]*Order),
+		mb:         priceLadder{},
+		ml:         priceLadder{},
+		strategies: make(map[string][2]priceLadder),
+	}
+}
+
+// Drops complete orders whose retention window has passed by pt
+func (c *OrderCache) evict(pt int64) {
+	if c.retention < 0 {
+		return
+	}
+
+	n := 0
+	for _, done := range c.completed {
+		if done.pt+c.retention.Milliseconds() > pt {
+			break
+		}
+		n++
+
+		m, ok := c.markets[done.marketId]
+		if !ok {
+			continue
+		}
+		r, ok := m.runners[done.key]
+		if !ok {
+			continue
+		}
+
+		// Still complete, it could have been reset by a later update
+		if o, ok := r.orders[done.id]; ok && o.Status == ORDER_STATUS_EXECUTION_COMPLETE {
+			delete(r.orders, done.id)
+
+			if c.evicted[done.marketId] == nil {
+				c.evicted[done.marketId] = make(map[string]struct{})
+			}
+			c.evicted[done.marketId][done.id] = struct{}{}
+		}
+	}
+
+	c.completed = slices.Delete(c.completed, 0, n)
+}
+
+func orderEvents(base OrderEvent, previous *Order, seen bool) []OrderEvent {
+	var events []OrderEvent
+	order := base.Order
+
+	add := func(t OrderEventType) {
+		e := base
+		e.Type = t
+		if seen {
+			prev := *previous
+			e.Previous = &prev
+		}
+		events = append(events, e)
+	}
+
+	prev := Order{Status: ORDER_STATUS_EXECUTABLE}
+	if seen {
+		prev = *previous
+	} else {
+		add(ORDER_PLACED)
+	}
+
+	if order.SizeMatched > prev.SizeMatched {
+		add(ORDER_MATCHED)
+	}
+	if order.SizeCancelled > prev.SizeCancelled {
+		add(ORDER_CANCELLED)
+	}
+	if order.SizeLapsed > prev.SizeLapsed {
+		add(ORDER_LAPSED)
+	}
+	if order.SizeVoided > prev.SizeVoided {
+		add(ORDER_VOIDED)
+	}
+	if order.Status == ORDER_STATUS_EXECUTION_COMPLETE && prev.Status != ORDER_STATUS_EXECUTION_COMPLETE {
+		add(ORDER_COMPLETE)
+	}
+
+	return events
+}
+
+func (c *OrderCache) MarketIds() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ids := make([]string, 0, len(c.markets))
+	for id := range c.markets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Reports whether Betfair has flagged the market as closed for our orders
+func (c *OrderCache) IsClosed(marketId string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, ok := c.markets[marketId]
+	return ok && m.closed
+}
+
+// Returns every order in the market, executable and complete orders still within the retention window
+func (c *OrderCache) Orders(marketId string) []Order {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, ok := c.markets[marketId]
+	if !ok {
+		return nil
+	}
+
+	var orders []Order
+	for _, r := range m.runners {
+		for _, o := range r.orders {
+			orders = append(orders, *o)
+		}
+	}
+	sortOrders(orders)
+
+	return orders
+}
+
+// Returns the executable (unmatched) orders in the market
+func (c *OrderCache) UnmatchedOrders(marketId string) []Order {
+	var unmatched []Order
+	for _, o := range c.Orders(marketId) {
+		if o.Status == ORDER_STATUS_EXECUTABLE {
+			unmatched = append(unmatched, o)
+		}
+	}
+
+	return unmatched
+}
+
+// Returns orders and matched positions for a runner
+func (c *OrderCache) Runner(marketId string, selectionId int64, handicap float64) (RunnerOrders, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, ok := c.markets[marketId]
+	if !ok {
+		return RunnerOrders{}, false
+	}
+
+	r, ok := m.runners[runnerKey{id: selectionId, hc: handicap}]
+	if !ok {
+		return RunnerOrders{}, false
+	}
+
+	return r.snapshot(marketId), true
+}
+
+// Returns orders and matched positions for every runner in the market
+func (c *OrderCache) Runners(marketId string) []RunnerOrders {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m, ok := c.markets[marketId]
+	if !ok {
+		return nil
+	}
+
+	result := make([]RunnerOrders, 0, len(m.runners))
+	for _, r := range m.runners {
+		result = append(result, r.snapshot(marketId))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SelectionId != result[j].SelectionId {
+			return result[i].SelectionId < result[j].SelectionId
+		}
+		return result[i].Handicap < result[j].Handicap
+	})
+
+	return result
+}
+
+func (c *OrderCache) Remove(marketId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.markets, marketId)
+	delete(c.evicted, marketId)
+	c.completed = slices.DeleteFunc(c.completed, func(done completedOrder) bool { return done.marketId == marketId })
+}
+
+func (c *OrderCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.markets = make(map[string]*orderMarketCache)
+	c.evicted = make(map[string]map[string]struct{})
+	c.completed = nil
+}
+
+func (r *orderRunnerCache) snapshot(marketId string) RunnerOrders {
+	ro := RunnerOrders{
+		MarketId:    marketId,
+		SelectionId: r.key.id,
+		Handicap:    r.key.hc,
+		Matched: MatchedPositions{
+			Backs: r.mb.sorted(false),
+			Lays:  r.ml.sorted(false),
+		},
+	}
+
+	for _, o := range r.orders {
+		ro.Orders = append(ro.Orders, *o)
+	}
+	sortOrders(ro.Orders)
+
+	if len(r.strategies) > 0 {
+		ro.Strategies = make(map[string]MatchedPositions, len(r.strategies))
+		for ref, ladders := range r.strategies {
+			ro.Strategies[ref] = MatchedPositions{
+				Backs: ladders[0].sorted(false),
+				Lays:  ladders[1].sorted(false),
+			}
+		}
+	}
+
+	return ro
+}
+
+func sortOrders(orders []Order) {
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].PlacedDate != orders[j].PlacedDate {
+			return orders[i].PlacedDate < orders[j].PlacedDate
+		}
+		return orders[i].Id < orders[j].Id
+	})
+}
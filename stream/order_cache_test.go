@@ -0,0 +1,174 @@
+// stream/order_cache_test.go
+
+package stream_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderCache_EmitsLifecycleEvents(t *testing.T) {
+	var events []stream.OrderEventType
+	cache := stream.NewOrderCache(func(e stream.OrderEvent) {
+		events = append(events, e.Type)
+	})
+
+	cache.ApplyMessage(mcm(t, `{"op":"ocm","pt":1,"oc":[{"id":"1.1","orc":[{"id":11,
+		"uo":[{"id":"100","p":2.5,"s":10,"side":"B","status":"E","pt":"L","ot":"L","pd":1,"sm":0,"sr":10}]}]}]}`))
+
+	cache.ApplyMessage(mcm(t, `{"op":"ocm","pt":2,"oc":[{"id":"1.1","orc":[{"id":11,"mb":[[2.5,4]],"smc":{"strat-a":{"mb":[[2.5,4]]}},
+		"uo":[{"id":"100","p":2.5,"s":10,"side":"B","status":"E","pt":"L","ot":"L","pd":1,"sm":4,"sr":6,"avp":2.5}]}]}]}`))
+
+	require.Len(t, cache.UnmatchedOrders("1.1"), 1)
+
+	cache.ApplyMessage(mcm(t, `{"op":"ocm","pt":3,"oc":[{"id":"1.1","orc":[{"id":11,
+		"uo":[{"id":"100","p":2.5,"s":10,"side":"B","status":"EC","pt":"L","ot":"L","pd":1,"sm":4,"sr":0,"sc":6}]}]}]}`))
+
+	assert.Equal(t, []stream.OrderEventType{
+		stream.ORDER_PLACED, stream.ORDER_MATCHED, stream.ORDER_CANCELLED, stream.ORDER_COMPLETE,
+	}, events)
+
+	assert.Empty(t, cache.UnmatchedOrders("1.1"))
+
+	runner, ok := cache.Runner("1.1", 11, 0)
+	require.True(t, ok)
+	assert.Equal(t, []types.RunnerPrice{{Price: 2.5, Size: 4}}, runner.Matched.Backs)
+	assert.Equal(t, []types.RunnerPrice{{Price: 2.5, Size: 4}}, runner.Strategies["strat-a"].Backs)
+	require.Len(t, runner.Orders, 1)
+	assert.Equal(t, 6.0, runner.Orders[0].SizeCancelled)
+}
+
+func ocmOrder(t *testing.T, pt int, id, status string, sizeMatched, sizeRemaining float64) *stream.ResponseMessage {
+	t.Helper()
+
+	return mcm(t, fmt.Sprintf(`{"op":"ocm","pt":%d,"oc":[{"id":"1.1","orc":[{"id":11,
+		"uo":[{"id":%q,"p":2.5,"s":10,"side":"B","status":%q,"pt":"L","ot":"L","pd":1,"sm":%g,"sr":%g,"sl":%g}]}]}]}`,
+		pt, id, status, sizeMatched, sizeRemaining, 10-sizeMatched-sizeRemaining))
+}
+
+func orderIds(orders []stream.Order) []string {
+	var ids []string
+	for _, o := range orders {
+		ids = append(ids, o.Id)
+	}
+	return ids
+}
+
+func TestOrderCache_EvictsCompleteOrdersAfterRetention(t *testing.T) {
+	var events []stream.OrderEventType
+	cache := stream.NewOrderCache(func(e stream.OrderEvent) {
+		events = append(events, e.Type)
+	}, stream.WithOrderRetention(time.Second))
+
+	cache.ApplyMessage(ocmOrder(t, 1000, "100", "E", 0, 10))
+	cache.ApplyMessage(ocmOrder(t, 1000, "101", "E", 0, 10))
+	cache.ApplyMessage(ocmOrder(t, 1500, "100", "EC", 4, 0))
+
+	// Inside the window the complete order is still visible
+	cache.ApplyMessage(ocmOrder(t, 2000, "101", "E", 2, 8))
+	assert.Equal(t, []string{"100", "101"}, orderIds(cache.Orders("1.1")))
+
+	cache.ApplyMessage(ocmOrder(t, 2500, "101", "E", 3, 7))
+	assert.Equal(t, []string{"101"}, orderIds(cache.Orders("1.1")))
+
+	// Matched positions are not affected by evicting orders
+	runner, ok := cache.Runner("1.1", 11, 0)
+	require.True(t, ok)
+	assert.Equal(t, []string{"101"}, orderIds(runner.Orders))
+
+	// An image repeating the evicted order neither restores it nor emits events again
+	before := len(events)
+	cache.ApplyMessage(ocmOrder(t, 2600, "100", "EC", 4, 0))
+	assert.Equal(t, []string{"101"}, orderIds(cache.Orders("1.1")))
+	assert.Len(t, events, before)
+}
+
+func TestOrderCache_EvictsOnComplete(t *testing.T) {
+	var events []stream.OrderEvent
+	cache := stream.NewOrderCache(func(e stream.OrderEvent) {
+		events = append(events, e)
+	}, stream.WithOrderRetention(0))
+
+	cache.ApplyMessage(ocmOrder(t, 1, "100", "E", 0, 10))
+	cache.ApplyMessage(ocmOrder(t, 2, "100", "EC", 0, 0))
+
+	assert.Empty(t, cache.Orders("1.1"))
+
+	// The event still carries the final state of the order
+	require.NotEmpty(t, events)
+	last := events[len(events)-1]
+	assert.Equal(t, stream.ORDER_COMPLETE, last.Type)
+	assert.Equal(t, 10.0, last.Order.SizeLapsed)
+}
+
+func TestOrderCache_NegativeRetentionKeepsCompleteOrders(t *testing.T) {
+	cache := stream.NewOrderCache(nil, stream.WithOrderRetention(-1))
+
+	cache.ApplyMessage(ocmOrder(t, 1, "100", "EC", 10, 0))
+	cache.ApplyMessage(ocmOrder(t, 24*60*60*1000, "101", "E", 0, 10))
+
+	assert.Equal(t, []string{"100", "101"}, orderIds(cache.Orders("1.1")))
+
+	cache.Remove("1.1")
+	assert.Empty(t, cache.Orders("1.1"))
+}
+
+func TestOrderCache_RunnerImageCompletesMissingOrders(t *testing.T) {
+	var events []stream.OrderEvent
+	cache := stream.NewOrderCache(func(e stream.OrderEvent) {
+		events = append(events, e)
+	})
+
+	cache.ApplyMessage(ocmOrder(t, 1, "100", "E", 0, 10))
+	cache.ApplyMessage(ocmOrder(t, 1, "101", "E", 0, 10))
+	events = nil
+
+	// Only 101 is still around, 100 finished while we were not listening
+	cache.ApplyMessage(mcm(t, `{"op":"ocm","pt":2,"oc":[{"id":"1.1","orc":[{"id":11,"fullImage":true,
+		"uo":[{"id":"101","p":2.5,"s":10,"side":"B","status":"E","pt":"L","ot":"L","pd":1,"sm":0,"sr":10}]}]}]}`))
+
+	assert.Equal(t, []string{"101"}, orderIds(cache.Orders("1.1")))
+
+	var kinds []stream.OrderEventType
+	for _, e := range events {
+		assert.Equal(t, "100", e.Order.Id)
+		kinds = append(kinds, e.Type)
+	}
+	assert.Equal(t, []stream.OrderEventType{stream.ORDER_LAPSED, stream.ORDER_COMPLETE}, kinds)
+	assert.Equal(t, stream.ORDER_STATUS_EXECUTION_COMPLETE, events[len(events)-1].Order.Status)
+	assert.Equal(t, 10.0, events[len(events)-1].Order.SizeLapsed)
+}
+
+func TestOrderCache_ReplaceAllDropsMarketsMissingFromImage(t *testing.T) {
+	var events []stream.OrderEvent
+	cache := stream.NewOrderCache(func(e stream.OrderEvent) {
+		events = append(events, e)
+	})
+
+	cache.ApplyMessage(ocmOrder(t, 1, "100", "E", 4, 6))
+	cache.ApplyMessage(mcm(t, `{"op":"ocm","pt":1,"oc":[{"id":"1.2","orc":[{"id":22,
+		"uo":[{"id":"200","p":3,"s":5,"side":"L","status":"E","pt":"L","ot":"L","pd":1,"sm":0,"sr":5}]}]}]}`))
+	events = nil
+
+	// Image only has market 1.2 with order 200 now matched
+	cache.ReplaceAll(mcm(t, `{"op":"ocm","ct":"SUB_IMAGE","pt":2,"oc":[{"id":"1.2","orc":[{"id":22,
+		"uo":[{"id":"200","p":3,"s":5,"side":"L","status":"EC","pt":"L","ot":"L","pd":1,"sm":5,"sr":0}]}]}]}`))
+
+	assert.Equal(t, []string{"1.2"}, cache.MarketIds())
+	assert.Empty(t, cache.UnmatchedOrders("1.1"))
+
+	completed := make(map[string]bool)
+	for _, e := range events {
+		if e.Type == stream.ORDER_COMPLETE {
+			completed[e.Order.Id] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{"100": true, "200": true}, completed)
+}
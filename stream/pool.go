@@ -0,0 +1,425 @@
+// stream/pool.go
+
+package stream
+
+// Spreads one logical market subscription across several connections.
+// Betfair caps the markets per subscription, so the filter is resolved to market ids
+// which are partitioned across clients sharing one market cache and dispatcher.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMarketsPerConn    = 200
+	defaultMaxConns          = 10
+	defaultRebalanceInterval = time.Minute
+)
+
+// Resolves a market filter to the ids of the markets currently matching it
+// e.g. by calling ListMarketCatalogue with an equivalent types.MarketFilter
+type MarketResolver func(ctx context.Context, filter MarketFilter) ([]string, error)
+
+type PoolConfig struct {
+	ClientConfig // applied to every connection
+
+	Resolve           MarketResolver // required
+	MarketsPerConn    int            // defaults to 200
+	MaxConns          int            // defaults to 10
+	RebalanceInterval time.Duration  // how often the filter is resolved again, defaults to 1 minute
+}
+
+type Pool struct {
+	cfg  PoolConfig
+	auth Authenticator
+
+	markets    *MarketCache
+	dispatcher *Dispatcher
+
+	// Serialises Rebalance, which does network I/O without holding mu
+	rebalanceMu sync.Mutex
+
+	mu         sync.Mutex
+	shards     []*poolShard
+	filter     *MarketFilter
+	dataFilter MarketDataFilter
+	opts       []SubscriptionOption
+	generation int // bumped when dataFilter or opts change, shards on an older one subscribe again
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	closed bool
+}
+
+type poolShard struct {
+	client     *Client
+	marketIds  map[string]struct{} // markets of the last accepted subscription
+	subscribed bool
+	generation int // pool generation of the last accepted subscription
+}
+
+func NewPool(cfg PoolConfig, auth Authenticator) (*Pool, error) {
+	if cfg.Resolve == nil {
+		return nil, fmt.Errorf("stream pool needs a market resolver")
+	}
+	if cfg.MarketsPerConn <= 0 {
+		cfg.MarketsPerConn = defaultMarketsPerConn
+	}
+	if cfg.MaxConns <= 0 {
+		cfg.MaxConns = defaultMaxConns
+	}
+	if cfg.RebalanceInterval <= 0 {
+		cfg.RebalanceInterval = defaultRebalanceInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	markets := newMarketCache(cfg.ClientConfig)
+
+	return &Pool{
+		cfg:        cfg,
+		auth:       auth,
+		markets:    markets,
+		dispatcher: NewDispatcher(markets),
+		ctx:        ctx,
+		cancel:     cancel,
+	}, nil
+}
+
+// Subscribes to every market matching filter, opening connections as needed
+// The filter is resolved again every RebalanceInterval so new markets are picked up and closed ones dropped
+func (p *Pool) Subscribe(ctx context.Context, filter MarketFilter, dataFilter MarketDataFilter, opts ...SubscriptionOption) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return fmt.Errorf("stream pool closed")
+	}
+
+	first := p.filter == nil
+	if !first && !sameSubscription(p.dataFilter, p.opts, dataFilter, opts) {
+		p.generation++
+	}
+	p.filter = &filter
+	p.dataFilter = dataFilter
+	p.opts = opts
+	p.mu.Unlock()
+
+	if err := p.Rebalance(ctx); err != nil {
+		return err
+	}
+
+	if first {
+		p.wg.Add(1)
+		go p.rebalanceLoop()
+	}
+
+	return nil
+}
+
+// Resolves the filter and moves markets between connections
+// Markets that no longer match are dropped from the cache, new ones go to the connection with the most room.
+// Connections that gave up reconnecting are replaced. A shard only takes on its new markets once its
+// subscription succeeds, so a failed one is retried on the next pass
+func (p *Pool) Rebalance(ctx context.Context) error {
+	p.rebalanceMu.Lock()
+	defer p.rebalanceMu.Unlock()
+
+	p.mu.Lock()
+	if p.closed || p.filter == nil {
+		p.mu.Unlock()
+		return nil
+	}
+	filter := *p.filter
+	p.mu.Unlock()
+
+	ids, err := p.cfg.Resolve(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("unable to resolve stream pool markets: %w", err)
+	}
+
+	var errs []error
+
+	p.mu.Lock()
+	var dead []*poolShard
+	live := make([]*poolShard, 0, len(p.shards))
+	for _, shard := range p.shards {
+		if shard.client.gaveUp.Load() {
+			dead = append(dead, shard)
+			continue
+		}
+		live = append(live, shard)
+	}
+	p.shards = live
+	plan, fresh, unplaced := p.plan(ids)
+	generation := p.generation
+	p.mu.Unlock()
+
+	for _, shard := range dead {
+		errs = append(errs, fmt.Errorf("stream pool connection stopped reconnecting, moving its %d markets", len(shard.marketIds)))
+		p.closeShard(shard)
+	}
+
+	if unplaced > 0 {
+		errs = append(errs, fmt.Errorf("stream pool full, %d markets not subscribed", unplaced))
+	}
+
+	for _, shard := range live {
+		next := plan[shard]
+		if shard.subscribed && shard.generation == generation && sameMarkets(next, shard.marketIds) {
+			continue
+		}
+
+		// Nothing left to follow, hand the connection back
+		if len(next) == 0 {
+			p.mu.Lock()
+			p.shards = slices.DeleteFunc(p.shards, func(s *poolShard) bool { return s == shard })
+			p.mu.Unlock()
+			p.closeShard(shard)
+			continue
+		}
+
+		if err := p.subscribeShard(ctx, shard, next); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, next := range fresh {
+		if err := p.openShard(ctx, next); err != nil {
+			errs = append(errs, fmt.Errorf("stream pool unable to open a connection for %d markets: %w", len(next), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Works out which markets each connection should follow, must hold p.mu
+// Markets stay where they are while they match, new ones go to the least loaded connection with room
+// and fresh lists the markets for connections still to be opened
+func (p *Pool) plan(ids []string) (map[*poolShard]map[string]struct{}, []map[string]struct{}, int) {
+	wanted := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[id] = struct{}{}
+	}
+
+	plan := make(map[*poolShard]map[string]struct{}, len(p.shards))
+	assigned := make(map[string]struct{})
+	for _, shard := range p.shards {
+		next := make(map[string]struct{}, len(shard.marketIds))
+		for id := range shard.marketIds {
+			if _, ok := wanted[id]; ok {
+				next[id] = struct{}{}
+				assigned[id] = struct{}{}
+			}
+		}
+		plan[shard] = next
+	}
+
+	var fresh []map[string]struct{}
+	unplaced := 0
+
+	for _, id := range ids {
+		if _, ok := assigned[id]; ok {
+			continue
+		}
+		assigned[id] = struct{}{}
+
+		var best map[string]struct{}
+		for _, shard := range p.shards {
+			if next := plan[shard]; len(next) < p.cfg.MarketsPerConn && (best == nil || len(next) < len(best)) {
+				best = next
+			}
+		}
+		for _, next := range fresh {
+			if len(next) < p.cfg.MarketsPerConn && (best == nil || len(next) < len(best)) {
+				best = next
+			}
+		}
+
+		if best == nil {
+			if len(p.shards)+len(fresh) >= p.cfg.MaxConns {
+				unplaced++
+				continue
+			}
+			best = make(map[string]struct{})
+			fresh = append(fresh, best)
+		}
+		best[id] = struct{}{}
+	}
+
+	return plan, fresh, unplaced
+}
+
+// New connections subscribe, existing ones change the filter of their live subscription
+// A changed data filter or options needs a fresh subscription, UpdateMarketSubscription keeps the old ones.
+// The shard's markets are only updated once Betfair accepts the subscription
+func (p *Pool) subscribeShard(ctx context.Context, shard *poolShard, next map[string]struct{}) error {
+	ids := make([]string, 0, len(next))
+	for id := range next {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	marketFilter := MarketFilter{MarketIds: ids}
+
+	p.mu.Lock()
+	dataFilter, opts, generation := p.dataFilter, p.opts, p.generation
+	p.mu.Unlock()
+
+	var err error
+	if shard.subscribed && shard.generation == generation {
+		_, err = shard.client.UpdateMarketSubscription(ctx, marketFilter)
+	} else {
+		err = shard.client.SubscribeMarkets(ctx, marketFilter, dataFilter, opts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	shard.marketIds = next
+	shard.subscribed = true
+	shard.generation = generation
+	p.mu.Unlock()
+
+	return nil
+}
+
+func sameSubscription(dataFilter MarketDataFilter, opts []SubscriptionOption, nextDataFilter MarketDataFilter, nextOpts []SubscriptionOption) bool {
+	return dataFilter.LadderLevels == nextDataFilter.LadderLevels &&
+		slices.Equal(dataFilter.Fields, nextDataFilter.Fields) &&
+		newSubscriptionOptions(opts) == newSubscriptionOptions(nextOpts)
+}
+
+// Opens a connection for markets that did not fit on the existing ones
+func (p *Pool) openShard(ctx context.Context, next map[string]struct{}) error {
+	client := newClient(p.cfg.ClientConfig, p.auth, p.markets, p.dispatcher)
+	client.shared = true
+
+	if err := client.Connect(ctx); err != nil {
+		client.Close()
+		return err
+	}
+
+	shard := &poolShard{client: client, marketIds: make(map[string]struct{})}
+	if err := p.subscribeShard(ctx, shard, next); err != nil {
+		p.closeShard(shard)
+		return err
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.closeShard(shard)
+		return fmt.Errorf("stream pool closed")
+	}
+	p.shards = append(p.shards, shard)
+	p.mu.Unlock()
+
+	return nil
+}
+
+func sameMarkets(a, b map[string]struct{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Closes a connection and drops its markets from the shared cache and dispatcher
+func (p *Pool) closeShard(shard *poolShard) {
+	shard.client.Close()
+
+	shard.client.mu.Lock()
+	for id := range shard.client.owned {
+		p.markets.Remove(id)
+		p.dispatcher.forget(id)
+	}
+	shard.client.mu.Unlock()
+}
+
+func (p *Pool) rebalanceLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.RebalanceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := p.Rebalance(p.ctx); err != nil && p.cfg.OnError != nil {
+			p.cfg.OnError(err)
+		}
+	}
+}
+
+// Merged cache of every connection
+func (p *Pool) Markets() *MarketCache {
+	return p.markets
+}
+
+// Delivers typed events for a single market, whichever connection it is on
+func (p *Pool) Listen(marketId string, opts ListenerOptions) *Listener {
+	return p.dispatcher.Listen(marketId, opts)
+}
+
+// Number of markets on each open connection
+func (p *Pool) Shards() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sizes := make([]int, len(p.shards))
+	for i, shard := range p.shards {
+		sizes[i] = len(shard.marketIds)
+	}
+
+	return sizes
+}
+
+// Stats of each open connection
+func (p *Pool) Stats() []Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]Stats, len(p.shards))
+	for i, shard := range p.shards {
+		stats[i] = shard.client.Stats()
+	}
+
+	return stats
+}
+
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return fmt.Errorf("stream pool already closed")
+	}
+	p.closed = true
+	shards := p.shards
+	p.shards = nil
+	p.mu.Unlock()
+
+	p.cancel()
+	p.wg.Wait()
+
+	for _, shard := range shards {
+		shard.client.Close()
+	}
+	p.dispatcher.CloseAll()
+
+	return nil
+}
@@ -0,0 +1,364 @@
+// stream/recorder.go
+
+package stream
+
+// Records raw stream messages to gzip compressed JSON lines files.
+// Each line is {"rt": <local receive time, unix nanos>, "msg": <message exactly as received>}.
+// Attach with Config.OnRawMessage = recorder.Record, recording never blocks the read loop.
+// Open files are flushed every FlushInterval so a crash loses at most that much of the recording.
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type SplitMode int
+
+const (
+	SPLIT_BY_DAY    SplitMode = iota // one file per UTC day
+	SPLIT_BY_MARKET                  // one file per market, non market messages go to CONTROL_FILE
+)
+
+const (
+	RECORDING_EXT = ".jsonl.gz"
+	CONTROL_FILE  = "control" + RECORDING_EXT
+
+	defaultRecorderBuffer    = 4096
+	defaultRecorderOpenFiles = 64
+	defaultRecorderFlush     = 5 * time.Second
+)
+
+// Market ids become file names, anything else is recorded in CONTROL_FILE
+var recordableMarketId = regexp.MustCompile(`^\d+\.\d+$`)
+
+type RecorderConfig struct {
+	Dir           string
+	Split         SplitMode
+	Buffer        int           // queued messages before new ones are dropped, default 4096
+	MaxOpenFiles  int           // per market mode only, least recently used files are closed, default 64
+	FlushInterval time.Duration // how often open files are flushed to disk, default 5 seconds
+	OnError       func(error)
+}
+
+type RecordedMessage struct {
+	ReceivedAt int64           `json:"rt"`
+	Msg        json.RawMessage `json:"msg"`
+}
+
+type recordFile struct {
+	file     *os.File
+	gz       *gzip.Writer
+	buf      *bufio.Writer
+	lastUsed int64
+}
+
+type Recorder struct {
+	cfg     RecorderConfig
+	queue   chan RecordedMessage
+	dropped atomic.Int64
+	closed  atomic.Bool
+	mu      sync.RWMutex // guards queue against send after close
+	done    chan struct{}
+	err     error
+
+	files map[string]*recordFile
+	seq   int64
+}
+
+func NewRecorder(cfg RecorderConfig) (*Recorder, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("recorder directory cannot be empty")
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = defaultRecorderBuffer
+	}
+	if cfg.MaxOpenFiles <= 0 {
+		cfg.MaxOpenFiles = defaultRecorderOpenFiles
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultRecorderFlush
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create recorder directory: %w", err)
+	}
+
+	r := &Recorder{
+		cfg:   cfg,
+		queue: make(chan RecordedMessage, cfg.Buffer),
+		done:  make(chan struct{}),
+		files: make(map[string]*recordFile),
+	}
+
+	go r.run()
+
+	return r, nil
+}
+
+// Queues a message for writing, never blocks
+// Messages are dropped (and counted) when the queue is full
+func (r *Recorder) Record(data []byte, receivedAt time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed.Load() {
+		return
+	}
+
+	select {
+	case r.queue <- RecordedMessage{ReceivedAt: receivedAt.UnixNano(), Msg: data}:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Messages lost because the writer could not keep up
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Writes everything still queued, then closes all files
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	if !r.closed.CompareAndSwap(false, true) {
+		r.mu.Unlock()
+		return fmt.Errorf("recorder already closed")
+	}
+	close(r.queue)
+	r.mu.Unlock()
+
+	<-r.done
+	return r.err
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.cfg.FlushInterval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case rec, ok := <-r.queue:
+			if !ok {
+				break loop
+			}
+			if err := r.write(rec); err != nil {
+				r.onError(err)
+			}
+		case <-ticker.C:
+			r.flush()
+		}
+	}
+
+	var errs []error
+	for name, f := range r.files {
+		if err := f.close(); err != nil {
+			errs = append(errs, fmt.Errorf("unable to close %s: %w", name, err))
+		}
+	}
+
+	r.err = errors.Join(errs...)
+}
+
+// Pushes buffered and compressed data of every open file to disk
+func (r *Recorder) flush() {
+	for name, f := range r.files {
+		if err := errors.Join(f.buf.Flush(), f.gz.Flush()); err != nil {
+			r.onError(fmt.Errorf("unable to flush %s: %w", name, err))
+		}
+	}
+}
+
+func (r *Recorder) onError(err error) {
+	if r.cfg.OnError != nil {
+		r.cfg.OnError(err)
+	}
+}
+
+func (r *Recorder) write(rec RecordedMessage) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("unable to encode recorded message: %w", err)
+	}
+	line = append(line, '\n')
+
+	for _, name := range r.fileNames(rec) {
+		f, err := r.open(name)
+		if err != nil {
+			return err
+		}
+
+		if _, err := f.buf.Write(line); err != nil {
+			return fmt.Errorf("unable to write %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Files a message belongs in
+func (r *Recorder) fileNames(rec RecordedMessage) []string {
+	if r.cfg.Split == SPLIT_BY_DAY {
+		day := time.Unix(0, rec.ReceivedAt).UTC().Format("2006-01-02")
+		return []string{day + RECORDING_EXT}
+	}
+
+	var peek struct {
+		Mc []struct {
+			Id string `json:"id"`
+		} `json:"mc"`
+		Oc []struct {
+			Id string `json:"id"`
+		} `json:"oc"`
+	}
+	if err := json.Unmarshal(rec.Msg, &peek); err != nil {
+		return []string{CONTROL_FILE}
+	}
+
+	seen := make(map[string]bool)
+	for _, mc := range peek.Mc {
+		seen[mc.Id] = true
+	}
+	for _, oc := range peek.Oc {
+		seen[oc.Id] = true
+	}
+
+	var names []string
+	control := false
+	for id := range seen {
+		if !recordableMarketId.MatchString(id) {
+			control = true
+			continue
+		}
+		names = append(names, id+RECORDING_EXT)
+	}
+	if control {
+		names = append(names, CONTROL_FILE)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return []string{CONTROL_FILE}
+	}
+
+	return names
+}
+
+func (r *Recorder) open(name string) (*recordFile, error) {
+	r.seq++
+
+	if f, ok := r.files[name]; ok {
+		f.lastUsed = r.seq
+		return f, nil
+	}
+
+	// Day files rotate, so only the current one needs to stay open
+	if r.cfg.Split == SPLIT_BY_DAY {
+		for other, f := range r.files {
+			if err := f.close(); err != nil {
+				r.onError(fmt.Errorf("unable to close %s: %w", other, err))
+			}
+			delete(r.files, other)
+		}
+	} else if len(r.files) >= r.cfg.MaxOpenFiles {
+		r.evictOldest()
+	}
+
+	// Appending starts a new gzip member, readers treat the file as one stream
+	file, err := os.OpenFile(filepath.Join(r.cfg.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %w", name, err)
+	}
+
+	gz := gzip.NewWriter(file)
+	f := &recordFile{file: file, gz: gz, buf: bufio.NewWriterSize(gz, 64*1024), lastUsed: r.seq}
+	r.files[name] = f
+
+	return f, nil
+}
+
+func (r *Recorder) evictOldest() {
+	var oldest string
+	var oldestSeq int64 = -1
+
+	for name, f := range r.files {
+		if oldestSeq == -1 || f.lastUsed < oldestSeq {
+			oldest, oldestSeq = name, f.lastUsed
+		}
+	}
+
+	if oldest == "" {
+		return
+	}
+
+	if err := r.files[oldest].close(); err != nil {
+		r.onError(fmt.Errorf("unable to close %s: %w", oldest, err))
+	}
+	delete(r.files, oldest)
+}
+
+func (f *recordFile) close() error {
+	return errors.Join(f.buf.Flush(), f.gz.Close(), f.file.Close())
+}
+
+// Reads messages back from a recording file in the order they were received
+type RecordingReader struct {
+	file    *os.File
+	gz      *gzip.Reader
+	decoder *json.Decoder
+}
+
+func OpenRecording(path string) (*RecordingReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open recording: %w", err)
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("unable to read recording: %w", err)
+	}
+
+	return &RecordingReader{file: file, gz: gz, decoder: json.NewDecoder(gz)}, nil
+}
+
+// Returns io.EOF once every message has been read
+func (r *RecordingReader) Next() (RecordedMessage, error) {
+	var rec RecordedMessage
+	if err := r.decoder.Decode(&rec); err != nil {
+		if errors.Is(err, io.EOF) {
+			return rec, io.EOF
+		}
+		return rec, fmt.Errorf("unable to decode recorded message: %w", err)
+	}
+
+	return rec, nil
+}
+
+// Decodes the recorded message, restoring its receive time
+func (rec RecordedMessage) Decode() (*ResponseMessage, error) {
+	msg := ResponseMessage{ReceivedAt: time.Unix(0, rec.ReceivedAt)}
+	if err := json.Unmarshal(rec.Msg, &msg); err != nil {
+		return nil, fmt.Errorf("unable to parse recorded message: %w", err)
+	}
+
+	return &msg, nil
+}
+
+func (r *RecordingReader) Close() error {
+	return errors.Join(r.gz.Close(), r.file.Close())
+}
@@ -0,0 +1,114 @@
+// stream/recorder_test.go
+
+package stream_test
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_SplitsByMarketAndReadsBack(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := stream.NewRecorder(stream.RecorderConfig{Dir: dir, Split: stream.SPLIT_BY_MARKET, MaxOpenFiles: 1})
+	require.NoError(t, err)
+
+	start := time.Unix(1700000000, 0)
+	rec.Record([]byte(`{"op":"connection","connectionId":"abc"}`), start)
+	rec.Record([]byte(`{"op":"mcm","pt":1,"mc":[{"id":"1.1","img":true}]}`), start.Add(time.Millisecond))
+	rec.Record([]byte(`{"op":"mcm","pt":2,"mc":[{"id":"1.2","img":true}]}`), start.Add(2*time.Millisecond))
+	// Reopening an evicted file appends a second gzip member
+	rec.Record([]byte(`{"op":"mcm","pt":3,"mc":[{"id":"1.1","tv":5}]}`), start.Add(3*time.Millisecond))
+	require.NoError(t, rec.Close())
+	assert.Zero(t, rec.Dropped())
+
+	r, err := stream.OpenRecording(filepath.Join(dir, "1.1"+stream.RECORDING_EXT))
+	require.NoError(t, err)
+	defer r.Close()
+
+	var pts []int64
+	for {
+		recorded, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		msg, err := recorded.Decode()
+		require.NoError(t, err)
+		assert.Equal(t, stream.OP_MCM, msg.Op)
+		pts = append(pts, msg.Pt)
+	}
+	assert.Equal(t, []int64{1, 3}, pts)
+
+	control, err := stream.OpenRecording(filepath.Join(dir, stream.CONTROL_FILE))
+	require.NoError(t, err)
+	defer control.Close()
+
+	recorded, err := control.Next()
+	require.NoError(t, err)
+	assert.Equal(t, start.UnixNano(), recorded.ReceivedAt)
+}
+
+func TestRecorder_FlushesWhileOpen(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := stream.NewRecorder(stream.RecorderConfig{Dir: dir, Split: stream.SPLIT_BY_MARKET, FlushInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer rec.Close()
+
+	rec.Record([]byte(`{"op":"mcm","pt":1,"mc":[{"id":"1.1","img":true}]}`), time.Now())
+
+	// The gzip stream is still open, so the first message is readable before the file ends
+	require.Eventually(t, func() bool {
+		r, err := stream.OpenRecording(filepath.Join(dir, "1.1"+stream.RECORDING_EXT))
+		if err != nil {
+			return false
+		}
+		defer r.Close()
+
+		recorded, err := r.Next()
+		return err == nil && string(recorded.Msg) == `{"op":"mcm","pt":1,"mc":[{"id":"1.1","img":true}]}`
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRecorder_UnsafeMarketIdsGoToControlFile(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := stream.NewRecorder(stream.RecorderConfig{Dir: dir, Split: stream.SPLIT_BY_MARKET})
+	require.NoError(t, err)
+
+	rec.Record([]byte(`{"op":"mcm","pt":1,"mc":[{"id":"../../escape"},{"id":"1.5"}]}`), time.Now())
+	rec.Record([]byte(`{"op":"ocm","pt":2,"oc":[{"id":"1.6/x"}]}`), time.Now())
+	require.NoError(t, rec.Close())
+
+	names, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "1.5"+stream.RECORDING_EXT),
+		filepath.Join(dir, stream.CONTROL_FILE),
+	}, names)
+
+	control, err := stream.OpenRecording(filepath.Join(dir, stream.CONTROL_FILE))
+	require.NoError(t, err)
+	defer control.Close()
+
+	var pts []int64
+	for {
+		recorded, err := control.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		msg, err := recorded.Decode()
+		require.NoError(t, err)
+		pts = append(pts, msg.Pt)
+	}
+	assert.Equal(t, []int64{1, 2}, pts)
+}
@@ -0,0 +1,176 @@
+// stream/resume.go
+
+package stream
+
+// Reconnects dropped connections and resumes subscriptions from the last clocks
+// so Betfair only sends what changed during the gap (ct=RESUB_DELTA).
+// If Betfair cannot resume it sends a full image (ct=SUB_IMAGE) which replaces the caches.
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	reconnectBaseDelay = 1 * time.Second
+	reconnectMaxDelay  = 30 * time.Second
+)
+
+// Clocks Betfair uses to resume a subscription
+// initialClk arrives with the first image, clk with every change
+type clocks struct {
+	initialClk string
+	clk        string
+}
+
+func (c *clocks) update(msg *ResponseMessage) {
+	if msg.InitialClk != "" {
+		c.initialClk = msg.InitialClk
+	}
+	if msg.Clk != "" {
+		c.clk = msg.Clk
+	}
+}
+
+func (c *clocks) get() (string, string) {
+	return c.initialClk, c.clk
+}
+
+func (c *clocks) reset() {
+	c.initialClk = ""
+	c.clk = ""
+}
+
+// Fatal errors are not retried, reconnecting would fail the same way
+func isFatal(err error) bool {
+	return IsErrorCode(err, NO_APP_KEY) ||
+		IsErrorCode(err, INVALID_APP_KEY) ||
+		IsErrorCode(err, NOT_AUTHORIZED)
+}
+
+func (c *Client) supervise() {
+	defer c.wg.Done()
+
+	for {
+		conn, err := c.currentConn()
+		if err != nil {
+			return
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-conn.Done():
+		}
+
+		if c.closed.Load() {
+			return
+		}
+
+		c.onError(fmt.Errorf("stream connection lost: %w", conn.Err()))
+
+		if !c.reconnect() {
+			if !c.closed.Load() {
+				c.gaveUp.Store(true)
+			}
+			return
+		}
+	}
+}
+
+// Redials with exponential backoff and resubscribes
+// Returns false if the client was closed or gave up
+func (c *Client) reconnect() bool {
+	for attempt := 1; ; attempt++ {
+		if c.cfg.MaxReconnectAttempts > 0 && attempt > c.cfg.MaxReconnectAttempts {
+			c.onError(fmt.Errorf("max stream reconnect attempts reached, giving up"))
+			return false
+		}
+
+		delay := reconnectBaseDelay * time.Duration(1<<min(attempt-1, 5))
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+		jitter := time.Duration(rand.Int63n(250)) * time.Millisecond
+
+		select {
+		case <-c.ctx.Done():
+			return false
+		case <-time.After(delay + jitter):
+		}
+
+		err := c.dial(c.ctx)
+		if err == nil {
+			err = c.resubscribe(c.ctx)
+		}
+
+		if err == nil {
+			c.stats.reconnected(c.cfg.Now())
+			return true
+		}
+
+		if c.closed.Load() {
+			return false
+		}
+
+		c.onError(fmt.Errorf("stream reconnect attempt %d failed: %w", attempt, err))
+
+		if isFatal(err) {
+			return false
+		}
+
+		// Resubscribe failed on a live connection, drop it and start over
+		if conn, connErr := c.currentConn(); connErr == nil {
+			conn.Close()
+		}
+	}
+}
+
+// Replays the current subscriptions on a fresh connection
+func (c *Client) resubscribe(ctx context.Context) error {
+	conn, err := c.currentConn()
+	if err != nil {
+		return err
+	}
+
+	// An update still waiting for its answer was lost with the old connection
+	c.mu.Lock()
+	marketSub, orderSub := c.marketSub, c.orderSub
+	c.pending = nil
+	c.mu.Unlock()
+
+	if marketSub != nil {
+		err := c.sendMarketSubscription(ctx, conn, marketSub)
+
+		// Clocks too old to resume from, fall back to a full image
+		if IsErrorCode(err, INVALID_CLOCK) {
+			c.mu.Lock()
+			marketSub.clocks.reset()
+			c.mu.Unlock()
+			err = c.sendMarketSubscription(ctx, conn, marketSub)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if orderSub != nil {
+		err := c.sendOrderSubscription(ctx, conn, orderSub)
+
+		if IsErrorCode(err, INVALID_CLOCK) {
+			c.mu.Lock()
+			orderSub.clocks.reset()
+			c.mu.Unlock()
+			err = c.sendOrderSubscription(ctx, conn, orderSub)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
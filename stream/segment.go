@@ -0,0 +1,66 @@
+// stream/segment.go
+
+package stream
+
+// Large images arrive split across SEG_START, SEG..., SEG_END messages.
+// Segments are buffered and merged so caches and consumers only ever see complete images.
+
+type segmentBuffer struct {
+	pending map[string][]*ResponseMessage // op -> segments received so far
+}
+
+func newSegmentBuffer() *segmentBuffer {
+	return &segmentBuffer{pending: make(map[string][]*ResponseMessage)}
+}
+
+// Returns the message to apply, or nil while a segmented message is still incomplete
+// Not safe for concurrent use, each connection has its own buffer
+func (b *segmentBuffer) add(msg *ResponseMessage) *ResponseMessage {
+	switch msg.SegmentType {
+	case SEG_START:
+		// A new image supersedes one that never finished
+		b.pending[msg.Op] = []*ResponseMessage{msg}
+		return nil
+
+	case SEG:
+		if _, ok := b.pending[msg.Op]; !ok {
+			// Missed the start, nothing to merge with
+			return msg
+		}
+		b.pending[msg.Op] = append(b.pending[msg.Op], msg)
+		return nil
+
+	case SEG_END:
+		segments, ok := b.pending[msg.Op]
+		if !ok {
+			return msg
+		}
+		delete(b.pending, msg.Op)
+		return mergeSegments(append(segments, msg))
+
+	default:
+		return msg
+	}
+}
+
+// Combines segments into a single message carrying the clocks and publish time of the last one
+func mergeSegments(segments []*ResponseMessage) *ResponseMessage {
+	first, last := segments[0], segments[len(segments)-1]
+
+	merged := *last
+	merged.SegmentType = ""
+	merged.Ct = first.Ct
+	merged.Mc = nil
+	merged.Oc = nil
+
+	for _, seg := range segments {
+		if merged.InitialClk == "" {
+			merged.InitialClk = seg.InitialClk
+		}
+		merged.Con = merged.Con || seg.Con
+		merged.Mc = append(merged.Mc, seg.Mc...)
+		merged.Oc = append(merged.Oc, seg.Oc...)
+	}
+
+	return &merged
+}
@@ -0,0 +1,220 @@
+// stream/stats.go
+
+package stream
+
+// Message statistics and staleness monitoring.
+// Latency is measured from Betfair's publish time (pt) to the local receive time,
+// so it includes any clock skew between us and Betfair.
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHeartbeatMs = 5000
+	heartbeatMisses    = 3
+	monitorInterval    = 500 * time.Millisecond
+)
+
+type Stats struct {
+	Messages      int64 // every mcm/ocm including heartbeats
+	Heartbeats    int64
+	Conflated     int64 // messages flagged con=true
+	Reconnects    int64
+	LastMessageAt time.Time
+	LastPt        time.Time // publish time of the last message
+
+	LastLatency time.Duration
+	MaxLatency  time.Duration
+	AvgLatency  time.Duration // exponentially weighted
+
+	HeartbeatMs int64 // heartbeat interval confirmed by Betfair
+	ConflateMs  int64 // conflation confirmed by Betfair
+}
+
+// Reported through OnError when no message arrives for longer than the heartbeat timeout
+type HeartbeatError struct {
+	Silence time.Duration
+	Timeout time.Duration
+}
+
+func (e *HeartbeatError) Error() string {
+	return fmt.Sprintf("no stream messages for %s (timeout %s), prices are stale", e.Silence.Round(time.Millisecond), e.Timeout)
+}
+
+// Reported through OnError when latency first exceeds the configured threshold
+type LatencyError struct {
+	Latency   time.Duration
+	Threshold time.Duration
+}
+
+func (e *LatencyError) Error() string {
+	return fmt.Sprintf("stream latency %s exceeds threshold %s", e.Latency.Round(time.Millisecond), e.Threshold)
+}
+
+type SubscriptionOption func(*subscriptionOptions)
+
+type subscriptionOptions struct {
+	conflateMs  int64
+	heartbeatMs int64
+}
+
+// Merges changes over the given window into a single message, 0 disables conflation
+func WithConflateMs(ms int64) SubscriptionOption {
+	return func(o *subscriptionOptions) {
+		o.conflateMs = ms
+	}
+}
+
+// Heartbeat interval when nothing changes, Betfair accepts 500 to 5000
+func WithHeartbeatMs(ms int64) SubscriptionOption {
+	return func(o *subscriptionOptions) {
+		o.heartbeatMs = ms
+	}
+}
+
+func newSubscriptionOptions(opts []SubscriptionOption) subscriptionOptions {
+	var o subscriptionOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+type statsTracker struct {
+	mu            sync.Mutex
+	stats         Stats
+	latencyAlarm  bool
+	heartbeatLost bool
+}
+
+// Records a change message, returns an error to report if it breaches the latency threshold
+func (t *statsTracker) record(msg *ResponseMessage, threshold time.Duration) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := &t.stats
+	s.Messages++
+	s.LastMessageAt = msg.ReceivedAt
+	t.heartbeatLost = false
+
+	if msg.Ct == CT_HEARTBEAT {
+		s.Heartbeats++
+	}
+	if msg.Con {
+		s.Conflated++
+	}
+	if msg.HeartbeatMs != 0 {
+		s.HeartbeatMs = msg.HeartbeatMs
+	}
+	if msg.ConflateMs != 0 {
+		s.ConflateMs = msg.ConflateMs
+	}
+
+	latency, ok := msg.Latency()
+	if !ok {
+		return nil
+	}
+
+	s.LastPt = time.UnixMilli(msg.Pt)
+	s.LastLatency = latency
+	if latency > s.MaxLatency {
+		s.MaxLatency = latency
+	}
+	if s.AvgLatency == 0 {
+		s.AvgLatency = latency
+	} else {
+		s.AvgLatency = (s.AvgLatency*9 + latency) / 10
+	}
+
+	if threshold <= 0 {
+		return nil
+	}
+
+	// Only alert on the transition over the threshold
+	if latency > threshold {
+		if !t.latencyAlarm {
+			t.latencyAlarm = true
+			return &LatencyError{Latency: latency, Threshold: threshold}
+		}
+	} else {
+		t.latencyAlarm = false
+	}
+
+	return nil
+}
+
+func (t *statsTracker) reconnected(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stats.Reconnects++
+	t.stats.LastMessageAt = now
+	t.heartbeatLost = false
+}
+
+// Returns an error the first time the silence exceeds timeout
+func (t *statsTracker) checkHeartbeat(now time.Time, timeout time.Duration) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.heartbeatLost || t.stats.LastMessageAt.IsZero() {
+		return nil
+	}
+
+	if timeout <= 0 {
+		heartbeatMs := t.stats.HeartbeatMs
+		if heartbeatMs == 0 {
+			heartbeatMs = defaultHeartbeatMs
+		}
+		timeout = heartbeatMisses * time.Duration(heartbeatMs) * time.Millisecond
+	}
+
+	silence := now.Sub(t.stats.LastMessageAt)
+	if silence <= timeout {
+		return nil
+	}
+
+	t.heartbeatLost = true
+	return &HeartbeatError{Silence: silence, Timeout: timeout}
+}
+
+func (t *statsTracker) snapshot() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stats
+}
+
+// Current message statistics
+func (c *Client) Stats() Stats {
+	return c.stats.snapshot()
+}
+
+// Watches for silent connections
+// A connection without heartbeats is closed so the supervisor reconnects it
+func (c *Client) monitor() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(monitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			conn, err := c.currentConn()
+			if err != nil || conn.Err() != nil {
+				continue
+			}
+
+			if err := c.stats.checkHeartbeat(c.cfg.Now(), c.cfg.HeartbeatTimeout); err != nil {
+				c.onError(err)
+				conn.Close()
+			}
+		}
+	}
+}
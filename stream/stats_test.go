@@ -0,0 +1,217 @@
+// stream/stats_test.go
+
+package stream_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream/streamtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Clock that only moves when the test says so
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.UnixMilli(time.Now().UnixMilli())}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type errorLog struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (l *errorLog) add(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errs = append(l.errs, err)
+}
+
+// Errors of type T reported so far
+func errorsOf[T error](l *errorLog) []T {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var found []T
+	for _, err := range l.errs {
+		var target T
+		if errors.As(err, &target) {
+			found = append(found, target)
+		}
+	}
+	return found
+}
+
+// Client subscribed to market 1.1 on a fresh server, with the clock and error log attached
+func subscribedClient(t *testing.T, cfg func(*stream.ClientConfig)) (*stream.Client, *streamtest.Server, *streamtest.ServerConn, int64) {
+	t.Helper()
+
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+	srv.SetMarketImage([]*stream.MarketChange{{Id: "1.1", Img: true, MarketDefinition: &stream.MarketDefinition{Status: "OPEN"}}})
+
+	config := srv.ClientConfig()
+	cfg(&config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sc := stream.NewClient(config, streamtest.DefaultCredentials())
+	t.Cleanup(func() { sc.Close() })
+
+	require.NoError(t, sc.Connect(ctx))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sc.SubscribeMarkets(ctx, stream.MarketFilter{MarketIds: []string{"1.1"}}, stream.MarketDataFilter{}))
+	sub, err := conn.WaitForRequest(ctx, stream.OP_MARKET_SUB)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return sc.Stats().Messages == 1 }, time.Second, 5*time.Millisecond)
+
+	return sc, srv, conn, sub.Id
+}
+
+func TestStats_TracksLatency(t *testing.T) {
+	clock := newFakeClock()
+	var log errorLog
+
+	sc, _, conn, subId := subscribedClient(t, func(cfg *stream.ClientConfig) {
+		cfg.Now = clock.Now
+		cfg.LatencyThreshold = 100 * time.Millisecond
+		cfg.OnError = log.add
+	})
+
+	// Publish times relative to the frozen clock, so each latency is exact
+	send := func(latency time.Duration) {
+		t.Helper()
+
+		before := sc.Stats().Messages
+		require.NoError(t, conn.Send(stream.ResponseMessage{Op: stream.OP_MCM, Id: subId, Ct: stream.CT_HEARTBEAT,
+			Pt: clock.Now().Add(-latency).UnixMilli()}))
+		require.Eventually(t, func() bool { return sc.Stats().Messages == before+1 }, time.Second, 5*time.Millisecond)
+	}
+
+	send(50 * time.Millisecond)
+	stats := sc.Stats()
+	assert.Equal(t, 50*time.Millisecond, stats.LastLatency)
+	assert.Equal(t, clock.Now().Add(-50*time.Millisecond), stats.LastPt)
+	assert.Equal(t, clock.Now(), stats.LastMessageAt)
+	assert.Empty(t, errorsOf[*stream.LatencyError](&log))
+
+	// Only crossing the threshold is reported, staying over it is not
+	send(200 * time.Millisecond)
+	send(300 * time.Millisecond)
+	latencyErrs := errorsOf[*stream.LatencyError](&log)
+	require.Len(t, latencyErrs, 1)
+	assert.Equal(t, 200*time.Millisecond, latencyErrs[0].Latency)
+	assert.Equal(t, 100*time.Millisecond, latencyErrs[0].Threshold)
+
+	send(10 * time.Millisecond)
+	send(150 * time.Millisecond)
+	assert.Len(t, errorsOf[*stream.LatencyError](&log), 2)
+
+	stats = sc.Stats()
+	assert.Equal(t, 150*time.Millisecond, stats.LastLatency)
+	assert.Equal(t, 300*time.Millisecond, stats.MaxLatency)
+	assert.Greater(t, stats.AvgLatency, 50*time.Millisecond)
+	assert.Less(t, stats.AvgLatency, stats.MaxLatency)
+}
+
+func TestStats_CountsHeartbeatsAndConflation(t *testing.T) {
+	clock := newFakeClock()
+
+	sc, _, conn, subId := subscribedClient(t, func(cfg *stream.ClientConfig) {
+		cfg.Now = clock.Now
+	})
+
+	require.NoError(t, conn.Send(stream.ResponseMessage{Op: stream.OP_MCM, Id: subId, Ct: stream.CT_HEARTBEAT, Pt: clock.Now().UnixMilli(),
+		HeartbeatMs: 1000, ConflateMs: 200}))
+	require.NoError(t, conn.Send(stream.ResponseMessage{Op: stream.OP_MCM, Id: subId, Pt: clock.Now().UnixMilli(), Con: true,
+		Mc: []*stream.MarketChange{{Id: "1.1", Tv: 10}}}))
+	require.NoError(t, conn.Send(stream.ResponseMessage{Op: stream.OP_MCM, Id: subId, Ct: stream.CT_HEARTBEAT, Pt: clock.Now().UnixMilli()}))
+
+	require.Eventually(t, func() bool { return sc.Stats().Messages == 4 }, time.Second, 5*time.Millisecond)
+
+	stats := sc.Stats()
+	assert.Equal(t, int64(2), stats.Heartbeats)
+	assert.Equal(t, int64(1), stats.Conflated)
+	assert.Equal(t, int64(1000), stats.HeartbeatMs)
+	assert.Equal(t, int64(200), stats.ConflateMs)
+}
+
+func TestMonitor_ClosesSilentConnection(t *testing.T) {
+	clock := newFakeClock()
+	var log errorLog
+
+	sc, srv, conn, subId := subscribedClient(t, func(cfg *stream.ClientConfig) {
+		cfg.Now = clock.Now
+		cfg.OnError = log.add
+	})
+
+	// Three missed 1s heartbeats make the connection dead
+	require.NoError(t, conn.Send(stream.ResponseMessage{Op: stream.OP_MCM, Id: subId, Ct: stream.CT_HEARTBEAT, Pt: clock.Now().UnixMilli(), HeartbeatMs: 1000}))
+	require.Eventually(t, func() bool { return sc.Stats().HeartbeatMs == 1000 }, time.Second, 5*time.Millisecond)
+
+	clock.Advance(2 * time.Second)
+	assert.Never(t, func() bool { return len(errorsOf[*stream.HeartbeatError](&log)) > 0 }, 700*time.Millisecond, 50*time.Millisecond)
+
+	clock.Advance(2 * time.Second)
+	require.Eventually(t, func() bool { return len(errorsOf[*stream.HeartbeatError](&log)) == 1 }, 2*time.Second, 20*time.Millisecond)
+
+	heartbeatErr := errorsOf[*stream.HeartbeatError](&log)[0]
+	assert.Equal(t, 4*time.Second, heartbeatErr.Silence)
+	assert.Equal(t, 3*time.Second, heartbeatErr.Timeout)
+
+	// The supervisor dials again and the silence is measured from the reconnect
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return sc.Stats().Reconnects == 1 }, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, clock.Now(), sc.Stats().LastMessageAt)
+	assert.Len(t, errorsOf[*stream.HeartbeatError](&log), 1)
+}
+
+func TestMonitor_UsesConfiguredTimeout(t *testing.T) {
+	clock := newFakeClock()
+	var log errorLog
+
+	subscribedClient(t, func(cfg *stream.ClientConfig) {
+		cfg.Now = clock.Now
+		cfg.OnError = log.add
+		cfg.HeartbeatTimeout = 30 * time.Second
+	})
+
+	// Past the default of 15s but inside the configured timeout
+	clock.Advance(20 * time.Second)
+	assert.Never(t, func() bool { return len(errorsOf[*stream.HeartbeatError](&log)) > 0 }, 700*time.Millisecond, 50*time.Millisecond)
+
+	clock.Advance(11 * time.Second)
+	require.Eventually(t, func() bool { return len(errorsOf[*stream.HeartbeatError](&log)) == 1 }, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 30*time.Second, errorsOf[*stream.HeartbeatError](&log)[0].Timeout)
+}
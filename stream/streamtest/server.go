@@ -0,0 +1,706 @@
+// stream/streamtest/server.go
+
+package streamtest
+
+// Local stand-in for the Exchange Stream API, for tests without network access.
+// Speaks the CRLF framed JSON protocol over TLS (self-signed) or plain TCP,
+// authenticates clients, records requests and lets tests push change messages.
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+)
+
+const (
+	APP_KEY       = "test-app-key"
+	SESSION_TOKEN = "test-session-token"
+)
+
+// Authenticator accepted by a Server with default settings
+type Credentials struct {
+	Key   string
+	Token string
+}
+
+func (c Credentials) AppKey() string {
+	return c.Key
+}
+
+func (c Credentials) SessionToken() (string, error) {
+	return c.Token, nil
+}
+
+// Credentials matching the server defaults
+func DefaultCredentials() Credentials {
+	return Credentials{Key: APP_KEY, Token: SESSION_TOKEN}
+}
+
+type Server struct {
+	ln        net.Listener
+	clientTLS *tls.Config
+
+	mu     sync.Mutex
+	conns  []*ServerConn
+	nextId atomic.Int64
+	clk    atomic.Int64
+
+	appKey       string // expected app key, empty accepts any
+	sessionToken string // expected session token, empty accepts any
+	// Reply to resubscriptions with a full image instead of RESUB_DELTA
+	forceFullImage bool
+
+	// Market changes sent as the image for every new market subscription
+	image []*stream.MarketChange
+	// Order changes sent as the image for every new order subscription
+	orderImage []*stream.OrderMarketChange
+	// Queued failures per op, see FailNext
+	failures map[string][]stream.ErrorCode
+
+	// Connections not yet taken by NextConn, ready is signalled whenever one is added
+	accepted []*ServerConn
+	ready    chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Starts a TLS server with a self-signed certificate on a random local port
+func NewServer() (*Server, error) {
+	serverTLS, clientTLS, err := selfSignedTLS()
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverTLS)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen: %w", err)
+	}
+
+	return start(ln, clientTLS), nil
+}
+
+// Starts a plain TCP server on a random local port
+func NewPlainServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen: %w", err)
+	}
+
+	return start(ln, nil), nil
+}
+
+func start(ln net.Listener, clientTLS *tls.Config) *Server {
+	s := &Server{
+		appKey:       APP_KEY,
+		sessionToken: SESSION_TOKEN,
+		ln:           ln,
+		clientTLS:    clientTLS,
+		ready:        make(chan struct{}, 1),
+	}
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+
+	return s
+}
+
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Client config pointing at this server
+func (s *Server) Config() stream.Config {
+	return stream.Config{
+		Addr:           s.Addr(),
+		TLSConfig:      s.clientTLS,
+		DisableTLS:     s.clientTLS == nil,
+		RequestTimeout: 5 * time.Second,
+	}
+}
+
+// Client and pool config pointing at this server
+func (s *Server) ClientConfig() stream.ClientConfig {
+	return stream.ClientConfig{Config: s.Config()}
+}
+
+// Sets the credentials checked on authentication, an empty value accepts any
+func (s *Server) SetCredentials(appKey, sessionToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.appKey = appKey
+	s.sessionToken = sessionToken
+}
+
+// Replies to resubscriptions with a full image instead of RESUB_DELTA
+func (s *Server) SetForceFullImage(force bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.forceFullImage = force
+}
+
+func (s *Server) credentials() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.appKey, s.sessionToken
+}
+
+// Sets the market image sent in reply to every market subscription
+func (s *Server) SetMarketImage(image []*stream.MarketChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.image = image
+}
+
+// Sets the order image sent in reply to every order subscription
+func (s *Server) SetOrderImage(image []*stream.OrderMarketChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.orderImage = image
+}
+
+// Fails the next request for op (e.g. stream.OP_MARKET_SUB) with code, leaving the connection open
+func (s *Server) FailNext(op string, code stream.ErrorCode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures == nil {
+		s.failures = make(map[string][]stream.ErrorCode)
+	}
+	s.failures[op] = append(s.failures[op], code)
+}
+
+func (s *Server) nextFailure(op string) (stream.ErrorCode, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue := s.failures[op]
+	if len(queue) == 0 {
+		return "", false
+	}
+	s.failures[op] = queue[1:]
+	return queue[0], true
+}
+
+// Waits for the next client connection
+func (s *Server) NextConn(ctx context.Context) (*ServerConn, error) {
+	for {
+		s.mu.Lock()
+		if len(s.accepted) > 0 {
+			c := s.accepted[0]
+			s.accepted = s.accepted[1:]
+			more := len(s.accepted) > 0
+			s.mu.Unlock()
+
+			// Pass the signal on in case another caller is waiting for the rest
+			if more {
+				s.signalReady()
+			}
+			return c, nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *Server) signalReady() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Every connection accepted so far, including closed ones
+func (s *Server) Conns() []*ServerConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*ServerConn(nil), s.conns...)
+}
+
+func (s *Server) Close() {
+	s.ln.Close()
+
+	for _, c := range s.Conns() {
+		c.Disconnect()
+	}
+
+	s.wg.Wait()
+}
+
+func (s *Server) nextClk() string {
+	return strconv.FormatInt(s.clk.Add(1), 10)
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+
+	for {
+		netConn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &ServerConn{
+			server:       s,
+			conn:         netConn,
+			connectionId: fmt.Sprintf("test-%d", s.nextId.Add(1)),
+			requests:     make(chan *stream.RequestMessage, 64),
+		}
+
+		s.mu.Lock()
+		s.conns = append(s.conns, c)
+		s.accepted = append(s.accepted, c)
+		s.mu.Unlock()
+		s.signalReady()
+
+		s.wg.Add(1)
+		go c.serve()
+	}
+}
+
+// Server side of a single client connection
+type ServerConn struct {
+	server       *Server
+	conn         net.Conn
+	connectionId string
+
+	writeMu sync.Mutex
+
+	mu            sync.Mutex
+	authenticated bool
+	marketSub     *stream.RequestMessage
+	orderSub      *stream.RequestMessage
+	history       []*stream.RequestMessage
+
+	requests chan *stream.RequestMessage
+	closed   atomic.Bool
+}
+
+func (c *ServerConn) ConnectionId() string {
+	return c.connectionId
+}
+
+func (c *ServerConn) serve() {
+	defer c.server.wg.Done()
+	defer c.conn.Close()
+
+	if err := c.Send(stream.ResponseMessage{Op: stream.OP_CONNECTION, ConnectionId: c.connectionId}); err != nil {
+		return
+	}
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		var req stream.RequestMessage
+		if err := json.Unmarshal(bytes.TrimRight(line, "\r\n"), &req); err != nil {
+			c.fail(0, stream.INVALID_INPUT, "unable to parse request", true)
+			return
+		}
+
+		c.mu.Lock()
+		c.history = append(c.history, &req)
+		c.mu.Unlock()
+
+		select {
+		case c.requests <- &req:
+		default:
+		}
+
+		if !c.handle(&req) {
+			return
+		}
+	}
+}
+
+// Returns false when the connection should be dropped
+func (c *ServerConn) handle(req *stream.RequestMessage) bool {
+	c.mu.Lock()
+	authenticated := c.authenticated
+	c.mu.Unlock()
+
+	if req.Op == stream.OP_AUTHENTICATION {
+		appKey, sessionToken := c.server.credentials()
+		if (appKey != "" && req.AppKey != appKey) || (sessionToken != "" && req.Session != sessionToken) {
+			c.fail(req.Id, stream.INVALID_SESSION_INFORMATION, "invalid credentials", true)
+			return false
+		}
+
+		c.mu.Lock()
+		c.authenticated = true
+		c.mu.Unlock()
+
+		c.success(req.Id)
+		return true
+	}
+
+	if !authenticated {
+		c.fail(req.Id, stream.NO_SESSION, "authenticate first", true)
+		return false
+	}
+
+	if code, ok := c.server.nextFailure(req.Op); ok {
+		c.fail(req.Id, code, "failed by streamtest", false)
+		return true
+	}
+
+	switch req.Op {
+	case stream.OP_HEARTBEAT:
+		c.success(req.Id)
+
+	case stream.OP_MARKET_SUB:
+		c.mu.Lock()
+		previous := c.marketSub
+		c.marketSub = req
+		c.mu.Unlock()
+
+		c.success(req.Id)
+		c.sendMarketImage(req, previous)
+
+	case stream.OP_ORDER_SUB:
+		c.mu.Lock()
+		c.orderSub = req
+		c.mu.Unlock()
+
+		c.success(req.Id)
+		c.sendOrderImage(req)
+
+	default:
+		c.fail(req.Id, stream.INVALID_REQUEST, "unknown op "+req.Op, false)
+	}
+
+	return true
+}
+
+// Resubscriptions carrying clocks get a RESUB_DELTA, everything else the full image
+// The delta holds images of the markets the filter adds to previous, the subscription it replaces on this connection
+func (c *ServerConn) sendMarketImage(req, previous *stream.RequestMessage) {
+	c.server.mu.Lock()
+	image, forceImage := c.server.image, c.server.forceFullImage
+	c.server.mu.Unlock()
+
+	if req.InitialClk != "" && req.Clk != "" && !forceImage {
+		var added []*stream.MarketChange
+		if previous != nil {
+			held := make(map[string]bool)
+			for _, mc := range filterMarkets(previous.MarketFilter, image) {
+				held[mc.Id] = true
+			}
+			for _, mc := range filterMarkets(req.MarketFilter, image) {
+				if !held[mc.Id] {
+					added = append(added, mc)
+				}
+			}
+		}
+
+		c.Send(stream.ResponseMessage{
+			Op:  stream.OP_MCM,
+			Id:  req.Id,
+			Ct:  stream.CT_RESUB_DELTA,
+			Clk: c.server.nextClk(),
+			Pt:  time.Now().UnixMilli(),
+			Mc:  added,
+		})
+		return
+	}
+
+	c.Send(stream.ResponseMessage{
+		Op:         stream.OP_MCM,
+		Id:         req.Id,
+		Ct:         stream.CT_SUB_IMAGE,
+		InitialClk: c.server.nextClk(),
+		Clk:        c.server.nextClk(),
+		Pt:         time.Now().UnixMilli(),
+		Mc:         filterMarkets(req.MarketFilter, image),
+	})
+}
+
+// Same as sendMarketImage for the order subscription
+func (c *ServerConn) sendOrderImage(req *stream.RequestMessage) {
+	c.server.mu.Lock()
+	image, forceImage := c.server.orderImage, c.server.forceFullImage
+	c.server.mu.Unlock()
+
+	if req.InitialClk != "" && req.Clk != "" && !forceImage {
+		c.Send(stream.ResponseMessage{
+			Op:  stream.OP_OCM,
+			Id:  req.Id,
+			Ct:  stream.CT_RESUB_DELTA,
+			Clk: c.server.nextClk(),
+			Pt:  time.Now().UnixMilli(),
+		})
+		return
+	}
+
+	c.Send(stream.ResponseMessage{
+		Op:         stream.OP_OCM,
+		Id:         req.Id,
+		Ct:         stream.CT_SUB_IMAGE,
+		InitialClk: c.server.nextClk(),
+		Clk:        c.server.nextClk(),
+		Pt:         time.Now().UnixMilli(),
+		Oc:         image,
+	})
+}
+
+func filterMarkets(filter *stream.MarketFilter, changes []*stream.MarketChange) []*stream.MarketChange {
+	if filter == nil || len(filter.MarketIds) == 0 {
+		return changes
+	}
+
+	wanted := make(map[string]bool, len(filter.MarketIds))
+	for _, id := range filter.MarketIds {
+		wanted[id] = true
+	}
+
+	var result []*stream.MarketChange
+	for _, mc := range changes {
+		if wanted[mc.Id] {
+			result = append(result, mc)
+		}
+	}
+
+	return result
+}
+
+func (c *ServerConn) success(id int64) {
+	c.Send(stream.ResponseMessage{Op: stream.OP_STATUS, Id: id, StatusCode: stream.STATUS_SUCCESS})
+}
+
+func (c *ServerConn) fail(id int64, code stream.ErrorCode, message string, closeConn bool) {
+	c.Send(stream.ResponseMessage{
+		Op:               stream.OP_STATUS,
+		Id:               id,
+		StatusCode:       stream.STATUS_FAILURE,
+		ErrorCode:        code,
+		ErrorMessage:     message,
+		ConnectionClosed: closeConn,
+	})
+}
+
+// Writes any message followed by CRLF
+func (c *ServerConn) Send(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return c.SendRaw(data)
+}
+
+// Writes pre-encoded JSON followed by CRLF
+func (c *ServerConn) SendRaw(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed.Load() {
+		return stream.ErrConnClosed
+	}
+
+	_, err := c.conn.Write(append(data, '\r', '\n'))
+	return err
+}
+
+// Pushes market changes on the current market subscription
+// Changes for markets outside the subscription's market ids are dropped
+func (c *ServerConn) SendMarketChanges(changes ...*stream.MarketChange) error {
+	c.mu.Lock()
+	sub := c.marketSub
+	c.mu.Unlock()
+
+	if sub == nil {
+		return fmt.Errorf("no market subscription")
+	}
+
+	return c.Send(stream.ResponseMessage{
+		Op:  stream.OP_MCM,
+		Id:  sub.Id,
+		Clk: c.server.nextClk(),
+		Pt:  time.Now().UnixMilli(),
+		Mc:  filterMarkets(sub.MarketFilter, changes),
+	})
+}
+
+// Pushes order changes on the current order subscription
+func (c *ServerConn) SendOrderChanges(changes ...*stream.OrderMarketChange) error {
+	c.mu.Lock()
+	sub := c.orderSub
+	c.mu.Unlock()
+
+	if sub == nil {
+		return fmt.Errorf("no order subscription")
+	}
+
+	return c.Send(stream.ResponseMessage{
+		Op:  stream.OP_OCM,
+		Id:  sub.Id,
+		Clk: c.server.nextClk(),
+		Pt:  time.Now().UnixMilli(),
+		Oc:  changes,
+	})
+}
+
+func (c *ServerConn) SendHeartbeat() error {
+	c.mu.Lock()
+	sub := c.marketSub
+	c.mu.Unlock()
+
+	msg := stream.ResponseMessage{Op: stream.OP_MCM, Ct: stream.CT_HEARTBEAT, Clk: c.server.nextClk(), Pt: time.Now().UnixMilli()}
+	if sub != nil {
+		msg.Id = sub.Id
+	}
+
+	return c.Send(msg)
+}
+
+// Sends an image split into segments of at most perSegment market changes
+func (c *ServerConn) SendSegmentedImage(changes []*stream.MarketChange, perSegment int) error {
+	c.mu.Lock()
+	sub := c.marketSub
+	c.mu.Unlock()
+
+	if sub == nil {
+		return fmt.Errorf("no market subscription")
+	}
+	if perSegment <= 0 {
+		perSegment = 1
+	}
+
+	pt := time.Now().UnixMilli()
+	initialClk := c.server.nextClk()
+
+	for start := 0; start < len(changes); start += perSegment {
+		end := min(start+perSegment, len(changes))
+
+		msg := stream.ResponseMessage{
+			Op:          stream.OP_MCM,
+			Id:          sub.Id,
+			Ct:          stream.CT_SUB_IMAGE,
+			Pt:          pt,
+			Mc:          changes[start:end],
+			SegmentType: stream.SEG,
+		}
+
+		switch {
+		case start == 0 && end == len(changes):
+			msg.SegmentType = ""
+		case start == 0:
+			msg.SegmentType = stream.SEG_START
+		case end == len(changes):
+			msg.SegmentType = stream.SEG_END
+		}
+
+		// Clocks only arrive with the final segment
+		if end == len(changes) {
+			msg.InitialClk = initialClk
+			msg.Clk = c.server.nextClk()
+		}
+
+		if err := c.Send(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Sends an unsolicited failure status and drops the connection, as Betfair does
+func (c *ServerConn) SendFailure(code stream.ErrorCode, message string) {
+	c.fail(0, code, message, true)
+	c.Disconnect()
+}
+
+// Drops the connection without warning
+func (c *ServerConn) Disconnect() {
+	if c.closed.CompareAndSwap(false, true) {
+		c.conn.Close()
+	}
+}
+
+// Waits for the next request with the given op
+func (c *ServerConn) WaitForRequest(ctx context.Context, op string) (*stream.RequestMessage, error) {
+	for {
+		select {
+		case req := <-c.requests:
+			if req.Op == op {
+				return req, nil
+			}
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for %s: %w", op, ctx.Err())
+		}
+	}
+}
+
+// Every request received on the connection so far
+func (c *ServerConn) Requests() []*stream.RequestMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*stream.RequestMessage(nil), c.history...)
+}
+
+func selfSignedTLS() (*tls.Config, *tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate key: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "streamtest"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create certificate: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse certificate: %w", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+
+	serverTLS := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}},
+	}
+	clientTLS := &tls.Config{RootCAs: roots, ServerName: "127.0.0.1"}
+
+	return serverTLS, clientTLS, nil
+}
@@ -0,0 +1,756 @@
+// stream/streamtest/server_test.go
+
+package streamtest_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream"
+	"github.com/Bazcampbell/betfair-api-go-sdk/stream/streamtest"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var image = []*stream.MarketChange{
+	{Id: "1.1", Img: true, MarketDefinition: &stream.MarketDefinition{Status: "OPEN"},
+		Rc: []*stream.RunnerChange{{Id: 11, Batb: [][]float64{{0, 2.0, 10}}}}},
+	{Id: "1.2", Img: true, MarketDefinition: &stream.MarketDefinition{Status: "OPEN"}},
+}
+
+func TestClient_SubscribeAndResume(t *testing.T) {
+	srv, err := streamtest.NewServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(image)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sc := stream.NewClient(srv.ClientConfig(), streamtest.DefaultCredentials())
+	defer sc.Close()
+
+	require.NoError(t, sc.Connect(ctx))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sc.SubscribeMarkets(ctx,
+		stream.MarketFilter{MarketIds: []string{"1.1"}},
+		stream.MarketDataFilter{Fields: []stream.MarketDataField{stream.EX_BEST_OFFERS}},
+	))
+
+	require.Eventually(t, func() bool { return sc.Markets().Len() == 1 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, conn.SendMarketChanges(&stream.MarketChange{Id: "1.1",
+		Rc: []*stream.RunnerChange{{Id: 11, Batb: [][]float64{{0, 2.02, 5}}}}}))
+
+	require.Eventually(t, func() bool {
+		book, _ := sc.Markets().MarketBook("1.1")
+		return len(book.Runners) == 1 && len(book.Runners[0].Ex.Back) == 1 && book.Runners[0].Ex.Back[0].Price == 2.02
+	}, time.Second, 10*time.Millisecond)
+
+	// Forced disconnect, the client must come back and resume from its clocks
+	conn.Disconnect()
+
+	resumed, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	sub, err := resumed.WaitForRequest(ctx, stream.OP_MARKET_SUB)
+	require.NoError(t, err)
+	assert.NotEmpty(t, sub.InitialClk)
+	assert.NotEmpty(t, sub.Clk)
+
+	require.Eventually(t, func() bool { return sc.Stats().Reconnects == 1 }, time.Second, 10*time.Millisecond)
+
+	// RESUB_DELTA keeps the cache as it was
+	book, ok := sc.Markets().MarketBook("1.1")
+	require.True(t, ok)
+	assert.Equal(t, float32(2.02), book.Runners[0].Ex.Back[0].Price)
+}
+
+func TestClient_RejectedCredentials(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = stream.Dial(ctx, srv.Config(), streamtest.Credentials{Key: streamtest.APP_KEY, Token: "expired"})
+	require.Error(t, err)
+	assert.True(t, stream.IsErrorCode(err, stream.INVALID_SESSION_INFORMATION))
+}
+
+func TestServer_SetCredentials(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetCredentials("other-key", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = stream.Dial(ctx, srv.Config(), streamtest.DefaultCredentials())
+	assert.True(t, stream.IsErrorCode(err, stream.INVALID_SESSION_INFORMATION))
+
+	// Any session token is accepted once the expected one is cleared
+	conn, err := stream.Dial(ctx, srv.Config(), streamtest.Credentials{Key: "other-key", Token: "anything"})
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestServer_NextConnKeepsEveryConnection(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// More connections than any fixed buffer, none may be lost before NextConn is called
+	const dials = 40
+	for i := 0; i < dials; i++ {
+		conn, err := stream.Dial(ctx, srv.Config(), streamtest.DefaultCredentials())
+		require.NoError(t, err)
+		defer conn.Close()
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < dials; i++ {
+		c, err := srv.NextConn(ctx)
+		require.NoError(t, err)
+		seen[c.ConnectionId()] = true
+	}
+	assert.Len(t, seen, dials)
+}
+
+func TestClient_SegmentedImageAppliedAtomically(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	images := make(chan *stream.ResponseMessage, 4)
+	cfg := srv.ClientConfig()
+	cfg.OnChange = func(msg *stream.ResponseMessage) {
+		if msg.Ct == stream.CT_SUB_IMAGE && len(msg.Mc) > 0 {
+			images <- msg
+		}
+	}
+
+	sc := stream.NewClient(cfg, streamtest.DefaultCredentials())
+	defer sc.Close()
+
+	require.NoError(t, sc.Connect(ctx))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sc.SubscribeMarkets(ctx, stream.MarketFilter{}, stream.MarketDataFilter{}))
+
+	changes := []*stream.MarketChange{
+		{Id: "1.1", Img: true}, {Id: "1.2", Img: true}, {Id: "1.3", Img: true}, {Id: "1.4", Img: true}, {Id: "1.5", Img: true},
+	}
+	require.NoError(t, conn.SendSegmentedImage(changes, 2))
+
+	select {
+	case msg := <-images:
+		assert.Len(t, msg.Mc, 5)
+		assert.Empty(t, msg.SegmentType)
+		assert.NotEmpty(t, msg.InitialClk)
+		assert.Equal(t, 5, sc.Markets().Len())
+	case <-ctx.Done():
+		t.Fatal("segmented image never delivered")
+	}
+}
+
+func TestPool_ShardsAndRebalances(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	var all []*stream.MarketChange
+	for _, id := range []string{"1.1", "1.2", "1.3", "1.4", "1.5"} {
+		all = append(all, &stream.MarketChange{Id: id, Img: true, MarketDefinition: &stream.MarketDefinition{Status: "OPEN"}})
+	}
+	srv.SetMarketImage(all)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	open := []string{"1.1", "1.2", "1.3", "1.4", "1.5"}
+	pool, err := stream.NewPool(stream.PoolConfig{
+		ClientConfig:   srv.ClientConfig(),
+		MarketsPerConn: 2,
+		Resolve: func(ctx context.Context, filter stream.MarketFilter) ([]string, error) {
+			return open, nil
+		},
+	}, streamtest.DefaultCredentials())
+	require.NoError(t, err)
+	defer pool.Close()
+
+	require.NoError(t, pool.Subscribe(ctx, stream.MarketFilter{EventTypeIds: []string{"7"}}, stream.MarketDataFilter{}))
+	assert.Equal(t, []int{2, 2, 1}, pool.Shards())
+	require.Eventually(t, func() bool { return pool.Markets().Len() == 5 }, time.Second, 10*time.Millisecond)
+
+	// Markets closing empties the last connection, which is released
+	open = []string{"1.1", "1.2", "1.3", "1.4"}
+	require.NoError(t, pool.Rebalance(ctx))
+	assert.Equal(t, []int{2, 2}, pool.Shards())
+	require.Eventually(t, func() bool {
+		_, ok := pool.Markets().MarketBook("1.5")
+		return !ok && pool.Markets().Len() == 4
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPool_RetriesFailedSubscription(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(append(image[:2:2], &stream.MarketChange{Id: "1.3", Img: true}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	open := []string{"1.1", "1.2"}
+	pool, err := stream.NewPool(stream.PoolConfig{
+		ClientConfig:   srv.ClientConfig(),
+		MarketsPerConn: 3,
+		Resolve: func(ctx context.Context, filter stream.MarketFilter) ([]string, error) {
+			return open, nil
+		},
+	}, streamtest.DefaultCredentials())
+	require.NoError(t, err)
+	defer pool.Close()
+
+	require.NoError(t, pool.Subscribe(ctx, stream.MarketFilter{EventTypeIds: []string{"7"}}, stream.MarketDataFilter{}))
+	assert.Equal(t, []int{2}, pool.Shards())
+
+	// The new market is only assigned once the subscription goes through
+	open = []string{"1.1", "1.2", "1.3"}
+	srv.FailNext(stream.OP_MARKET_SUB, stream.TOO_MANY_REQUESTS)
+	assert.Error(t, pool.Rebalance(ctx))
+	assert.Equal(t, []int{2}, pool.Shards())
+
+	require.NoError(t, pool.Rebalance(ctx))
+	assert.Equal(t, []int{3}, pool.Shards())
+	assert.Equal(t, []string{"1.1", "1.2", "1.3"}, pool.Markets().MarketIds())
+}
+
+func TestPool_ResubscribesWhenDataFilterChanges(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(image)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := stream.NewPool(stream.PoolConfig{
+		ClientConfig: srv.ClientConfig(),
+		Resolve: func(ctx context.Context, filter stream.MarketFilter) ([]string, error) {
+			return []string{"1.1", "1.2"}, nil
+		},
+	}, streamtest.DefaultCredentials())
+	require.NoError(t, err)
+	defer pool.Close()
+
+	filter := stream.MarketFilter{EventTypeIds: []string{"7"}}
+	require.NoError(t, pool.Subscribe(ctx, filter, stream.MarketDataFilter{LadderLevels: 1}, stream.WithConflateMs(50)))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	subscriptions := func() []*stream.RequestMessage {
+		var subs []*stream.RequestMessage
+		for _, req := range conn.Requests() {
+			if req.Op == stream.OP_MARKET_SUB {
+				subs = append(subs, req)
+			}
+		}
+		return subs
+	}
+
+	// Nothing changed, nothing is sent
+	require.NoError(t, pool.Subscribe(ctx, filter, stream.MarketDataFilter{LadderLevels: 1}, stream.WithConflateMs(50)))
+	assert.Len(t, subscriptions(), 1)
+
+	require.NoError(t, pool.Subscribe(ctx, filter, stream.MarketDataFilter{LadderLevels: 3}, stream.WithConflateMs(50)))
+	subs := subscriptions()
+	require.Len(t, subs, 2)
+	assert.Equal(t, 3, subs[1].MarketDataFilter.LadderLevels)
+	assert.Empty(t, subs[1].Clk)
+
+	require.NoError(t, pool.Subscribe(ctx, filter, stream.MarketDataFilter{LadderLevels: 3}, stream.WithConflateMs(200)))
+	subs = subscriptions()
+	require.Len(t, subs, 3)
+	assert.Equal(t, int64(200), subs[2].ConflateMs)
+	assert.Equal(t, 3, subs[2].MarketDataFilter.LadderLevels)
+
+	assert.Equal(t, []string{"1.1", "1.2"}, pool.Markets().MarketIds())
+	assert.Len(t, srv.Conns(), 1)
+}
+
+func TestPool_ClosedConnectionForgetsListenerState(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(image)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	open := []string{"1.1", "1.2"}
+	pool, err := stream.NewPool(stream.PoolConfig{
+		ClientConfig:   srv.ClientConfig(),
+		MarketsPerConn: 1,
+		Resolve: func(ctx context.Context, filter stream.MarketFilter) ([]string, error) {
+			return open, nil
+		},
+	}, streamtest.DefaultCredentials())
+	require.NoError(t, err)
+	defer pool.Close()
+
+	listener := pool.Listen("1.2", stream.ListenerOptions{})
+	defer listener.Close()
+
+	nextStatus := func() stream.StatusChanged {
+		t.Helper()
+		for {
+			select {
+			case e := <-listener.C():
+				if status, ok := e.(stream.StatusChanged); ok {
+					return status
+				}
+			case <-ctx.Done():
+				t.Fatal("no status change")
+			}
+		}
+	}
+
+	require.NoError(t, pool.Subscribe(ctx, stream.MarketFilter{EventTypeIds: []string{"7"}}, stream.MarketDataFilter{}))
+	assert.Equal(t, []int{1, 1}, pool.Shards())
+	assert.Equal(t, types.OPEN, nextStatus().Status)
+
+	// Dropping the market closes its connection, coming back on a new one starts from scratch
+	open = []string{"1.1"}
+	require.NoError(t, pool.Rebalance(ctx))
+	assert.Equal(t, []int{1}, pool.Shards())
+
+	open = []string{"1.1", "1.2"}
+	require.NoError(t, pool.Rebalance(ctx))
+	status := nextStatus()
+	assert.Empty(t, status.Previous)
+	assert.Equal(t, types.OPEN, status.Status)
+}
+
+// Hands out a bad session token while failing is set
+type flakyAuth struct {
+	streamtest.Credentials
+	failing atomic.Bool
+}
+
+func (a *flakyAuth) SessionToken() (string, error) {
+	if a.failing.Load() {
+		return "expired", nil
+	}
+	return a.Credentials.SessionToken()
+}
+
+func TestPool_ReplacesConnectionThatGaveUp(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(image)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	gaveUp := make(chan struct{}, 1)
+	cfg := srv.ClientConfig()
+	cfg.MaxReconnectAttempts = 1
+	cfg.OnError = func(err error) {
+		if strings.Contains(err.Error(), "max stream reconnect attempts") {
+			gaveUp <- struct{}{}
+		}
+	}
+
+	auth := &flakyAuth{Credentials: streamtest.DefaultCredentials()}
+	pool, err := stream.NewPool(stream.PoolConfig{
+		ClientConfig: cfg,
+		Resolve: func(ctx context.Context, filter stream.MarketFilter) ([]string, error) {
+			return []string{"1.1", "1.2"}, nil
+		},
+	}, auth)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	require.NoError(t, pool.Subscribe(ctx, stream.MarketFilter{EventTypeIds: []string{"7"}}, stream.MarketDataFilter{}))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	auth.failing.Store(true)
+	conn.Disconnect()
+	select {
+	case <-gaveUp:
+	case <-ctx.Done():
+		t.Fatal("connection never gave up reconnecting")
+	}
+
+	auth.failing.Store(false)
+	assert.ErrorContains(t, pool.Rebalance(ctx), "stopped reconnecting")
+	assert.Equal(t, []int{2}, pool.Shards())
+	assert.Equal(t, []string{"1.1", "1.2"}, pool.Markets().MarketIds())
+	assert.Len(t, srv.Conns(), 3) // original, failed reconnect, replacement
+}
+
+func TestClient_UpdateMarketSubscription(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(append(image[:2:2], &stream.MarketChange{Id: "1.3", Img: true}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sc := stream.NewClient(srv.ClientConfig(), streamtest.DefaultCredentials())
+	defer sc.Close()
+
+	require.NoError(t, sc.Connect(ctx))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sc.SubscribeMarkets(ctx, stream.MarketFilter{MarketIds: []string{"1.1"}}, stream.MarketDataFilter{}))
+	require.Eventually(t, func() bool { return sc.Markets().Len() == 1 }, time.Second, 10*time.Millisecond)
+
+	diff, err := sc.UpdateMarketSubscription(ctx, stream.MarketFilter{MarketIds: []string{"1.2"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2"}, diff.Added)
+	assert.Equal(t, []string{"1.1"}, diff.Removed)
+	assert.Equal(t, []string{"1.2"}, sc.Markets().MarketIds())
+
+	// Same connection, resumed from the current clocks so only the new market is imaged
+	requests := conn.Requests()
+	last := requests[len(requests)-1]
+	assert.Equal(t, stream.OP_MARKET_SUB, last.Op)
+	assert.NotEmpty(t, last.Clk)
+	assert.NotEmpty(t, last.InitialClk)
+
+	require.NoError(t, conn.SendMarketChanges(&stream.MarketChange{Id: "1.2", Tv: 50}))
+	require.Eventually(t, func() bool {
+		book, _ := sc.Markets().MarketBook("1.2")
+		return book.TotalMatched == 50
+	}, time.Second, 10*time.Millisecond)
+
+	// Growing the set keeps the markets already held as they are
+	diff, err = sc.UpdateMarketSubscription(ctx, stream.MarketFilter{MarketIds: []string{"1.2", "1.3"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.3"}, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Equal(t, []string{"1.2", "1.3"}, sc.Markets().MarketIds())
+
+	book, _ := sc.Markets().MarketBook("1.2")
+	assert.Equal(t, float32(50), book.TotalMatched)
+
+	assert.Zero(t, sc.Stats().Reconnects)
+}
+
+func TestClient_UpdateMarketSubscriptionFallsBackToFullImage(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(image)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sc := stream.NewClient(srv.ClientConfig(), streamtest.DefaultCredentials())
+	defer sc.Close()
+
+	require.NoError(t, sc.Connect(ctx))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sc.SubscribeMarkets(ctx, stream.MarketFilter{MarketIds: []string{"1.1"}}, stream.MarketDataFilter{}))
+	require.Eventually(t, func() bool { return sc.Markets().Len() == 1 }, time.Second, 10*time.Millisecond)
+
+	srv.FailNext(stream.OP_MARKET_SUB, stream.INVALID_CLOCK)
+	diff, err := sc.UpdateMarketSubscription(ctx, stream.MarketFilter{MarketIds: []string{"1.1", "1.2"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2"}, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Equal(t, []string{"1.1", "1.2"}, sc.Markets().MarketIds())
+
+	requests := conn.Requests()
+	retry := requests[len(requests)-1]
+	assert.NotEmpty(t, requests[len(requests)-2].Clk)
+	assert.Empty(t, retry.Clk)
+	assert.Empty(t, retry.InitialClk)
+}
+
+func TestClient_ImageForgetsTransitionsOfDroppedMarkets(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(image)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sc := stream.NewClient(srv.ClientConfig(), streamtest.DefaultCredentials())
+	defer sc.Close()
+	require.NoError(t, sc.Connect(ctx))
+
+	l := sc.Listen("1.2", stream.ListenerOptions{})
+	defer l.Close()
+
+	nextStatus := func() stream.StatusChanged {
+		for {
+			select {
+			case e := <-l.C():
+				if status, ok := e.(stream.StatusChanged); ok {
+					return status
+				}
+			case <-ctx.Done():
+				t.Fatal("no status change")
+			}
+		}
+	}
+
+	both := stream.MarketFilter{MarketIds: []string{"1.1", "1.2"}}
+	require.NoError(t, sc.SubscribeMarkets(ctx, both, stream.MarketDataFilter{}))
+	assert.Equal(t, types.MarketStatus(""), nextStatus().Previous)
+
+	// 1.2 leaves with the full image, coming back later is a fresh start
+	require.NoError(t, sc.SubscribeMarkets(ctx, stream.MarketFilter{MarketIds: []string{"1.1"}}, stream.MarketDataFilter{}))
+	require.Eventually(t, func() bool { return sc.Markets().Len() == 1 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, sc.SubscribeMarkets(ctx, both, stream.MarketDataFilter{}))
+	status := nextStatus()
+	assert.Equal(t, types.MarketStatus(""), status.Previous)
+	assert.Equal(t, types.OPEN, status.Status)
+}
+
+func TestClient_FailedUpdateKeepsPreviousSubscription(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(image)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sc := stream.NewClient(srv.ClientConfig(), streamtest.DefaultCredentials())
+	defer sc.Close()
+
+	require.NoError(t, sc.Connect(ctx))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sc.SubscribeMarkets(ctx, stream.MarketFilter{MarketIds: []string{"1.1"}}, stream.MarketDataFilter{}))
+	require.Eventually(t, func() bool { return sc.Markets().Len() == 1 }, time.Second, 10*time.Millisecond)
+
+	srv.FailNext(stream.OP_MARKET_SUB, stream.TOO_MANY_REQUESTS)
+	_, err = sc.UpdateMarketSubscription(ctx, stream.MarketFilter{MarketIds: []string{"1.2"}})
+	require.True(t, stream.IsErrorCode(err, stream.TOO_MANY_REQUESTS))
+
+	// Reconnecting resumes the subscription Betfair still has
+	conn.Disconnect()
+	next, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	resub, err := next.WaitForRequest(ctx, stream.OP_MARKET_SUB)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.1"}, resub.MarketFilter.MarketIds)
+	assert.NotEmpty(t, resub.Clk)
+	assert.Equal(t, []string{"1.1"}, sc.Markets().MarketIds())
+}
+
+func TestClient_EvictsClosedMarkets(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(image)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	closed := make(chan types.ListMarketBookResponse, 1)
+	cfg := srv.ClientConfig()
+	cfg.OnMarketClosed = func(book types.ListMarketBookResponse) { closed <- book }
+
+	sc := stream.NewClient(cfg, streamtest.DefaultCredentials())
+	defer sc.Close()
+
+	require.NoError(t, sc.Connect(ctx))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sc.SubscribeMarkets(ctx, stream.MarketFilter{MarketIds: []string{"1.1", "1.2"}}, stream.MarketDataFilter{}))
+	require.Eventually(t, func() bool { return sc.Markets().Len() == 2 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, conn.SendMarketChanges(&stream.MarketChange{Id: "1.1", MarketDefinition: &stream.MarketDefinition{Status: "CLOSED"}}))
+
+	select {
+	case book := <-closed:
+		assert.Equal(t, "1.1", book.MarketId)
+		assert.Equal(t, types.CLOSED, book.Status)
+	case <-ctx.Done():
+		t.Fatal("market close not reported")
+	}
+
+	require.NoError(t, conn.SendMarketChanges(&stream.MarketChange{Id: "1.2", Tv: 10}))
+	require.Eventually(t, func() bool {
+		ids := sc.Markets().MarketIds()
+		return len(ids) == 1 && ids[0] == "1.2"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestClient_FullImageAfterReconnectReplacesMarkets(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(image)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sc := stream.NewClient(srv.ClientConfig(), streamtest.DefaultCredentials())
+	defer sc.Close()
+
+	require.NoError(t, sc.Connect(ctx))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sc.SubscribeMarkets(ctx, stream.MarketFilter{MarketIds: []string{"1.1", "1.2"}}, stream.MarketDataFilter{}))
+	require.Eventually(t, func() bool { return sc.Markets().Len() == 2 }, time.Second, 10*time.Millisecond)
+
+	// Betfair cannot resume and sends a fresh image in which 1.2 is gone and 1.1 has moved
+	srv.SetForceFullImage(true)
+	srv.SetMarketImage([]*stream.MarketChange{{Id: "1.1", Img: true, MarketDefinition: &stream.MarketDefinition{Status: "SUSPENDED"},
+		Rc: []*stream.RunnerChange{{Id: 11, Batb: [][]float64{{0, 3.0, 7}}}}}})
+	conn.Disconnect()
+
+	resumed, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+	sub, err := resumed.WaitForRequest(ctx, stream.OP_MARKET_SUB)
+	require.NoError(t, err)
+	assert.NotEmpty(t, sub.Clk)
+
+	require.Eventually(t, func() bool {
+		book, ok := sc.Markets().MarketBook("1.1")
+		return ok && book.Status == types.SUSPENDED
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, []string{"1.1"}, sc.Markets().MarketIds())
+	book, _ := sc.Markets().MarketBook("1.1")
+	assert.Equal(t, []types.RunnerPrice{{Price: 3.0, Size: 7}}, book.Runners[0].Ex.Back)
+}
+
+func TestClient_FullImageAfterReconnectCompletesMissingOrders(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events := make(chan stream.OrderEvent, 16)
+	cfg := srv.ClientConfig()
+	cfg.OnOrderEvent = func(e stream.OrderEvent) { events <- e }
+
+	sc := stream.NewClient(cfg, streamtest.DefaultCredentials())
+	defer sc.Close()
+
+	require.NoError(t, sc.Connect(ctx))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sc.SubscribeOrders(ctx, stream.OrderFilter{}))
+	_, err = conn.WaitForRequest(ctx, stream.OP_ORDER_SUB)
+	require.NoError(t, err)
+
+	executable := func(id string) *stream.Order {
+		return &stream.Order{Id: id, Price: 2.5, Size: 10, Side: "B", Status: stream.ORDER_STATUS_EXECUTABLE, SizeRemaining: 10}
+	}
+	require.NoError(t, conn.SendOrderChanges(&stream.OrderMarketChange{Id: "1.1",
+		Orc: []*stream.OrderRunnerChange{{Id: 11, Uo: []*stream.Order{executable("100"), executable("101")}}}}))
+	require.Eventually(t, func() bool { return len(sc.Orders().UnmatchedOrders("1.1")) == 2 }, time.Second, 10*time.Millisecond)
+
+	// Order 100 finished during the gap, so the image only carries 101
+	srv.SetForceFullImage(true)
+	srv.SetOrderImage([]*stream.OrderMarketChange{{Id: "1.1", FullImage: true,
+		Orc: []*stream.OrderRunnerChange{{Id: 11, FullImage: true, Uo: []*stream.Order{executable("101")}}}}})
+	conn.Disconnect()
+
+	for {
+		select {
+		case e := <-events:
+			if e.Type != stream.ORDER_COMPLETE {
+				continue
+			}
+			assert.Equal(t, "100", e.Order.Id)
+
+			unmatched := sc.Orders().UnmatchedOrders("1.1")
+			require.Len(t, unmatched, 1)
+			assert.Equal(t, "101", unmatched[0].Id)
+			return
+		case <-ctx.Done():
+			t.Fatal("order missing from the image was not completed")
+		}
+	}
+}
+
+func TestClient_InvalidClockResubscribesWithoutClocks(t *testing.T) {
+	srv, err := streamtest.NewPlainServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.SetMarketImage(image)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sc := stream.NewClient(srv.ClientConfig(), streamtest.DefaultCredentials())
+	defer sc.Close()
+
+	require.NoError(t, sc.Connect(ctx))
+	conn, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, sc.SubscribeMarkets(ctx, stream.MarketFilter{MarketIds: []string{"1.1"}}, stream.MarketDataFilter{}))
+	require.NoError(t, sc.SubscribeOrders(ctx, stream.OrderFilter{}))
+	require.NoError(t, conn.SendOrderChanges(&stream.OrderMarketChange{Id: "1.1"}))
+
+	// Both clocks are too old to resume from
+	srv.FailNext(stream.OP_MARKET_SUB, stream.INVALID_CLOCK)
+	srv.FailNext(stream.OP_ORDER_SUB, stream.INVALID_CLOCK)
+	conn.Disconnect()
+
+	resumed, err := srv.NextConn(ctx)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return sc.Stats().Reconnects == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	var subs []*stream.RequestMessage
+	for _, req := range resumed.Requests() {
+		if req.Op == stream.OP_MARKET_SUB || req.Op == stream.OP_ORDER_SUB {
+			subs = append(subs, req)
+		}
+	}
+	require.Len(t, subs, 4)
+
+	for i, op := range []string{stream.OP_MARKET_SUB, stream.OP_ORDER_SUB} {
+		rejected, retried := subs[2*i], subs[2*i+1]
+		assert.Equal(t, op, rejected.Op)
+		assert.NotEmpty(t, rejected.Clk)
+		assert.Equal(t, op, retried.Op)
+		assert.Empty(t, retried.InitialClk)
+		assert.Empty(t, retried.Clk)
+	}
+
+	assert.Equal(t, []string{"1.1"}, sc.Markets().MarketIds())
+}
@@ -1,46 +1,122 @@
-//go:build integration
+// tests/client_integration_test.go
 
 package tests
 
+// Runs against a local betfairtest server by default.
+// Set BETFAIR_LIVE=1 (in the environment or .env) to run against Betfair with real credentials.
+
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/Bazcampbell/betfair-api-go-sdk/betfairtest"
 	"github.com/Bazcampbell/betfair-api-go-sdk/client"
 	"github.com/Bazcampbell/betfair-api-go-sdk/types"
 
 	"github.com/joho/godotenv"
 )
 
-func TestClientUsesGoodProxy(t *testing.T) {
+func live() bool {
 	_ = godotenv.Load(".env")
+	return os.Getenv("BETFAIR_LIVE") == "1"
+}
+
+func liveCredentials(t *testing.T) types.BetfairCredentials {
+	t.Helper()
 
-	proxy := os.Getenv("HTTP_PROXY")
 	key := os.Getenv("BETFAIR_KEY_BASE64")
 	cert := os.Getenv("BETFAIR_CERT_BASE64")
 	username := os.Getenv("BETFAIR_USERNAME")
 	password := os.Getenv("BETFAIR_PASSWORD")
 	appKey := os.Getenv("BETFAIR_APP_KEY")
 
-	if key == "" || cert == "" || username == "" || password == "" || appKey == "" || proxy == "" {
-		t.Fatalf("Betfair credentials or proxy not set")
+	if key == "" || cert == "" || username == "" || password == "" || appKey == "" {
+		t.Fatalf("Betfair credentials not set")
 	}
 
-	creds := types.BetfairCredentials{
+	return types.BetfairCredentials{
 		Username:   username,
 		Password:   password,
 		AppKey:     appKey,
 		KeyString:  key,
 		CertString: cert,
-		ProxyUrl:   &proxy,
+	}
+}
+
+func localServer(t *testing.T) *betfairtest.Server {
+	t.Helper()
+
+	srv, err := betfairtest.NewServer()
+	if err != nil {
+		t.Fatalf("unable to start server: %v", err)
+	}
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// Forwarding HTTP proxy that counts the requests passing through it
+func localProxy(t *testing.T) (string, *atomic.Int64) {
+	t.Helper()
+
+	var hits atomic.Int64
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+
+		out := r.Clone(r.Context())
+		out.RequestURI = ""
+
+		resp, err := http.DefaultTransport.RoundTrip(out)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(proxy.Close)
+
+	return proxy.URL, &hits
+}
+
+func TestClientUsesGoodProxy(t *testing.T) {
+	var creds types.BetfairCredentials
+	var opts []client.Option
+	var srv *betfairtest.Server
+	var hits *atomic.Int64
+
+	if live() {
+		creds = liveCredentials(t)
+		proxy := os.Getenv("HTTP_PROXY")
+		if proxy == "" {
+			t.Fatalf("proxy not set")
+		}
+		creds.ProxyUrl = &proxy
+	} else {
+		srv = localServer(t)
+		creds = srv.Credentials()
+
+		var proxy string
+		proxy, hits = localProxy(t)
+		creds.ProxyUrl = &proxy
+		opts = append(opts, client.WithBaseURL(srv.URL()))
 	}
 
 	onErrorFunc := func(err error) {
 		t.Fatalf("betfair client error: %v", err)
 	}
 
-	bfClient, err := client.NewSession(creds, onErrorFunc)
+	bfClient, err := client.NewSession(creds, onErrorFunc, opts...)
 	if err != nil {
 		t.Fatalf("error creating session with proxy: %v", err)
 	}
@@ -51,34 +127,43 @@ func TestClientUsesGoodProxy(t *testing.T) {
 	if err != nil {
 		t.Fatalf("betfair call failed via proxy: %v", err)
 	}
-}
 
-func TestClientUsesNonAusProxy(t *testing.T) {
-	_ = godotenv.Load(".env")
+	if srv == nil {
+		return
+	}
 
-	key := os.Getenv("BETFAIR_KEY_BASE64")
-	cert := os.Getenv("BETFAIR_CERT_BASE64")
-	username := os.Getenv("BETFAIR_USERNAME")
-	password := os.Getenv("BETFAIR_PASSWORD")
-	appKey := os.Getenv("BETFAIR_APP_KEY")
+	var req types.ListRequest
+	if err := srv.AssertCalled(t, "listEventTypes").Decode(&req); err != nil {
+		t.Fatalf("unable to decode request: %v", err)
+	}
+	if len(req.Filter.MarketCountries) != 1 || req.Filter.MarketCountries[0] != "AU" {
+		t.Fatalf("unexpected filter sent: %+v", req.Filter)
+	}
 
-	if key == "" || cert == "" || username == "" || password == "" || appKey == "" {
-		t.Fatalf("Betfair credentials not set")
+	// Login and the call both go through the proxy
+	if hits.Load() < 2 {
+		t.Fatalf("expected requests to go through the proxy, got %d", hits.Load())
 	}
+}
 
-	creds := types.BetfairCredentials{
-		Username:   username,
-		Password:   password,
-		AppKey:     appKey,
-		KeyString:  key,
-		CertString: cert,
+func TestClientUsesNonAusProxy(t *testing.T) {
+	var creds types.BetfairCredentials
+	var opts []client.Option
+
+	if live() {
+		creds = liveCredentials(t)
+	} else {
+		srv := localServer(t)
+		srv.FailNext(betfairtest.OP_LOGIN, "BETTING_RESTRICTED_LOCATION")
+		creds = srv.Credentials()
+		opts = append(opts, client.WithBaseURL(srv.URL()))
 	}
 
 	onErrorFunc := func(err error) {
 		t.Fatalf("betfair client error: %v", err)
 	}
 
-	_, err := client.NewSession(creds, onErrorFunc)
+	_, err := client.NewSession(creds, onErrorFunc, opts...)
 	if err == nil {
 		t.Fatal("expected BETTING_RESTRICTED_LOCATION error, got none")
 	}
@@ -52,6 +52,18 @@ const (
 	CLOSED    MarketStatus = "CLOSED"
 )
 
+type RunnerStatus string
+
+const (
+	ACTIVE         RunnerStatus = "ACTIVE"
+	WINNER         RunnerStatus = "WINNER"
+	LOSER          RunnerStatus = "LOSER"
+	PLACED         RunnerStatus = "PLACED"
+	REMOVED_VACANT RunnerStatus = "REMOVED_VACANT"
+	REMOVED        RunnerStatus = "REMOVED"
+	HIDDEN         RunnerStatus = "HIDDEN"
+)
+
 type Side string
 
 const (
@@ -74,3 +86,36 @@ const (
 	PAYOUT RollupModel = "PAYOUT"
 	NONE   RollupModel = "NONE"
 )
+
+type RaceStatus string
+
+const (
+	DORMANT     RaceStatus = "DORMANT"
+	DELAYED     RaceStatus = "DELAYED"
+	PARADING    RaceStatus = "PARADING"
+	GOINGDOWN   RaceStatus = "GOINGDOWN"
+	GOINGBEHIND RaceStatus = "GOINGBEHIND"
+	ATTHEPOST   RaceStatus = "ATTHEPOST"
+	UNDERORDERS RaceStatus = "UNDERORDERS"
+	OFF         RaceStatus = "OFF"
+	FINISHED    RaceStatus = "FINISHED"
+	FALSESTART  RaceStatus = "FALSESTART"
+	PHOTOGRAPH  RaceStatus = "PHOTOGRAPH"
+	RESULT      RaceStatus = "RESULT"
+	WEIGHEDIN   RaceStatus = "WEIGHEDIN"
+	RACEVOID    RaceStatus = "RACEVOID"
+	ABANDONED   RaceStatus = "ABANDONED"
+	APPROACHING RaceStatus = "APPROACHING"
+	GOINGAROUND RaceStatus = "GOINGAROUND"
+	FINALRESULT RaceStatus = "FINALRESULT"
+	NORACE      RaceStatus = "NORACE"
+	RERUN       RaceStatus = "RERUN"
+)
+
+type PriceLadderType string
+
+const (
+	CLASSIC    PriceLadderType = "CLASSIC"
+	FINEST     PriceLadderType = "FINEST"
+	LINE_RANGE PriceLadderType = "LINE_RANGE"
+)
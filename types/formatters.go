@@ -139,6 +139,41 @@ func (l ListMarketBookResponse) String() string {
 	return result
 }
 
+// RaceDetails String method
+func (r RaceDetails) String() string {
+	return fmt.Sprintf("Race{Id: %s, Meeting: %s, Status: %s, Updated: %s}",
+		r.RaceId, r.MeetingId, r.RaceStatus, r.LastUpdated)
+}
+
+// NavigationNode String method
+func (n NavigationNode) String() string {
+	return fmt.Sprintf("%s{Id: %s, Name: %s, Children: %d}", n.Type, n.Id, n.Name, len(n.Children))
+}
+
+// Score String method
+func (s Score) String() string {
+	return fmt.Sprintf("Score{Event: %s, Status: %s, Sequence: %d}",
+		s.EventId, s.EventStatus, s.UpdateContext.UpdateSequence)
+}
+
+// SoccerScore String method
+func (s SoccerScore) String() string {
+	return fmt.Sprintf("%s %d - %d %s (%d')", s.Home.Name, s.Home.Score, s.Away.Score, s.Away.Name, s.TimeElapsed)
+}
+
+// TennisScore String method
+func (s TennisScore) String() string {
+	return fmt.Sprintf("%s %d/%d/%s - %s %d/%d/%s",
+		s.Home.Name, s.Home.Sets, s.Home.Games, s.Home.Score,
+		s.Away.Name, s.Away.Sets, s.Away.Games, s.Away.Score)
+}
+
+// AccountFundsResponse String method
+func (a AccountFundsResponse) String() string {
+	return fmt.Sprintf("AccountFunds{Available: %.2f, Exposure: %.2f, Wallet: %s}",
+		a.AvailableToBetBalance, a.Exposure, a.Wallet)
+}
+
 // LoginResponse String method
 func (l LoginResponse) String() string {
 	return fmt.Sprintf("Login{Status: %s, SessionToken: %s...}", l.Status, truncate(l.SessionToken, 10))
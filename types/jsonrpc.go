@@ -0,0 +1,66 @@
+// types/jsonrpc.go
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type RPCRequest struct {
+	JsonRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+	Id      int    `json:"id"`
+}
+
+type RPCResponse struct {
+	JsonRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	Id      int             `json:"id"`
+}
+
+type RPCError struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    *RPCErrorData `json:"data,omitempty"`
+}
+
+type RPCErrorData struct {
+	ExceptionName         string          `json:"exceptionname,omitempty"`
+	APINGException        *APINGException `json:"APINGException,omitempty"`
+	AccountAPINGException *APINGException `json:"AccountAPINGException,omitempty"`
+}
+
+// Betfair's fault detail, e.g. INVALID_SESSION_INFORMATION or TOO_MUCH_DATA
+type APINGException struct {
+	ErrorCode    string `json:"errorCode"`
+	ErrorDetails string `json:"errorDetails"`
+	RequestUUID  string `json:"requestUUID"`
+}
+
+// Returns the APING error code if Betfair supplied one
+func (e *RPCError) ErrorCode() string {
+	if e.Data == nil {
+		return ""
+	}
+
+	if e.Data.APINGException != nil {
+		return e.Data.APINGException.ErrorCode
+	}
+
+	if e.Data.AccountAPINGException != nil {
+		return e.Data.AccountAPINGException.ErrorCode
+	}
+
+	return ""
+}
+
+func (e *RPCError) Error() string {
+	if code := e.ErrorCode(); code != "" {
+		return fmt.Sprintf("json-rpc error %d: %s (%s)", e.Code, e.Message, code)
+	}
+
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
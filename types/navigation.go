@@ -0,0 +1,139 @@
+// types/navigation.go
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type NavigationNodeType string
+
+const (
+	NAV_GROUP      NavigationNodeType = "GROUP"
+	NAV_EVENT_TYPE NavigationNodeType = "EVENT_TYPE"
+	NAV_EVENT      NavigationNodeType = "EVENT"
+	NAV_RACE       NavigationNodeType = "RACE"
+	NAV_MARKET     NavigationNodeType = "MARKET"
+)
+
+// Navigation menu ids are numbers for some node types and strings for others
+type NavigationId string
+
+func (n *NavigationId) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*n = NavigationId(s)
+		return nil
+	}
+
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return fmt.Errorf("invalid navigation id %s: %w", string(data), err)
+	}
+
+	*n = NavigationId(num.String())
+	return nil
+}
+
+// A single node of the navigation menu
+// Fields beyond Type/Name/Id are only populated for the node types that carry them
+type NavigationNode struct {
+	Type     NavigationNodeType `json:"type"`
+	Name     string             `json:"name"`
+	Id       NavigationId       `json:"id"`
+	Children []*NavigationNode  `json:"children,omitempty"`
+
+	// EVENT and RACE
+	CountryCode string `json:"countryCode,omitempty"`
+
+	// RACE
+	Venue      string `json:"venue,omitempty"`
+	StartTime  string `json:"startTime,omitempty"`
+	RaceNumber string `json:"raceNumber,omitempty"`
+
+	// MARKET
+	ExchangeId      string `json:"exchangeId,omitempty"`
+	MarketType      string `json:"marketType,omitempty"`
+	MarketStartTime string `json:"marketStartTime,omitempty"`
+	NumberOfWinners int    `json:"numberOfWinners,omitempty"`
+}
+
+// Visits every node depth first, parents before children
+// The path holds the ancestors of the node, root first
+// Returning false from fn skips the node's children
+func (n *NavigationNode) Walk(fn func(node *NavigationNode, path []*NavigationNode) bool) {
+	n.walk(nil, fn)
+}
+
+func (n *NavigationNode) walk(path []*NavigationNode, fn func(*NavigationNode, []*NavigationNode) bool) {
+	if !fn(n, path) {
+		return
+	}
+
+	// Siblings must not share a backing array, fn may keep the path it was given
+	path = append(slices.Clone(path), n)
+	for _, child := range n.Children {
+		child.walk(path, fn)
+	}
+}
+
+// Returns every node of the given type below (and including) n
+func (n *NavigationNode) FilterByType(nodeType NavigationNodeType) []*NavigationNode {
+	var result []*NavigationNode
+
+	n.Walk(func(node *NavigationNode, _ []*NavigationNode) bool {
+		if node.Type == nodeType {
+			result = append(result, node)
+		}
+		return true
+	})
+
+	return result
+}
+
+// Returns every node whose name contains query, case insensitive
+func (n *NavigationNode) SearchByName(query string) []*NavigationNode {
+	query = strings.ToLower(query)
+	var result []*NavigationNode
+
+	n.Walk(func(node *NavigationNode, _ []*NavigationNode) bool {
+		if strings.Contains(strings.ToLower(node.Name), query) {
+			result = append(result, node)
+		}
+		return true
+	})
+
+	return result
+}
+
+// Returns the ids of every market below (and including) n
+// The ids can be passed straight to ListMarketBook
+func (n *NavigationNode) MarketIds() []string {
+	var ids []string
+
+	for _, market := range n.FilterByType(NAV_MARKET) {
+		ids = append(ids, string(market.Id))
+	}
+
+	return ids
+}
+
+// Collects the market ids below every node in nodes, without duplicates
+func MarketIdsOf(nodes []*NavigationNode) []string {
+	seen := make(map[string]bool)
+	var ids []string
+
+	for _, node := range nodes {
+		for _, id := range node.MarketIds() {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids
+}
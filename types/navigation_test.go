@@ -0,0 +1,88 @@
+// types/navigation_test.go
+
+package types_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const menuJSON = `{
+  "type": "GROUP", "name": "ROOT", "id": 0,
+  "children": [
+    {"type": "EVENT_TYPE", "name": "Horse Racing", "id": "7", "children": [
+      {"type": "GROUP", "name": "AUS", "id": 123, "children": [
+        {"type": "RACE", "name": "R1 1200m", "id": "32861234.0130", "venue": "Flemington", "countryCode": "AU", "children": [
+          {"type": "MARKET", "name": "R1 1200m Hcap", "id": "1.200000001", "marketType": "WIN", "numberOfWinners": 1},
+          {"type": "MARKET", "name": "To Be Placed", "id": "1.200000002", "marketType": "PLACE", "numberOfWinners": 3}
+        ]}
+      ]}
+    ]},
+    {"type": "EVENT_TYPE", "name": "Soccer", "id": "1", "children": [
+      {"type": "EVENT", "name": "Arsenal v Chelsea", "id": "33000001", "countryCode": "GB", "children": [
+        {"type": "MARKET", "name": "Match Odds", "id": "1.300000001", "marketType": "MATCH_ODDS"}
+      ]}
+    ]}
+  ]
+}`
+
+func TestNavigationMenu(t *testing.T) {
+	var root types.NavigationNode
+	require.NoError(t, json.Unmarshal([]byte(menuJSON), &root))
+
+	assert.Equal(t, types.NavigationId("0"), root.Id)
+	assert.Equal(t, []string{"1.200000001", "1.200000002", "1.300000001"}, root.MarketIds())
+
+	races := root.FilterByType(types.NAV_RACE)
+	require.Len(t, races, 1)
+	assert.Equal(t, "Flemington", races[0].Venue)
+
+	soccer := root.SearchByName("soccer")
+	require.Len(t, soccer, 1)
+	assert.Equal(t, []string{"1.300000001"}, types.MarketIdsOf(soccer))
+
+	var depth int
+	root.Walk(func(node *types.NavigationNode, path []*types.NavigationNode) bool {
+		if node.Id == "1.200000001" {
+			depth = len(path)
+		}
+		return node.Type != types.NAV_EVENT
+	})
+	assert.Equal(t, 4, depth)
+}
+
+func TestNavigationWalk_PathsAreNotShared(t *testing.T) {
+	var root types.NavigationNode
+	require.NoError(t, json.Unmarshal([]byte(`{"type": "GROUP", "name": "ROOT", "id": 0, "children": [
+		{"type": "EVENT_TYPE", "name": "Horse Racing", "id": "7", "children": [
+			{"type": "GROUP", "name": "AUS", "id": 123, "children": [
+				{"type": "RACE", "name": "R1", "id": "1.0130", "children": [{"type": "MARKET", "name": "Win", "id": "1.1"}]},
+				{"type": "RACE", "name": "R2", "id": "1.0200", "children": [{"type": "MARKET", "name": "Win", "id": "1.2"}]}
+			]}
+		]}
+	]}`), &root))
+
+	// Keep every path, later siblings must not overwrite earlier ones
+	paths := make(map[types.NavigationId][]*types.NavigationNode)
+	root.Walk(func(node *types.NavigationNode, path []*types.NavigationNode) bool {
+		paths[node.Id] = path
+		return true
+	})
+
+	names := func(path []*types.NavigationNode) []string {
+		var result []string
+		for _, node := range path {
+			result = append(result, node.Name)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"ROOT", "Horse Racing", "AUS", "R1"}, names(paths["1.1"]))
+	assert.Equal(t, []string{"ROOT", "Horse Racing", "AUS", "R2"}, names(paths["1.2"]))
+	assert.Equal(t, []string{"ROOT", "Horse Racing", "AUS"}, names(paths["1.0130"]))
+}
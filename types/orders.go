@@ -0,0 +1,338 @@
+// types/orders.go
+
+package types
+
+// Betting operations: placeOrders, cancelOrders, replaceOrders, updateOrders and listCurrentOrders
+
+type OrderType string
+
+const (
+	LIMIT           OrderType = "LIMIT"
+	LIMIT_ON_CLOSE  OrderType = "LIMIT_ON_CLOSE"
+	MARKET_ON_CLOSE OrderType = "MARKET_ON_CLOSE"
+)
+
+type PersistenceType string
+
+const (
+	LAPSE                       PersistenceType = "LAPSE"
+	PERSIST                     PersistenceType = "PERSIST"
+	PERSISTENCE_MARKET_ON_CLOSE PersistenceType = "MARKET_ON_CLOSE"
+)
+
+type TimeInForce string
+
+const (
+	FILL_OR_KILL TimeInForce = "FILL_OR_KILL"
+)
+
+type BetTargetType string
+
+const (
+	TARGET_BACKERS_PROFIT BetTargetType = "BACKERS_PROFIT"
+	TARGET_PAYOUT         BetTargetType = "PAYOUT"
+)
+
+type OrderStatus string
+
+const (
+	ORDER_PENDING            OrderStatus = "PENDING"
+	ORDER_EXECUTION_COMPLETE OrderStatus = "EXECUTION_COMPLETE"
+	ORDER_EXECUTABLE         OrderStatus = "EXECUTABLE"
+	ORDER_EXPIRED            OrderStatus = "EXPIRED"
+)
+
+type ExecutionReportStatus string
+
+const (
+	EXECUTION_SUCCESS               ExecutionReportStatus = "SUCCESS"
+	EXECUTION_FAILURE               ExecutionReportStatus = "FAILURE"
+	EXECUTION_PROCESSED_WITH_ERRORS ExecutionReportStatus = "PROCESSED_WITH_ERRORS"
+	EXECUTION_TIMEOUT               ExecutionReportStatus = "TIMEOUT"
+)
+
+type InstructionReportStatus string
+
+const (
+	INSTRUCTION_SUCCESS InstructionReportStatus = "SUCCESS"
+	INSTRUCTION_FAILURE InstructionReportStatus = "FAILURE"
+	INSTRUCTION_TIMEOUT InstructionReportStatus = "TIMEOUT"
+)
+
+// Why a whole request failed
+type ExecutionReportErrorCode string
+
+const (
+	ERROR_IN_MATCHER            ExecutionReportErrorCode = "ERROR_IN_MATCHER"
+	PROCESSED_WITH_ERRORS       ExecutionReportErrorCode = "PROCESSED_WITH_ERRORS"
+	BET_ACTION_ERROR            ExecutionReportErrorCode = "BET_ACTION_ERROR"
+	INVALID_ACCOUNT_STATE       ExecutionReportErrorCode = "INVALID_ACCOUNT_STATE"
+	INVALID_WALLET_STATUS       ExecutionReportErrorCode = "INVALID_WALLET_STATUS"
+	INSUFFICIENT_FUNDS          ExecutionReportErrorCode = "INSUFFICIENT_FUNDS"
+	LOSS_LIMIT_EXCEEDED         ExecutionReportErrorCode = "LOSS_LIMIT_EXCEEDED"
+	MARKET_SUSPENDED            ExecutionReportErrorCode = "MARKET_SUSPENDED"
+	MARKET_NOT_OPEN_FOR_BETTING ExecutionReportErrorCode = "MARKET_NOT_OPEN_FOR_BETTING"
+	DUPLICATE_TRANSACTION       ExecutionReportErrorCode = "DUPLICATE_TRANSACTION"
+	INVALID_ORDER               ExecutionReportErrorCode = "INVALID_ORDER"
+	INVALID_MARKET_ID           ExecutionReportErrorCode = "INVALID_MARKET_ID"
+	PERMISSION_DENIED           ExecutionReportErrorCode = "PERMISSION_DENIED"
+	DUPLICATE_BETIDS            ExecutionReportErrorCode = "DUPLICATE_BETIDS"
+	NO_ACTION_REQUIRED          ExecutionReportErrorCode = "NO_ACTION_REQUIRED"
+	SERVICE_UNAVAILABLE         ExecutionReportErrorCode = "SERVICE_UNAVAILABLE"
+	REJECTED_BY_REGULATOR       ExecutionReportErrorCode = "REJECTED_BY_REGULATOR"
+	NO_CHASING                  ExecutionReportErrorCode = "NO_CHASING"
+	REGULATOR_IS_NOT_AVAILABLE  ExecutionReportErrorCode = "REGULATOR_IS_NOT_AVAILABLE"
+	TOO_MANY_INSTRUCTIONS       ExecutionReportErrorCode = "TOO_MANY_INSTRUCTIONS"
+	INVALID_MARKET_VERSION      ExecutionReportErrorCode = "INVALID_MARKET_VERSION"
+)
+
+// Why a single instruction failed
+type InstructionReportErrorCode string
+
+const (
+	INVALID_BET_SIZE                       InstructionReportErrorCode = "INVALID_BET_SIZE"
+	INVALID_RUNNER                         InstructionReportErrorCode = "INVALID_RUNNER"
+	BET_TAKEN_OR_LAPSED                    InstructionReportErrorCode = "BET_TAKEN_OR_LAPSED"
+	BET_IN_PROGRESS                        InstructionReportErrorCode = "BET_IN_PROGRESS"
+	RUNNER_REMOVED                         InstructionReportErrorCode = "RUNNER_REMOVED"
+	MARKET_NOT_OPEN_FOR_BSP_BETTING        InstructionReportErrorCode = "MARKET_NOT_OPEN_FOR_BSP_BETTING"
+	INVALID_PRICE_EDIT                     InstructionReportErrorCode = "INVALID_PRICE_EDIT"
+	INVALID_ODDS                           InstructionReportErrorCode = "INVALID_ODDS"
+	INVALID_PERSISTENCE_TYPE               InstructionReportErrorCode = "INVALID_PERSISTENCE_TYPE"
+	INVALID_BACK_LAY_COMBINATION           InstructionReportErrorCode = "INVALID_BACK_LAY_COMBINATION"
+	ERROR_IN_ORDER                         InstructionReportErrorCode = "ERROR_IN_ORDER"
+	INVALID_BID_TYPE                       InstructionReportErrorCode = "INVALID_BID_TYPE"
+	INVALID_BET_ID                         InstructionReportErrorCode = "INVALID_BET_ID"
+	CANCELLED_NOT_PLACED                   InstructionReportErrorCode = "CANCELLED_NOT_PLACED"
+	RELATED_ACTION_FAILED                  InstructionReportErrorCode = "RELATED_ACTION_FAILED"
+	TIME_IN_FORCE_CONFLICT                 InstructionReportErrorCode = "TIME_IN_FORCE_CONFLICT"
+	UNEXPECTED_PERSISTENCE_TYPE            InstructionReportErrorCode = "UNEXPECTED_PERSISTENCE_TYPE"
+	INVALID_ORDER_TYPE                     InstructionReportErrorCode = "INVALID_ORDER_TYPE"
+	UNEXPECTED_MIN_FILL_SIZE               InstructionReportErrorCode = "UNEXPECTED_MIN_FILL_SIZE"
+	INVALID_CUSTOMER_ORDER_REF             InstructionReportErrorCode = "INVALID_CUSTOMER_ORDER_REF"
+	INVALID_MIN_FILL_SIZE                  InstructionReportErrorCode = "INVALID_MIN_FILL_SIZE"
+	BET_LAPSED_PRICE_IMPROVEMENT_TOO_LARGE InstructionReportErrorCode = "BET_LAPSED_PRICE_IMPROVEMENT_TOO_LARGE"
+	INVALID_CUSTOMER_STRATEGY_REF          InstructionReportErrorCode = "INVALID_CUSTOMER_STRATEGY_REF"
+	INVALID_PROFIT_RATIO                   InstructionReportErrorCode = "INVALID_PROFIT_RATIO"
+
+	// Shares its name with the ExecutionReportErrorCode
+	INSTRUCTION_INSUFFICIENT_FUNDS InstructionReportErrorCode = "INSUFFICIENT_FUNDS"
+)
+
+// Implemented by errors returned before a request reaches Betfair, e.g. *validation.ValidationError and *risk.Rejection
+// Nothing in the request was sent, codes are keyed by instruction index and only cover the instructions at fault
+type PreflightError interface {
+	error
+	PreflightCodes() map[int]InstructionReportErrorCode
+}
+
+type OrderBy string
+
+const (
+	BY_BET          OrderBy = "BY_BET"
+	BY_MARKET       OrderBy = "BY_MARKET"
+	BY_MATCH_TIME   OrderBy = "BY_MATCH_TIME"
+	BY_PLACE_TIME   OrderBy = "BY_PLACE_TIME"
+	BY_SETTLED_TIME OrderBy = "BY_SETTLED_TIME"
+	BY_VOID_TIME    OrderBy = "BY_VOID_TIME"
+)
+
+type SortDir string
+
+const (
+	EARLIEST_TO_LATEST SortDir = "EARLIEST_TO_LATEST"
+	LATEST_TO_EARLIEST SortDir = "LATEST_TO_EARLIEST"
+)
+
+type LimitOrder struct {
+	Size            float64         `json:"size,omitempty"`
+	Price           float64         `json:"price"`
+	PersistenceType PersistenceType `json:"persistenceType,omitempty"`
+	TimeInForce     TimeInForce     `json:"timeInForce,omitempty"`
+	MinFillSize     float64         `json:"minFillSize,omitempty"`
+	BetTargetType   BetTargetType   `json:"betTargetType,omitempty"`
+	BetTargetSize   float64         `json:"betTargetSize,omitempty"`
+}
+
+type LimitOnCloseOrder struct {
+	Liability float64 `json:"liability"`
+	Price     float64 `json:"price"`
+}
+
+type MarketOnCloseOrder struct {
+	Liability float64 `json:"liability"`
+}
+
+// Exactly one of LimitOrder, LimitOnCloseOrder or MarketOnCloseOrder is set, matching OrderType
+type PlaceInstruction struct {
+	OrderType          OrderType           `json:"orderType"`
+	SelectionId        int64               `json:"selectionId"`
+	Handicap           float64             `json:"handicap,omitempty"`
+	Side               Side                `json:"side"`
+	LimitOrder         *LimitOrder         `json:"limitOrder,omitempty"`
+	LimitOnCloseOrder  *LimitOnCloseOrder  `json:"limitOnCloseOrder,omitempty"`
+	MarketOnCloseOrder *MarketOnCloseOrder `json:"marketOnCloseOrder,omitempty"`
+	CustomerOrderRef   string              `json:"customerOrderRef,omitempty"`
+}
+
+type MarketVersion struct {
+	Version int64 `json:"version"`
+}
+
+type PlaceOrdersRequest struct {
+	MarketId            string             `json:"marketId"`
+	Instructions        []PlaceInstruction `json:"instructions"`
+	CustomerRef         string             `json:"customerRef,omitempty"` // de-duplicates requests for 60 seconds
+	MarketVersion       *MarketVersion     `json:"marketVersion,omitempty"`
+	CustomerStrategyRef string             `json:"customerStrategyRef,omitempty"`
+	Async               bool               `json:"async,omitempty"`
+}
+
+type PlaceInstructionReport struct {
+	Status              InstructionReportStatus    `json:"status"`
+	ErrorCode           InstructionReportErrorCode `json:"errorCode,omitempty"`
+	OrderStatus         OrderStatus                `json:"orderStatus,omitempty"`
+	Instruction         PlaceInstruction           `json:"instruction"`
+	BetId               string                     `json:"betId,omitempty"`
+	PlacedDate          string                     `json:"placedDate,omitempty"`
+	AveragePriceMatched float64                    `json:"averagePriceMatched,omitempty"`
+	SizeMatched         float64                    `json:"sizeMatched,omitempty"`
+}
+
+type PlaceExecutionReport struct {
+	CustomerRef        string                   `json:"customerRef,omitempty"`
+	Status             ExecutionReportStatus    `json:"status"`
+	ErrorCode          ExecutionReportErrorCode `json:"errorCode,omitempty"`
+	MarketId           string                   `json:"marketId"`
+	InstructionReports []PlaceInstructionReport `json:"instructionReports,omitempty"`
+}
+
+type CancelInstruction struct {
+	BetId         string   `json:"betId"`
+	SizeReduction *float64 `json:"sizeReduction,omitempty"` // nil cancels the whole remaining size
+}
+
+// Leave MarketId empty to cancel every order on every market
+type CancelOrdersRequest struct {
+	MarketId     string              `json:"marketId,omitempty"`
+	Instructions []CancelInstruction `json:"instructions,omitempty"`
+	CustomerRef  string              `json:"customerRef,omitempty"`
+}
+
+type CancelInstructionReport struct {
+	Status        InstructionReportStatus    `json:"status"`
+	ErrorCode     InstructionReportErrorCode `json:"errorCode,omitempty"`
+	Instruction   *CancelInstruction         `json:"instruction,omitempty"`
+	SizeCancelled float64                    `json:"sizeCancelled"`
+	CancelledDate string                     `json:"cancelledDate,omitempty"`
+}
+
+type CancelExecutionReport struct {
+	CustomerRef        string                    `json:"customerRef,omitempty"`
+	Status             ExecutionReportStatus     `json:"status"`
+	ErrorCode          ExecutionReportErrorCode  `json:"errorCode,omitempty"`
+	MarketId           string                    `json:"marketId,omitempty"`
+	InstructionReports []CancelInstructionReport `json:"instructionReports,omitempty"`
+}
+
+// Cancels an unmatched bet and places its remaining size at NewPrice
+type ReplaceInstruction struct {
+	BetId    string  `json:"betId"`
+	NewPrice float64 `json:"newPrice"`
+}
+
+type ReplaceOrdersRequest struct {
+	MarketId      string               `json:"marketId"`
+	Instructions  []ReplaceInstruction `json:"instructions"`
+	CustomerRef   string               `json:"customerRef,omitempty"`
+	MarketVersion *MarketVersion       `json:"marketVersion,omitempty"`
+	Async         bool                 `json:"async,omitempty"`
+}
+
+type ReplaceInstructionReport struct {
+	Status                  InstructionReportStatus    `json:"status"`
+	ErrorCode               InstructionReportErrorCode `json:"errorCode,omitempty"`
+	CancelInstructionReport *CancelInstructionReport   `json:"cancelInstructionReport,omitempty"`
+	PlaceInstructionReport  *PlaceInstructionReport    `json:"placeInstructionReport,omitempty"`
+}
+
+type ReplaceExecutionReport struct {
+	CustomerRef        string                     `json:"customerRef,omitempty"`
+	Status             ExecutionReportStatus      `json:"status"`
+	ErrorCode          ExecutionReportErrorCode   `json:"errorCode,omitempty"`
+	MarketId           string                     `json:"marketId"`
+	InstructionReports []ReplaceInstructionReport `json:"instructionReports,omitempty"`
+}
+
+// Changes the persistence of an unmatched bet
+type UpdateInstruction struct {
+	BetId              string          `json:"betId"`
+	NewPersistenceType PersistenceType `json:"newPersistenceType"`
+}
+
+type UpdateOrdersRequest struct {
+	MarketId     string              `json:"marketId"`
+	Instructions []UpdateInstruction `json:"instructions"`
+	CustomerRef  string              `json:"customerRef,omitempty"`
+}
+
+type UpdateInstructionReport struct {
+	Status      InstructionReportStatus    `json:"status"`
+	ErrorCode   InstructionReportErrorCode `json:"errorCode,omitempty"`
+	Instruction UpdateInstruction          `json:"instruction"`
+}
+
+type UpdateExecutionReport struct {
+	CustomerRef        string                    `json:"customerRef,omitempty"`
+	Status             ExecutionReportStatus     `json:"status"`
+	ErrorCode          ExecutionReportErrorCode  `json:"errorCode,omitempty"`
+	MarketId           string                    `json:"marketId"`
+	InstructionReports []UpdateInstructionReport `json:"instructionReports,omitempty"`
+}
+
+type ListCurrentOrdersRequest struct {
+	BetIds               []string        `json:"betIds,omitempty"`
+	MarketIds            []string        `json:"marketIds,omitempty"`
+	OrderProjection      OrderProjection `json:"orderProjection,omitempty"`
+	CustomerOrderRefs    []string        `json:"customerOrderRefs,omitempty"`
+	CustomerStrategyRefs []string        `json:"customerStrategyRefs,omitempty"`
+	DateRange            *TimeRange      `json:"dateRange,omitempty"`
+	OrderBy              OrderBy         `json:"orderBy,omitempty"`
+	SortDir              SortDir         `json:"sortDir,omitempty"`
+	FromRecord           int             `json:"fromRecord,omitempty"`
+	RecordCount          int             `json:"recordCount,omitempty"` // max 1000
+}
+
+type PriceSize struct {
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+type CurrentOrderSummary struct {
+	BetId               string          `json:"betId"`
+	MarketId            string          `json:"marketId"`
+	SelectionId         int64           `json:"selectionId"`
+	Handicap            float64         `json:"handicap"`
+	PriceSize           PriceSize       `json:"priceSize"`
+	BspLiability        float64         `json:"bspLiability"`
+	Side                Side            `json:"side"`
+	Status              OrderStatus     `json:"status"`
+	PersistenceType     PersistenceType `json:"persistenceType"`
+	OrderType           OrderType       `json:"orderType"`
+	PlacedDate          string          `json:"placedDate"`
+	MatchedDate         string          `json:"matchedDate,omitempty"`
+	AveragePriceMatched float64         `json:"averagePriceMatched,omitempty"`
+	SizeMatched         float64         `json:"sizeMatched,omitempty"`
+	SizeRemaining       float64         `json:"sizeRemaining,omitempty"`
+	SizeLapsed          float64         `json:"sizeLapsed,omitempty"`
+	SizeCancelled       float64         `json:"sizeCancelled,omitempty"`
+	SizeVoided          float64         `json:"sizeVoided,omitempty"`
+	RegulatorCode       string          `json:"regulatorCode,omitempty"`
+	CustomerOrderRef    string          `json:"customerOrderRef,omitempty"`
+	CustomerStrategyRef string          `json:"customerStrategyRef,omitempty"`
+}
+
+type CurrentOrderSummaryReport struct {
+	CurrentOrders []CurrentOrderSummary `json:"currentOrders"`
+	MoreAvailable bool                  `json:"moreAvailable"`
+}
@@ -16,3 +16,12 @@ type ListMarketBookRequest struct {
 	OrderProjection  OrderProjection    `json:"orderProjection,omitempty"`
 	MarketProjection []MarketProjection `json:"marketProjection,omitempty"`
 }
+
+type ListRaceDetailsRequest struct {
+	MeetingIds []string `json:"meetingIds,omitempty"`
+	RaceIds    []string `json:"raceIds,omitempty"`
+}
+
+type GetAccountFundsRequest struct {
+	Wallet string `json:"wallet,omitempty"`
+}
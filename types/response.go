@@ -42,23 +42,61 @@ type ListEventsResponse struct {
 }
 
 type ListMarketCataloguesResponse struct {
-	MarketId        string   `json:"marketId"`
-	MarketName      string   `json:"marketName"`
-	MarketStartTime string   `json:"marketStartTime,omitempty"`
-	TotalMatched    float32  `json:"totalMatched"`
-	Runners         []Runner `json:"runners,omitempty"`
+	MarketId        string             `json:"marketId"`
+	MarketName      string             `json:"marketName"`
+	MarketStartTime string             `json:"marketStartTime,omitempty"`
+	TotalMatched    float32            `json:"totalMatched"`
+	Runners         []Runner           `json:"runners,omitempty"`
+	Event           *Event             `json:"event,omitempty"`       // requires EVENT projection
+	Description     *MarketDescription `json:"description,omitempty"` // requires MARKET_DESCRIPTION projection
+}
+
+type MarketDescription struct {
+	PersistenceEnabled     bool                    `json:"persistenceEnabled"`
+	BspMarket              bool                    `json:"bspMarket"`
+	MarketTime             string                  `json:"marketTime,omitempty"`
+	SuspendTime            string                  `json:"suspendTime,omitempty"`
+	BettingType            string                  `json:"bettingType"`
+	TurnInPlayEnabled      bool                    `json:"turnInPlayEnabled"`
+	MarketType             string                  `json:"marketType"`
+	Regulator              string                  `json:"regulator,omitempty"`
+	MarketBaseRate         float32                 `json:"marketBaseRate"`
+	DiscountAllowed        bool                    `json:"discountAllowed"`
+	Rules                  string                  `json:"rules,omitempty"`
+	RaceType               string                  `json:"raceType,omitempty"`
+	PriceLadderDescription *PriceLadderDescription `json:"priceLadderDescription,omitempty"`
+	LineRangeInfo          *MarketLineRangeInfo    `json:"lineRangeInfo,omitempty"` // LINE_RANGE markets only
+}
+
+type PriceLadderDescription struct {
+	Type PriceLadderType `json:"type"`
+}
+
+type MarketLineRangeInfo struct {
+	MaxUnitValue float64 `json:"maxUnitValue"`
+	MinUnitValue float64 `json:"minUnitValue"`
+	Interval     float64 `json:"interval"`
+	MarketUnit   string  `json:"marketUnit"`
 }
 
 type ListMarketSelectionsResponse struct {
 }
 
 type Runner struct {
-	SelectionId     int     `json:"selectionId"`
-	RunnerName      string  `json:"runnerName"`
-	Handicap        float32 `json:"handicap"`
-	LastPriceTraded float32 `json:"lastPriceTraded"`
-	TotalMatched    float32 `json:"totalMatched"`
-	Ex              Ex      `json:"ex"`
+	SelectionId     int             `json:"selectionId"`
+	RunnerName      string          `json:"runnerName"`
+	Handicap        float32         `json:"handicap"`
+	Status          RunnerStatus    `json:"status,omitempty"`
+	LastPriceTraded float32         `json:"lastPriceTraded"`
+	TotalMatched    float32         `json:"totalMatched"`
+	Sp              *StartingPrices `json:"sp,omitempty"`
+	Ex              Ex              `json:"ex"`
+}
+
+type StartingPrices struct {
+	NearPrice float32 `json:"nearPrice,omitempty"`
+	FarPrice  float32 `json:"farPrice,omitempty"`
+	ActualSP  float32 `json:"actualSP,omitempty"`
 }
 
 type Ex struct {
@@ -73,7 +111,31 @@ type RunnerPrice struct {
 }
 
 type ListMarketBookResponse struct {
-	Runners []Runner `json:"runners"`
+	MarketId     string       `json:"marketId"`
+	Status       MarketStatus `json:"status"`
+	BetDelay     int          `json:"betDelay"`
+	InPlay       bool         `json:"inplay"`
+	TotalMatched float32      `json:"totalMatched"`
+	Version      int64        `json:"version"`
+	Runners      []Runner     `json:"runners"`
+}
+
+type RaceDetails struct {
+	MeetingId    string     `json:"meetingId"`
+	RaceId       string     `json:"raceId"`
+	RaceStatus   RaceStatus `json:"raceStatus"`
+	LastUpdated  string     `json:"lastUpdated"`
+	ResponseCode string     `json:"responseCode"`
+}
+
+type AccountFundsResponse struct {
+	AvailableToBetBalance float64 `json:"availableToBetBalance"`
+	Exposure              float64 `json:"exposure"`
+	RetainedCommission    float64 `json:"retainedCommission"`
+	ExposureLimit         float64 `json:"exposureLimit"`
+	DiscountRate          float64 `json:"discountRate"`
+	PointsBalance         int     `json:"pointsBalance"`
+	Wallet                string  `json:"wallet"`
 }
 
 // AUTH RESPONSE TYPES
@@ -0,0 +1,175 @@
+// types/scores.go
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	SOCCER_EVENT_TYPE_ID = "1"
+	TENNIS_EVENT_TYPE_ID = "2"
+)
+
+type ScoreEventStatus string
+
+const (
+	SCORE_UNKNOWN   ScoreEventStatus = "UNKNOWN"
+	SCORE_PRE_MATCH ScoreEventStatus = "PRE_MATCH"
+	SCORE_IN_PLAY   ScoreEventStatus = "IN_PLAY"
+	SCORE_FINISHED  ScoreEventStatus = "FINISHED"
+)
+
+// REQUESTS
+type ListAvailableEventsRequest struct {
+	EventIds     []string           `json:"eventIds,omitempty"`
+	EventTypeIds []string           `json:"eventTypeIds,omitempty"`
+	EventStatus  []ScoreEventStatus `json:"eventStatus,omitempty"`
+}
+
+// Pass the last processed update sequence to only receive newer updates
+type UpdateKey struct {
+	EventId                     string `json:"eventId"`
+	LastUpdateSequenceProcessed int64  `json:"lastUpdateSequenceProcessed,omitempty"`
+}
+
+type ListScoresRequest struct {
+	UpdateKeys []UpdateKey `json:"updateKeys"`
+}
+
+type ListIncidentsRequest struct {
+	UpdateKeys []UpdateKey `json:"updateKeys"`
+}
+
+// RESPONSES
+type AvailableEvent struct {
+	EventId     string           `json:"eventId"`
+	EventTypeId string           `json:"eventTypeId"`
+	EventStatus ScoreEventStatus `json:"eventStatus"`
+}
+
+type UpdateContext struct {
+	EventTime      string `json:"eventTime"`
+	UpdateSequence int64  `json:"updateSequence"`
+	UpdateType     string `json:"updateType"`
+}
+
+// Score for a single event
+// Values differ per sport, decode them with Soccer() or Tennis()
+type Score struct {
+	EventId       string           `json:"eventId"`
+	EventTypeId   string           `json:"eventTypeId"`
+	EventStatus   ScoreEventStatus `json:"eventStatus"`
+	ResponseCode  string           `json:"responseCode"`
+	UpdateContext UpdateContext    `json:"updateContext"`
+	Values        json.RawMessage  `json:"values,omitempty"`
+}
+
+type SoccerTeamScore struct {
+	Name                string `json:"name"`
+	Score               int    `json:"score"`
+	HalfTimeScore       int    `json:"halfTimeScore"`
+	FullTimeScore       int    `json:"fullTimeScore"`
+	PenaltiesScore      int    `json:"penaltiesScore"`
+	NumberOfYellowCards int    `json:"numberOfYellowCards"`
+	NumberOfRedCards    int    `json:"numberOfRedCards"`
+	NumberOfCorners     int    `json:"numberOfCorners"`
+	BookingPoints       int    `json:"bookingPoints"`
+}
+
+type SoccerScore struct {
+	Home               SoccerTeamScore `json:"home"`
+	Away               SoccerTeamScore `json:"away"`
+	TimeElapsed        int             `json:"timeElapsed"`
+	ElapsedRegularTime int             `json:"elapsedRegularTime"`
+	ElapsedAddedTime   int             `json:"elapsedAddedTime"`
+	MatchStatus        string          `json:"matchStatus"`
+}
+
+type TennisPlayerScore struct {
+	Name      string `json:"name"`
+	Score     string `json:"score"` // points in the current game, e.g. "15", "40", "A"
+	Sets      int    `json:"sets"`
+	Games     int    `json:"games"`
+	IsServing bool   `json:"isServing"`
+}
+
+type TennisScore struct {
+	Home          TennisPlayerScore `json:"home"`
+	Away          TennisPlayerScore `json:"away"`
+	CurrentSet    int               `json:"currentSet"`
+	CurrentGame   int               `json:"currentGame"`
+	IsTieBreak    bool              `json:"isTieBreak"`
+	FullTimeScore string            `json:"fullTimeScore"`
+}
+
+func (s Score) Soccer() (*SoccerScore, error) {
+	if s.EventTypeId != SOCCER_EVENT_TYPE_ID {
+		return nil, fmt.Errorf("event %s is not a soccer event (event type %s)", s.EventId, s.EventTypeId)
+	}
+
+	var score SoccerScore
+	if err := json.Unmarshal(s.Values, &score); err != nil {
+		return nil, fmt.Errorf("unable to decode soccer score: %w", err)
+	}
+
+	return &score, nil
+}
+
+func (s Score) Tennis() (*TennisScore, error) {
+	if s.EventTypeId != TENNIS_EVENT_TYPE_ID {
+		return nil, fmt.Errorf("event %s is not a tennis event (event type %s)", s.EventId, s.EventTypeId)
+	}
+
+	var score TennisScore
+	if err := json.Unmarshal(s.Values, &score); err != nil {
+		return nil, fmt.Errorf("unable to decode tennis score: %w", err)
+	}
+
+	return &score, nil
+}
+
+type IncidentType string
+
+const (
+	INCIDENT_GOAL           IncidentType = "GOAL"
+	INCIDENT_OWN_GOAL       IncidentType = "OWN_GOAL"
+	INCIDENT_PENALTY_GOAL   IncidentType = "PENALTY_GOAL"
+	INCIDENT_YELLOW_CARD    IncidentType = "YELLOW_CARD"
+	INCIDENT_RED_CARD       IncidentType = "RED_CARD"
+	INCIDENT_CORNER         IncidentType = "CORNER"
+	INCIDENT_BREAK_OF_SERVE IncidentType = "BREAK_OF_SERVE"
+	INCIDENT_SET_WON        IncidentType = "SET_WON"
+	INCIDENT_PERIOD_START   IncidentType = "PERIOD_START"
+	INCIDENT_PERIOD_END     IncidentType = "PERIOD_END"
+)
+
+type Incident struct {
+	Type        IncidentType `json:"type"`
+	Participant string       `json:"participant"` // "home" or "away"
+	EventTime   string       `json:"eventTime"`
+	ElapsedTime int          `json:"elapsedTime"`
+	Value       string       `json:"value,omitempty"`
+}
+
+type EventIncidents struct {
+	EventId       string        `json:"eventId"`
+	EventTypeId   string        `json:"eventTypeId"`
+	UpdateContext UpdateContext `json:"updateContext"`
+	Incidents     []Incident    `json:"incidents"`
+}
+
+// Builds the update keys for the next poll so only newer updates are returned
+func NextUpdateKeys(scores []Score) []UpdateKey {
+	keys := make([]UpdateKey, 0, len(scores))
+
+	for _, s := range scores {
+		keys = append(keys, UpdateKey{
+			EventId:                     s.EventId,
+			LastUpdateSequenceProcessed: s.UpdateContext.UpdateSequence,
+		})
+	}
+
+	return keys
+}
@@ -0,0 +1,92 @@
+// types/scores_test.go
+
+package types_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const scoresJSON = `[
+  {"eventId": "33000001", "eventTypeId": "1", "eventStatus": "IN_PLAY", "responseCode": "OK",
+   "updateContext": {"eventTime": "2024-03-02T15:47:12.000Z", "updateSequence": 412, "updateType": "ACTUAL"},
+   "values": {
+     "home": {"name": "Arsenal", "score": 2, "halfTimeScore": 1, "numberOfYellowCards": 1, "numberOfCorners": 6},
+     "away": {"name": "Chelsea", "score": 1, "halfTimeScore": 1, "numberOfRedCards": 1, "bookingPoints": 35},
+     "timeElapsed": 62, "elapsedRegularTime": 62, "matchStatus": "SecondHalfKickOff"}},
+  {"eventId": "33000002", "eventTypeId": "2", "eventStatus": "IN_PLAY", "responseCode": "OK",
+   "updateContext": {"eventTime": "2024-03-02T15:47:10.000Z", "updateSequence": 97, "updateType": "ACTUAL"},
+   "values": {
+     "home": {"name": "Alcaraz", "score": "40", "sets": 1, "games": 4, "isServing": true},
+     "away": {"name": "Sinner", "score": "A", "sets": 0, "games": 3},
+     "currentSet": 2, "currentGame": 8, "isTieBreak": false}}
+]`
+
+func TestScore_UnmarshalSoccerAndTennis(t *testing.T) {
+	var scores []types.Score
+	require.NoError(t, json.Unmarshal([]byte(scoresJSON), &scores))
+	require.Len(t, scores, 2)
+
+	assert.Equal(t, types.SCORE_IN_PLAY, scores[0].EventStatus)
+	assert.Equal(t, int64(412), scores[0].UpdateContext.UpdateSequence)
+
+	soccer, err := scores[0].Soccer()
+	require.NoError(t, err)
+	assert.Equal(t, "Arsenal", soccer.Home.Name)
+	assert.Equal(t, 2, soccer.Home.Score)
+	assert.Equal(t, 6, soccer.Home.NumberOfCorners)
+	assert.Equal(t, 1, soccer.Away.NumberOfRedCards)
+	assert.Equal(t, 62, soccer.TimeElapsed)
+
+	tennis, err := scores[1].Tennis()
+	require.NoError(t, err)
+	assert.Equal(t, "40", tennis.Home.Score)
+	assert.True(t, tennis.Home.IsServing)
+	assert.Equal(t, "A", tennis.Away.Score)
+	assert.Equal(t, 2, tennis.CurrentSet)
+
+	_, err = scores[0].Tennis()
+	assert.ErrorContains(t, err, "not a tennis event")
+	_, err = scores[1].Soccer()
+	assert.ErrorContains(t, err, "not a soccer event")
+
+	assert.Equal(t, []types.UpdateKey{
+		{EventId: "33000001", LastUpdateSequenceProcessed: 412},
+		{EventId: "33000002", LastUpdateSequenceProcessed: 97},
+	}, types.NextUpdateKeys(scores))
+}
+
+func TestEventIncidents_Unmarshal(t *testing.T) {
+	var incidents []types.EventIncidents
+	require.NoError(t, json.Unmarshal([]byte(`[
+	  {"eventId": "33000001", "eventTypeId": "1",
+	   "updateContext": {"eventTime": "2024-03-02T15:47:12.000Z", "updateSequence": 412, "updateType": "ACTUAL"},
+	   "incidents": [
+	     {"type": "GOAL", "participant": "home", "eventTime": "2024-03-02T15:12:40.000Z", "elapsedTime": 27},
+	     {"type": "RED_CARD", "participant": "away", "eventTime": "2024-03-02T15:40:02.000Z", "elapsedTime": 55}
+	   ]},
+	  {"eventId": "33000002", "eventTypeId": "2",
+	   "updateContext": {"eventTime": "2024-03-02T15:47:10.000Z", "updateSequence": 97, "updateType": "ACTUAL"},
+	   "incidents": [{"type": "BREAK_OF_SERVE", "participant": "home", "eventTime": "2024-03-02T15:45:00.000Z", "value": "2-4"}]}
+	]`), &incidents))
+
+	require.Len(t, incidents, 2)
+	require.Len(t, incidents[0].Incidents, 2)
+	assert.Equal(t, types.INCIDENT_GOAL, incidents[0].Incidents[0].Type)
+	assert.Equal(t, 27, incidents[0].Incidents[0].ElapsedTime)
+	assert.Equal(t, types.INCIDENT_RED_CARD, incidents[0].Incidents[1].Type)
+	assert.Equal(t, "away", incidents[0].Incidents[1].Participant)
+	assert.Equal(t, types.INCIDENT_BREAK_OF_SERVE, incidents[1].Incidents[0].Type)
+	assert.Equal(t, "2-4", incidents[1].Incidents[0].Value)
+}
+
+func TestAvailableEvent_Unmarshal(t *testing.T) {
+	var events []types.AvailableEvent
+	require.NoError(t, json.Unmarshal([]byte(`[{"eventId": "33000001", "eventTypeId": "1", "eventStatus": "PRE_MATCH"}]`), &events))
+	assert.Equal(t, []types.AvailableEvent{{EventId: "33000001", EventTypeId: "1", EventStatus: types.SCORE_PRE_MATCH}}, events)
+}
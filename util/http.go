@@ -18,15 +18,29 @@ const (
 	BASE_URL   = "https://api.betfair.com/exchange/betting/rest/v1.0/"
 )
 
-// Send a POST request to a given URL
+// Send a POST request to a betting endpoint
 // Add parameter headers
 // Attempt to unmarshal the response into T
 func GenericPost[T any](client *http.Client, endpoint, appKey, sessionToken string, body any) (T, error) {
+	return GenericPostUrl[T](client, BASE_URL+endpoint, appKey, sessionToken, body)
+}
+
+// Same as GenericPost but for services hosted outside the betting API (e.g. scores)
+func GenericPostUrl[T any](client *http.Client, fullUrl, appKey, sessionToken string, body any) (T, error) {
+	return postUrl[T](client, fullUrl, appKey, sessionToken, body, maxRetries)
+}
+
+// Same as GenericPostUrl but sent exactly once, for operations that are unsafe to repeat (e.g. placeOrders)
+// A transport error or timeout does not mean the request was not processed
+func GenericPostUrlOnce[T any](client *http.Client, fullUrl, appKey, sessionToken string, body any) (T, error) {
+	return postUrl[T](client, fullUrl, appKey, sessionToken, body, 1)
+}
+
+func postUrl[T any](client *http.Client, fullUrl, appKey, sessionToken string, body any, attempts int) (T, error) {
 	var result T
 	var lastErr error
 
-	fullUrl := BASE_URL + endpoint
-	for attempt := 0; attempt < maxRetries; attempt++ {
+	for attempt := 0; attempt < attempts; attempt++ {
 		if attempt > 0 {
 			// exponential backoff + jitter
 			delay := baseDelay * time.Duration(1<<attempt)
@@ -94,7 +108,64 @@ func GenericPost[T any](client *http.Client, endpoint, appKey, sessionToken stri
 	}
 
 	if lastErr == nil {
-		lastErr = fmt.Errorf("request failed after %d attempts (unknown reason)", maxRetries)
+		lastErr = fmt.Errorf("request failed after %d attempts (unknown reason)", attempts)
+	}
+
+	if attempts == 1 {
+		return result, lastErr
+	}
+	return result, fmt.Errorf("%w (after %d attempts)", lastErr, attempts)
+}
+
+// Send a GET request to a given URL with session headers
+// Retries transport failures and retryable status codes
+func GenericGetUrl[T any](client *http.Client, fullUrl, appKey, sessionToken string) (T, error) {
+	var result T
+	var lastErr error
+
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := baseDelay * time.Duration(1<<attempt)
+			jitter := time.Duration(time.Now().UnixNano()%100) * time.Millisecond
+			time.Sleep(delay + jitter)
+		}
+
+		req, err := http.NewRequest("GET", fullUrl, nil)
+		if err != nil {
+			return result, fmt.Errorf("unable to build request: %w", err)
+		}
+
+		req.Header.Set("X-Application", appKey)
+		req.Header.Set("X-Authentication", sessionToken)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("unable to make request: %w", err)
+			continue
+		}
+
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("unable to read response: %w", err)
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			lastErr = fmt.Errorf("http %d: %s", resp.StatusCode, string(bodyBytes))
+
+			if !shouldRetry(resp.StatusCode, attempt) {
+				return result, lastErr
+			}
+			continue
+		}
+
+		if err = json.Unmarshal(bodyBytes, &result); err != nil {
+			return result, fmt.Errorf("json unmarshal failed: %w", err)
+		}
+
+		return result, nil
 	}
 
 	return result, fmt.Errorf("%w (after %d attempts)", lastErr, maxRetries)
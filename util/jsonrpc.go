@@ -0,0 +1,170 @@
+// util/jsonrpc.go
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+const (
+	BETTING_RPC_URL = "https://api.betfair.com/exchange/betting/json-rpc/v1"
+	ACCOUNT_RPC_URL = "https://api.betfair.com/exchange/account/json-rpc/v1"
+	SCORES_RPC_URL  = "https://api.betfair.com/exchange/scores/json-rpc/v1"
+)
+
+// Send a single JSON-RPC call
+// Attempt to unmarshal the result into T
+func JSONRPCPost[T any](client *http.Client, rpcUrl, appKey, sessionToken, method string, params any) (T, error) {
+	return rpcPost[T](client, rpcUrl, appKey, sessionToken, method, params, maxRetries)
+}
+
+// Same as JSONRPCPost but sent exactly once, for operations that are unsafe to repeat (e.g. placeOrders)
+func JSONRPCPostOnce[T any](client *http.Client, rpcUrl, appKey, sessionToken, method string, params any) (T, error) {
+	return rpcPost[T](client, rpcUrl, appKey, sessionToken, method, params, 1)
+}
+
+func rpcPost[T any](client *http.Client, rpcUrl, appKey, sessionToken, method string, params any, attempts int) (T, error) {
+	var result T
+
+	responses, err := rpcBatch(client, rpcUrl, appKey, sessionToken, []types.RPCRequest{
+		{JsonRPC: "2.0", Method: method, Params: params, Id: 1},
+	}, attempts)
+	if err != nil {
+		return result, err
+	}
+
+	resp := responses[0]
+	if resp.Error != nil {
+		return result, resp.Error
+	}
+
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return result, fmt.Errorf("json unmarshal failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// Send several JSON-RPC calls in one HTTP round trip
+// Responses are returned in the same order as reqs, matched by id
+func JSONRPCBatch(client *http.Client, rpcUrl, appKey, sessionToken string, reqs []types.RPCRequest) ([]types.RPCResponse, error) {
+	return rpcBatch(client, rpcUrl, appKey, sessionToken, reqs, maxRetries)
+}
+
+// Same as JSONRPCBatch but sent exactly once, for batches containing operations that are unsafe to repeat
+func JSONRPCBatchOnce(client *http.Client, rpcUrl, appKey, sessionToken string, reqs []types.RPCRequest) ([]types.RPCResponse, error) {
+	return rpcBatch(client, rpcUrl, appKey, sessionToken, reqs, 1)
+}
+
+func rpcBatch(client *http.Client, rpcUrl, appKey, sessionToken string, reqs []types.RPCRequest, attempts int) ([]types.RPCResponse, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("no calls to send")
+	}
+
+	index := make(map[int]int, len(reqs))
+	for i, r := range reqs {
+		if _, dup := index[r.Id]; dup {
+			return nil, fmt.Errorf("duplicate json-rpc id %d", r.Id)
+		}
+		index[r.Id] = i
+	}
+
+	// A single call is sent as an object, several as an array
+	var payload any = reqs
+	if len(reqs) == 1 {
+		payload = reqs[0]
+	}
+
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal body: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			delay := baseDelay * time.Duration(1<<attempt)
+			jitter := time.Duration(time.Now().UnixNano()%100) * time.Millisecond
+			time.Sleep(delay + jitter)
+		}
+
+		req, err := http.NewRequest("POST", rpcUrl, bytes.NewReader(reqBody))
+		if err != nil {
+			return nil, fmt.Errorf("unable to build request: %w", err)
+		}
+
+		req.Header.Set("X-Application", appKey)
+		req.Header.Set("X-Authentication", sessionToken)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("unable to make request: %w", err)
+			continue
+		}
+
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("unable to read response: %w", err)
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			lastErr = fmt.Errorf("http %d: %s", resp.StatusCode, string(bodyBytes))
+
+			if !shouldRetry(resp.StatusCode, attempt) {
+				return nil, lastErr
+			}
+			continue
+		}
+
+		if len(reqs) == 1 {
+			var single types.RPCResponse
+			if err := json.Unmarshal(bodyBytes, &single); err != nil {
+				return nil, fmt.Errorf("json unmarshal failed: %w", err)
+			}
+			return []types.RPCResponse{single}, nil
+		}
+
+		var decoded []types.RPCResponse
+		if err := json.Unmarshal(bodyBytes, &decoded); err != nil {
+			return nil, fmt.Errorf("json unmarshal failed: %w", err)
+		}
+
+		ordered := make([]types.RPCResponse, len(reqs))
+		found := make([]bool, len(reqs))
+		for _, r := range decoded {
+			i, ok := index[r.Id]
+			if !ok {
+				return nil, fmt.Errorf("unexpected json-rpc response id %d", r.Id)
+			}
+			ordered[i] = r
+			found[i] = true
+		}
+
+		for i, ok := range found {
+			if !ok {
+				ordered[i] = types.RPCResponse{
+					Id:    reqs[i].Id,
+					Error: &types.RPCError{Message: "no response for call " + reqs[i].Method},
+				}
+			}
+		}
+
+		return ordered, nil
+	}
+
+	if attempts == 1 {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("%w (after %d attempts)", lastErr, attempts)
+}
@@ -0,0 +1,53 @@
+// util/jsonrpc_test.go
+
+package util_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+	"github.com/Bazcampbell/betfair-api-go-sdk/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONRPCBatch_DemultiplexesById(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "APPKEY", r.Header.Get("X-Application"))
+		assert.Equal(t, "TOKEN", r.Header.Get("X-Authentication"))
+
+		var reqs []types.RPCRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqs))
+		require.Len(t, reqs, 3)
+
+		// Answer out of order, with the middle call failing
+		w.Write([]byte(`[
+			{"jsonrpc":"2.0","result":[{"marketId":"1.3"}],"id":3},
+			{"jsonrpc":"2.0","error":{"code":-32099,"message":"ANGX-0003","data":{"exceptionname":"APINGException","APINGException":{"errorCode":"INVALID_SESSION_INFORMATION"}}},"id":2},
+			{"jsonrpc":"2.0","result":[{"marketId":"1.1"}],"id":1}
+		]`))
+	}))
+	defer srv.Close()
+
+	reqs := []types.RPCRequest{
+		{JsonRPC: "2.0", Method: "SportsAPING/v1.0/listMarketBook", Params: map[string]any{}, Id: 1},
+		{JsonRPC: "2.0", Method: "SportsAPING/v1.0/listMarketBook", Params: map[string]any{}, Id: 2},
+		{JsonRPC: "2.0", Method: "SportsAPING/v1.0/listCurrentOrders", Params: map[string]any{}, Id: 3},
+	}
+
+	responses, err := util.JSONRPCBatch(srv.Client(), srv.URL, "APPKEY", "TOKEN", reqs)
+	require.NoError(t, err)
+	require.Len(t, responses, 3)
+
+	assert.Equal(t, 1, responses[0].Id)
+	assert.JSONEq(t, `[{"marketId":"1.1"}]`, string(responses[0].Result))
+
+	require.NotNil(t, responses[1].Error)
+	assert.Equal(t, "INVALID_SESSION_INFORMATION", responses[1].Error.ErrorCode())
+
+	assert.JSONEq(t, `[{"marketId":"1.3"}]`, string(responses[2].Result))
+}
@@ -0,0 +1,388 @@
+// validation/validation.go
+
+package validation
+
+// Pre-flight checks for order instructions.
+// Catches what Betfair would reject so bad orders never cost a transaction.
+// Error codes are Betfair's own, so callers can handle local and remote rejections the same way.
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/ladder"
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+)
+
+// Instruction limits per request
+const (
+	MAX_PLACE_INSTRUCTIONS   = 200
+	MAX_CANCEL_INSTRUCTIONS  = 60
+	MAX_REPLACE_INSTRUCTIONS = 60
+	MAX_UPDATE_INSTRUCTIONS  = 60
+)
+
+// Currency parameters, check them against Betfair's current values for your account
+type Minimums struct {
+	BetSize      float64 // minimum stake
+	BetPayout    float64 // stakes below BetSize are allowed when they can win at least this much, 0 disables
+	BspLiability float64 // minimum liability of BSP lay bets, BSP backs need BetSize
+}
+
+var currencyMinimums = map[string]Minimums{
+	"GBP": {BetSize: 1, BetPayout: 10, BspLiability: 10},
+	"EUR": {BetSize: 1, BetPayout: 10, BspLiability: 10},
+}
+
+// Returns the known minimums for an ISO currency code
+func CurrencyMinimums(currency string) (Minimums, bool) {
+	m, ok := currencyMinimums[strings.ToUpper(currency)]
+	return m, ok
+}
+
+// What the validator needs to know about a market
+type MarketInfo struct {
+	Ladder             *ladder.Ladder // nil means CLASSIC
+	BspMarket          bool
+	PersistenceEnabled bool
+}
+
+// Builds market info from a catalogue entry requested with the MARKET_DESCRIPTION projection
+func InfoFromCatalogue(cat types.ListMarketCataloguesResponse) (MarketInfo, error) {
+	l, err := ladder.FromDescription(cat.Description)
+	if err != nil {
+		return MarketInfo{}, fmt.Errorf("unable to build ladder for %s: %w", cat.MarketId, err)
+	}
+
+	info := MarketInfo{Ladder: l}
+	if cat.Description != nil {
+		info.BspMarket = cat.Description.BspMarket
+		info.PersistenceEnabled = cat.Description.PersistenceEnabled
+	}
+
+	return info, nil
+}
+
+// One rejected instruction
+type InstructionError struct {
+	Index   int // position in the request's instructions
+	Code    types.InstructionReportErrorCode
+	Message string
+}
+
+func (e InstructionError) Error() string {
+	return fmt.Sprintf("instruction %d: %s: %s", e.Index, e.Code, e.Message)
+}
+
+var _ types.PreflightError = (*ValidationError)(nil)
+
+// Returned when a request fails validation
+// Code is set when the request as a whole is invalid, Instructions lists each rejected instruction
+type ValidationError struct {
+	MarketId     string
+	Code         types.ExecutionReportErrorCode
+	Message      string
+	Instructions []InstructionError
+}
+
+func (e *ValidationError) Error() string {
+	var parts []string
+	if e.Code != "" {
+		parts = append(parts, fmt.Sprintf("%s: %s", e.Code, e.Message))
+	}
+	for _, ie := range e.Instructions {
+		parts = append(parts, ie.Error())
+	}
+
+	return fmt.Sprintf("order validation failed for market %s: %s", e.MarketId, strings.Join(parts, "; "))
+}
+
+// Satisfies types.PreflightError
+func (e *ValidationError) PreflightCodes() map[int]types.InstructionReportErrorCode {
+	return e.Codes()
+}
+
+// Error codes of the rejected instructions, keyed by instruction index
+func (e *ValidationError) Codes() map[int]types.InstructionReportErrorCode {
+	codes := make(map[int]types.InstructionReportErrorCode, len(e.Instructions))
+	for _, ie := range e.Instructions {
+		codes[ie.Index] = ie.Code
+	}
+	return codes
+}
+
+type Validator struct {
+	minimums Minimums
+
+	mu      sync.RWMutex
+	markets map[string]MarketInfo
+}
+
+func New(minimums Minimums) *Validator {
+	return &Validator{minimums: minimums, markets: make(map[string]MarketInfo)}
+}
+
+// Validator using the minimums of a known currency
+func ForCurrency(currency string) (*Validator, error) {
+	m, ok := CurrencyMinimums(currency)
+	if !ok {
+		return nil, fmt.Errorf("no minimums known for currency %s", currency)
+	}
+	return New(m), nil
+}
+
+// Registers what is known about a market
+// Markets never registered are checked against the CLASSIC ladder, BSP and persistence checks are skipped
+func (v *Validator) SetMarket(marketId string, info MarketInfo) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.markets[marketId] = info
+}
+
+func (v *Validator) RemoveMarket(marketId string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.markets, marketId)
+}
+
+func (v *Validator) market(marketId string) (MarketInfo, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	info, ok := v.markets[marketId]
+	if info.Ladder == nil {
+		info.Ladder = ladder.Classic()
+	}
+	return info, ok
+}
+
+func (v *Validator) ValidatePlace(req types.PlaceOrdersRequest) error {
+	verr := &ValidationError{MarketId: req.MarketId}
+
+	if !checkCount(verr, len(req.Instructions), MAX_PLACE_INSTRUCTIONS) {
+		return verr
+	}
+
+	info, known := v.market(req.MarketId)
+
+	for i, ins := range req.Instructions {
+		if code, msg := v.checkPlace(ins, info, known); code != "" {
+			verr.Instructions = append(verr.Instructions, InstructionError{Index: i, Code: code, Message: msg})
+		}
+	}
+
+	if len(verr.Instructions) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func (v *Validator) ValidateReplace(req types.ReplaceOrdersRequest) error {
+	verr := &ValidationError{MarketId: req.MarketId}
+
+	if !checkCount(verr, len(req.Instructions), MAX_REPLACE_INSTRUCTIONS) {
+		return verr
+	}
+
+	info, _ := v.market(req.MarketId)
+
+	for i, ins := range req.Instructions {
+		if ins.BetId == "" {
+			verr.Instructions = append(verr.Instructions, InstructionError{Index: i, Code: types.INVALID_BET_ID, Message: "bet id missing"})
+			continue
+		}
+		if err := info.Ladder.Validate(ins.NewPrice); err != nil {
+			verr.Instructions = append(verr.Instructions, InstructionError{Index: i, Code: types.INVALID_ODDS, Message: err.Error()})
+		}
+	}
+
+	if len(verr.Instructions) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func (v *Validator) ValidateCancel(req types.CancelOrdersRequest) error {
+	verr := &ValidationError{MarketId: req.MarketId}
+
+	if !checkCount(verr, len(req.Instructions), MAX_CANCEL_INSTRUCTIONS) {
+		return verr
+	}
+
+	if req.MarketId == "" && len(req.Instructions) > 0 {
+		verr.Code = types.INVALID_MARKET_ID
+		verr.Message = "instructions need a market id"
+		return verr
+	}
+
+	for i, ins := range req.Instructions {
+		if ins.SizeReduction != nil && (*ins.SizeReduction <= 0 || !twoDecimals(*ins.SizeReduction)) {
+			verr.Instructions = append(verr.Instructions, InstructionError{Index: i, Code: types.INVALID_BET_SIZE,
+				Message: fmt.Sprintf("size reduction %g must be positive with at most 2 decimals", *ins.SizeReduction)})
+		}
+	}
+
+	if len(verr.Instructions) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func (v *Validator) ValidateUpdate(req types.UpdateOrdersRequest) error {
+	verr := &ValidationError{MarketId: req.MarketId}
+
+	if !checkCount(verr, len(req.Instructions), MAX_UPDATE_INSTRUCTIONS) {
+		return verr
+	}
+
+	info, known := v.market(req.MarketId)
+
+	for i, ins := range req.Instructions {
+		if code, msg := checkPersistence(ins.NewPersistenceType, info, known); code != "" {
+			verr.Instructions = append(verr.Instructions, InstructionError{Index: i, Code: code, Message: msg})
+		}
+	}
+
+	if len(verr.Instructions) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func checkCount(verr *ValidationError, n, max int) bool {
+	if n > max {
+		verr.Code = types.TOO_MANY_INSTRUCTIONS
+		verr.Message = fmt.Sprintf("%d instructions, at most %d allowed per request", n, max)
+		return false
+	}
+	return true
+}
+
+// Returns an empty code when the instruction is valid
+func (v *Validator) checkPlace(ins types.PlaceInstruction, info MarketInfo, known bool) (types.InstructionReportErrorCode, string) {
+	if ins.Side != types.BACK && ins.Side != types.LAY {
+		return types.ERROR_IN_ORDER, fmt.Sprintf("invalid side %q", ins.Side)
+	}
+
+	switch ins.OrderType {
+	case types.LIMIT:
+		order := ins.LimitOrder
+		if order == nil || ins.LimitOnCloseOrder != nil || ins.MarketOnCloseOrder != nil {
+			return types.ERROR_IN_ORDER, "LIMIT orders need a limitOrder and nothing else"
+		}
+
+		if err := info.Ladder.Validate(order.Price); err != nil {
+			return types.INVALID_ODDS, err.Error()
+		}
+
+		if code, msg := checkPersistence(order.PersistenceType, info, known); code != "" {
+			return code, msg
+		}
+
+		if order.TimeInForce == types.FILL_OR_KILL && order.PersistenceType != "" {
+			return types.TIME_IN_FORCE_CONFLICT, "FILL_OR_KILL orders cannot set a persistence type"
+		}
+
+		if order.BetTargetType != "" {
+			if order.Size != 0 {
+				return types.ERROR_IN_ORDER, "set either size or a bet target, not both"
+			}
+			if order.BetTargetSize <= 0 || !twoDecimals(order.BetTargetSize) {
+				return types.INVALID_BET_SIZE, fmt.Sprintf("bet target size %g must be positive with at most 2 decimals", order.BetTargetSize)
+			}
+			return "", ""
+		}
+
+		return v.checkStake(order.Size, order.Price)
+
+	case types.LIMIT_ON_CLOSE:
+		order := ins.LimitOnCloseOrder
+		if order == nil || ins.LimitOrder != nil || ins.MarketOnCloseOrder != nil {
+			return types.ERROR_IN_ORDER, "LIMIT_ON_CLOSE orders need a limitOnCloseOrder and nothing else"
+		}
+		if known && !info.BspMarket {
+			return types.MARKET_NOT_OPEN_FOR_BSP_BETTING, "not a BSP market"
+		}
+		if err := info.Ladder.Validate(order.Price); err != nil {
+			return types.INVALID_ODDS, err.Error()
+		}
+		return v.checkBspLiability(ins.Side, order.Liability)
+
+	case types.MARKET_ON_CLOSE:
+		order := ins.MarketOnCloseOrder
+		if order == nil || ins.LimitOrder != nil || ins.LimitOnCloseOrder != nil {
+			return types.ERROR_IN_ORDER, "MARKET_ON_CLOSE orders need a marketOnCloseOrder and nothing else"
+		}
+		if known && !info.BspMarket {
+			return types.MARKET_NOT_OPEN_FOR_BSP_BETTING, "not a BSP market"
+		}
+		return v.checkBspLiability(ins.Side, order.Liability)
+
+	default:
+		return types.INVALID_ORDER_TYPE, fmt.Sprintf("unknown order type %q", ins.OrderType)
+	}
+}
+
+func (v *Validator) checkStake(size, price float64) (types.InstructionReportErrorCode, string) {
+	if size <= 0 {
+		return types.INVALID_BET_SIZE, fmt.Sprintf("stake %g must be positive", size)
+	}
+	if !twoDecimals(size) {
+		return types.INVALID_BET_SIZE, fmt.Sprintf("stake %g has more than 2 decimals", size)
+	}
+
+	if size < v.minimums.BetSize {
+		// Small stakes are allowed when they can still pay out the minimum
+		if v.minimums.BetPayout > 0 && size*price >= v.minimums.BetPayout {
+			return "", ""
+		}
+		return types.INVALID_BET_SIZE, fmt.Sprintf("stake %g below minimum %g", size, v.minimums.BetSize)
+	}
+
+	return "", ""
+}
+
+func (v *Validator) checkBspLiability(side types.Side, liability float64) (types.InstructionReportErrorCode, string) {
+	if liability <= 0 || !twoDecimals(liability) {
+		return types.INVALID_BET_SIZE, fmt.Sprintf("liability %g must be positive with at most 2 decimals", liability)
+	}
+
+	minimum := v.minimums.BetSize
+	if side == types.LAY {
+		minimum = v.minimums.BspLiability
+	}
+
+	if liability < minimum {
+		return types.INVALID_BET_SIZE, fmt.Sprintf("BSP liability %g below minimum %g", liability, minimum)
+	}
+
+	return "", ""
+}
+
+func checkPersistence(p types.PersistenceType, info MarketInfo, known bool) (types.InstructionReportErrorCode, string) {
+	switch p {
+	case "", types.LAPSE:
+		return "", ""
+	case types.PERSIST:
+		if known && !info.PersistenceEnabled {
+			return types.INVALID_PERSISTENCE_TYPE, "market does not allow persistence"
+		}
+	case types.PERSISTENCE_MARKET_ON_CLOSE:
+		if known && !info.BspMarket {
+			return types.INVALID_PERSISTENCE_TYPE, "MARKET_ON_CLOSE persistence needs a BSP market"
+		}
+	default:
+		return types.INVALID_PERSISTENCE_TYPE, fmt.Sprintf("unknown persistence type %q", p)
+	}
+
+	return "", ""
+}
+
+func twoDecimals(v float64) bool {
+	cents := v * 100
+	return math.Abs(cents-math.Round(cents)) < 1e-6
+}
@@ -0,0 +1,75 @@
+// validation/validation_test.go
+
+package validation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Bazcampbell/betfair-api-go-sdk/types"
+	"github.com/Bazcampbell/betfair-api-go-sdk/validation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func limit(side types.Side, price, size float64, persistence types.PersistenceType) types.PlaceInstruction {
+	return types.PlaceInstruction{
+		OrderType:   types.LIMIT,
+		SelectionId: 11,
+		Side:        side,
+		LimitOrder:  &types.LimitOrder{Price: price, Size: size, PersistenceType: persistence},
+	}
+}
+
+func TestValidatePlace_RejectsEachBadInstruction(t *testing.T) {
+	v, err := validation.ForCurrency("GBP")
+	require.NoError(t, err)
+	v.SetMarket("1.1", validation.MarketInfo{BspMarket: false, PersistenceEnabled: true})
+
+	err = v.ValidatePlace(types.PlaceOrdersRequest{
+		MarketId: "1.1",
+		Instructions: []types.PlaceInstruction{
+			limit(types.BACK, 2.02, 5, types.LAPSE),                      // valid
+			limit(types.BACK, 2.01, 5, types.LAPSE),                      // off ladder
+			limit(types.LAY, 3.0, 0.5, types.LAPSE),                      // below minimum, pays out 1.5
+			limit(types.BACK, 30, 0.5, types.LAPSE),                      // below minimum but pays out 15
+			limit(types.BACK, 3.0, 2.505, types.PERSIST),                 // 3 decimals
+			limit(types.BACK, 3.0, 2, types.PERSISTENCE_MARKET_ON_CLOSE), // not a BSP market
+			{OrderType: types.MARKET_ON_CLOSE, Side: types.LAY, MarketOnCloseOrder: &types.MarketOnCloseOrder{Liability: 20}},
+		},
+	})
+
+	var verr *validation.ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Equal(t, map[int]types.InstructionReportErrorCode{
+		1: types.INVALID_ODDS,
+		2: types.INVALID_BET_SIZE,
+		4: types.INVALID_BET_SIZE,
+		5: types.INVALID_PERSISTENCE_TYPE,
+		6: types.MARKET_NOT_OPEN_FOR_BSP_BETTING,
+	}, verr.Codes())
+}
+
+func TestValidatePlace_BspLiabilityAndInstructionLimit(t *testing.T) {
+	v, err := validation.ForCurrency("GBP")
+	require.NoError(t, err)
+	v.SetMarket("1.1", validation.MarketInfo{BspMarket: true})
+
+	err = v.ValidatePlace(types.PlaceOrdersRequest{MarketId: "1.1", Instructions: []types.PlaceInstruction{
+		{OrderType: types.LIMIT_ON_CLOSE, Side: types.LAY, LimitOnCloseOrder: &types.LimitOnCloseOrder{Price: 5, Liability: 5}},
+		{OrderType: types.MARKET_ON_CLOSE, Side: types.BACK, MarketOnCloseOrder: &types.MarketOnCloseOrder{Liability: 5}},
+	}})
+
+	var verr *validation.ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Equal(t, map[int]types.InstructionReportErrorCode{0: types.INVALID_BET_SIZE}, verr.Codes())
+
+	many := make([]types.PlaceInstruction, validation.MAX_PLACE_INSTRUCTIONS+1)
+	for i := range many {
+		many[i] = limit(types.BACK, 2, 2, types.LAPSE)
+	}
+	err = v.ValidatePlace(types.PlaceOrdersRequest{MarketId: "1.1", Instructions: many})
+	require.True(t, errors.As(err, &verr))
+	assert.Equal(t, types.TOO_MANY_INSTRUCTIONS, verr.Code)
+}